@@ -0,0 +1,349 @@
+// Package analysis derives per-team and per-participant series from the timeline of a
+// riot.MatchDetail.
+package analysis
+
+import (
+	"github.com/OwenDurni/loltools/riot"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// The version of the results produced by Analyze. Bump it whenever the output changes
+// so that stored analyses are recomputed.
+const Version = 1
+
+// Minutes at which participant snapshots are taken.
+const (
+	EarlyMinute = 10
+	MidMinute   = 15
+)
+
+// Everything derived from one match. Serializes to JSON.
+type MatchAnalysis struct {
+	Version  int
+	MatchId  int64
+	Duration time.Duration
+
+	// Blue minus purple totals at every timeline frame.
+	Diffs []*TeamDiff
+
+	// The first of each objective taken in the game. Nil if nobody took it.
+	FirstBlood      *Objective
+	FirstTower      *Objective
+	FirstInhibitor  *Objective
+	FirstDragon     *Objective
+	FirstRiftHerald *Objective
+	FirstBaron      *Objective
+
+	// Blue side first.
+	Teams []*TeamAnalysis
+
+	// In participant id order.
+	Participants []*ParticipantAnalysis
+}
+
+// The gold and experience lead of the blue side at a point in the game. Negative values
+// mean the purple side is ahead.
+type TeamDiff struct {
+	Time     time.Duration
+	GoldDiff int
+	XpDiff   int
+}
+
+type Objective struct {
+	Time time.Duration
+
+	// The side that took the objective.
+	TeamId int
+
+	// The participant credited with the objective, or 0 if it was not a champion (ex: a
+	// tower taken by minions).
+	ParticipantId int
+}
+
+type TeamAnalysis struct {
+	TeamId      int
+	Kills       int
+	WardsPlaced int
+	WardsKilled int
+}
+
+type ParticipantAnalysis struct {
+	ParticipantId int
+	TeamId        int
+	ChampionId    int
+	SummonerId    int64
+	SummonerName  string
+
+	// Nil if the game ended before the minute.
+	AtEarly *Snapshot
+	AtMid   *Snapshot
+
+	Kills   int
+	Deaths  int
+	Assists int
+
+	// The fraction (0.0 to 1.0) of the team's kills the participant killed or assisted.
+	KillParticipation float64
+
+	WardsPlaced int
+	WardsKilled int
+
+	// Items purchased in order. Purchases that were undone are left out.
+	Items []*ItemPurchase
+}
+
+// A participant's totals as of a minute of the game.
+type Snapshot struct {
+	Minute int
+
+	// Lane minions plus jungle monsters.
+	Cs   int
+	Gold int
+	Xp   int
+}
+
+type ItemPurchase struct {
+	Time   time.Duration
+	ItemId int
+}
+
+// Returns the analysis of match, which must include a timeline.
+func Analyze(match *riot.MatchDetail) *MatchAnalysis {
+	a := &MatchAnalysis{
+		Version:  Version,
+		MatchId:  match.MatchId,
+		Duration: time.Duration(match.MatchDuration) * time.Second,
+	}
+
+	participants := make(map[int]*ParticipantAnalysis)
+	for _, p := range match.Participants {
+		pa := &ParticipantAnalysis{
+			ParticipantId: p.ParticipantId,
+			TeamId:        p.TeamId,
+			ChampionId:    p.ChampionId,
+		}
+		participants[p.ParticipantId] = pa
+		a.Participants = append(a.Participants, pa)
+	}
+	sort.Sort(participantsById(a.Participants))
+	for _, identity := range match.ParticipantIdentities {
+		if pa, ok := participants[identity.ParticipantId]; ok && identity.Player != nil {
+			pa.SummonerId = identity.Player.SummonerId
+			pa.SummonerName = identity.Player.SummonerName
+		}
+	}
+
+	teams := make(map[int]*TeamAnalysis)
+	teamIds := []int{riot.BlueTeamId, riot.PurpleTeamId}
+	for _, pa := range a.Participants {
+		teamIds = append(teamIds, pa.TeamId)
+	}
+	for _, teamId := range teamIds {
+		if _, ok := teams[teamId]; !ok {
+			teams[teamId] = &TeamAnalysis{TeamId: teamId}
+			a.Teams = append(a.Teams, teams[teamId])
+		}
+	}
+
+	if match.Timeline == nil {
+		return a
+	}
+	for _, frame := range match.Timeline.Frames {
+		a.addFrame(frame, participants)
+		for _, e := range frame.Events {
+			a.addEvent(e, participants, teams)
+		}
+	}
+
+	for _, pa := range a.Participants {
+		if t, ok := teams[pa.TeamId]; ok && t.Kills > 0 {
+			pa.KillParticipation = float64(pa.Kills+pa.Assists) / float64(t.Kills)
+		}
+	}
+	return a
+}
+
+type participantsById []*ParticipantAnalysis
+
+func (a participantsById) Len() int           { return len(a) }
+func (a participantsById) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a participantsById) Less(i, j int) bool { return a[i].ParticipantId < a[j].ParticipantId }
+
+// Frame timestamps are milliseconds since the start of the game, which riot.RiotTime
+// decodes as a time that many milliseconds after the Unix epoch.
+func frameTime(frame *riot.Frame) time.Duration {
+	return time.Time(frame.Timestamp).Sub(time.Unix(0, 0))
+}
+
+func eventTime(e *riot.Event) time.Duration {
+	return time.Duration(e.Timestamp) * time.Millisecond
+}
+
+func (a *MatchAnalysis) addFrame(
+	frame *riot.Frame, participants map[int]*ParticipantAnalysis) {
+	t := frameTime(frame)
+	diff := &TeamDiff{Time: t}
+	for key, pf := range frame.ParticipantFrames {
+		participantId := pf.ParticipantId
+		if participantId == 0 {
+			// Older matches only identify the participant by the map key.
+			participantId, _ = strconv.Atoi(key)
+		}
+		pa, ok := participants[participantId]
+		if !ok {
+			continue
+		}
+		sign := 1
+		if pa.TeamId == riot.PurpleTeamId {
+			sign = -1
+		}
+		diff.GoldDiff += sign * pf.TotalGold
+		diff.XpDiff += sign * pf.Xp
+
+		// Frames are about a minute apart, so take the first at or after the minute.
+		if pa.AtEarly == nil && t >= EarlyMinute*time.Minute {
+			pa.AtEarly = newSnapshot(EarlyMinute, &pf)
+		}
+		if pa.AtMid == nil && t >= MidMinute*time.Minute {
+			pa.AtMid = newSnapshot(MidMinute, &pf)
+		}
+	}
+	a.Diffs = append(a.Diffs, diff)
+}
+
+func newSnapshot(minute int, pf *riot.ParticipantFrame) *Snapshot {
+	return &Snapshot{
+		Minute: minute,
+		Cs:     pf.MinionsKilled + pf.JungleMinionsKilled,
+		Gold:   pf.TotalGold,
+		Xp:     pf.Xp,
+	}
+}
+
+func (a *MatchAnalysis) addEvent(
+	e *riot.Event,
+	participants map[int]*ParticipantAnalysis,
+	teams map[int]*TeamAnalysis) {
+	t := eventTime(e)
+
+	// The side of the participant credited with e, if any.
+	killerTeamId := 0
+	if killer, ok := participants[e.KillerId]; ok {
+		killerTeamId = killer.TeamId
+	}
+
+	switch e.EventType {
+	case "CHAMPION_KILL":
+		if killer, ok := participants[e.KillerId]; ok {
+			killer.Kills++
+			teams[killer.TeamId].Kills++
+			if a.FirstBlood == nil {
+				a.FirstBlood = &Objective{t, killer.TeamId, killer.ParticipantId}
+			}
+		}
+		if victim, ok := participants[e.VictimId]; ok {
+			victim.Deaths++
+		}
+		for _, id := range e.AssistingParticipantIds {
+			if assister, ok := participants[id]; ok {
+				assister.Assists++
+			}
+		}
+
+	case "WARD_PLACED":
+		if e.WardType == "UNDEFINED" {
+			// Not a ward a player placed (ex: a Teemo mushroom).
+			return
+		}
+		if creator, ok := participants[e.CreatorId]; ok {
+			creator.WardsPlaced++
+			teams[creator.TeamId].WardsPlaced++
+		}
+
+	case "WARD_KILL":
+		if killer, ok := participants[e.KillerId]; ok {
+			killer.WardsKilled++
+			teams[killer.TeamId].WardsKilled++
+		}
+
+	case "BUILDING_KILL":
+		// The event names the side that lost the building.
+		teamId := riot.BlueTeamId + riot.PurpleTeamId - e.TeamId
+		objective := &Objective{t, teamId, 0}
+		if killerTeamId == teamId {
+			objective.ParticipantId = e.KillerId
+		}
+		switch e.BuildingType {
+		case "TOWER_BUILDING":
+			if a.FirstTower == nil {
+				a.FirstTower = objective
+			}
+		case "INHIBITOR_BUILDING":
+			if a.FirstInhibitor == nil {
+				a.FirstInhibitor = objective
+			}
+		}
+
+	case "ELITE_MONSTER_KILL":
+		if killerTeamId == 0 {
+			return
+		}
+		objective := &Objective{t, killerTeamId, e.KillerId}
+		switch e.MonsterType {
+		case "DRAGON":
+			if a.FirstDragon == nil {
+				a.FirstDragon = objective
+			}
+		case "RIFTHERALD":
+			if a.FirstRiftHerald == nil {
+				a.FirstRiftHerald = objective
+			}
+		case "BARON_NASHOR":
+			if a.FirstBaron == nil {
+				a.FirstBaron = objective
+			}
+		}
+
+	case "ITEM_PURCHASED":
+		if p, ok := participants[e.ParticipantId]; ok {
+			p.Items = append(p.Items, &ItemPurchase{t, e.ItemId})
+		}
+
+	case "ITEM_UNDO":
+		// ItemBefore is the item whose purchase was undone. Sell undos have no
+		// ItemBefore and do not change the build.
+		p, ok := participants[e.ParticipantId]
+		if !ok || e.ItemBefore == 0 {
+			return
+		}
+		for i := len(p.Items) - 1; i >= 0; i-- {
+			if p.Items[i].ItemId == e.ItemBefore {
+				p.Items = append(p.Items[:i], p.Items[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Returns the participants on side teamId.
+func (a *MatchAnalysis) TeamParticipants(teamId int) []*ParticipantAnalysis {
+	var ret []*ParticipantAnalysis
+	for _, pa := range a.Participants {
+		if pa.TeamId == teamId {
+			ret = append(ret, pa)
+		}
+	}
+	return ret
+}
+
+// Returns the participant playing summonerId, or nil if they were not in the match.
+func (a *MatchAnalysis) ParticipantBySummonerId(summonerId int64) *ParticipantAnalysis {
+	for _, pa := range a.Participants {
+		if pa.SummonerId == summonerId {
+			return pa
+		}
+	}
+	return nil
+}
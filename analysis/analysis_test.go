@@ -0,0 +1,222 @@
+package analysis
+
+import (
+	"encoding/json"
+	"github.com/OwenDurni/loltools/riot"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func frameAt(minute int, frames map[string]riot.ParticipantFrame, events ...*riot.Event) *riot.Frame {
+	return &riot.Frame{
+		Timestamp:         riot.RiotTime(time.Unix(0, 0).Add(time.Duration(minute) * time.Minute)),
+		ParticipantFrames: frames,
+		Events:            events,
+	}
+}
+
+func millis(minute int) int64 {
+	return int64(minute) * 60 * 1000
+}
+
+// Two players a side: participants 1 and 2 are blue, 3 and 4 are purple.
+func testMatch() *riot.MatchDetail {
+	pf := func(id, cs, jungle, gold, xp int) riot.ParticipantFrame {
+		return riot.ParticipantFrame{
+			ParticipantId:       id,
+			MinionsKilled:       cs,
+			JungleMinionsKilled: jungle,
+			TotalGold:           gold,
+			Xp:                  xp,
+		}
+	}
+	return &riot.MatchDetail{
+		MatchId:       7,
+		MatchDuration: 20 * 60,
+		Participants: []*riot.Participant{
+			{ParticipantId: 3, TeamId: riot.PurpleTeamId, ChampionId: 30},
+			{ParticipantId: 1, TeamId: riot.BlueTeamId, ChampionId: 10},
+			{ParticipantId: 2, TeamId: riot.BlueTeamId, ChampionId: 20},
+			{ParticipantId: 4, TeamId: riot.PurpleTeamId, ChampionId: 40},
+		},
+		ParticipantIdentities: []*riot.ParticipantIdentity{
+			{ParticipantId: 1, Player: &riot.Player{SummonerId: 1001, SummonerName: "One"}},
+			{ParticipantId: 3, Player: &riot.Player{SummonerId: 1003, SummonerName: "Three"}},
+		},
+		Timeline: &riot.Timeline{
+			FrameInterval: 60000,
+			Frames: []*riot.Frame{
+				frameAt(0, map[string]riot.ParticipantFrame{
+					"1": pf(1, 0, 0, 500, 0),
+					"2": pf(2, 0, 0, 500, 0),
+					"3": pf(3, 0, 0, 500, 0),
+					"4": pf(4, 0, 0, 500, 0),
+				},
+					&riot.Event{EventType: "ITEM_PURCHASED", ParticipantId: 1, ItemId: 1055, Timestamp: 1000},
+					&riot.Event{EventType: "ITEM_PURCHASED", ParticipantId: 1, ItemId: 2003, Timestamp: 2000},
+					&riot.Event{EventType: "ITEM_UNDO", ParticipantId: 1, ItemBefore: 2003, Timestamp: 3000},
+					&riot.Event{EventType: "ITEM_PURCHASED", ParticipantId: 1, ItemId: 3340, Timestamp: 4000},
+				),
+				frameAt(5, nil,
+					&riot.Event{
+						EventType: "CHAMPION_KILL", KillerId: 1, VictimId: 3,
+						AssistingParticipantIds: []int{2}, Timestamp: millis(4),
+					},
+					&riot.Event{EventType: "WARD_PLACED", CreatorId: 2, WardType: "YELLOW_TRINKET", Timestamp: millis(4)},
+					&riot.Event{EventType: "WARD_PLACED", CreatorId: 4, WardType: "UNDEFINED", Timestamp: millis(4)},
+					&riot.Event{EventType: "WARD_KILL", KillerId: 3, WardType: "YELLOW_TRINKET", Timestamp: millis(5)},
+				),
+				// The old format identified participants only by the map key.
+				frameAt(10, map[string]riot.ParticipantFrame{
+					"1": pf(0, 80, 2, 4000, 5000),
+					"2": pf(0, 10, 60, 3500, 4500),
+					"3": pf(0, 70, 0, 3000, 4000),
+					"4": pf(0, 5, 50, 3200, 4200),
+				},
+					&riot.Event{EventType: "ELITE_MONSTER_KILL", KillerId: 4, MonsterType: "DRAGON", Timestamp: millis(9)},
+					&riot.Event{
+						EventType: "BUILDING_KILL", KillerId: 0, TeamId: riot.BlueTeamId,
+						BuildingType: "TOWER_BUILDING", Timestamp: millis(10),
+					},
+				),
+				frameAt(16, map[string]riot.ParticipantFrame{
+					"1": pf(1, 130, 2, 6000, 8000),
+					"2": pf(2, 20, 90, 5500, 7500),
+					"3": pf(3, 120, 0, 5000, 7000),
+					"4": pf(4, 10, 80, 5200, 7200),
+				},
+					&riot.Event{
+						EventType: "CHAMPION_KILL", KillerId: 4, VictimId: 2,
+						Timestamp: millis(15),
+					},
+					&riot.Event{
+						EventType: "CHAMPION_KILL", KillerId: 2, VictimId: 4,
+						AssistingParticipantIds: []int{1}, Timestamp: millis(16),
+					},
+					&riot.Event{
+						EventType: "BUILDING_KILL", KillerId: 1, TeamId: riot.PurpleTeamId,
+						BuildingType: "TOWER_BUILDING", Timestamp: millis(16),
+					},
+				),
+			},
+		},
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	a := Analyze(testMatch())
+
+	if a.Version != Version || a.MatchId != 7 || a.Duration != 20*time.Minute {
+		t.Errorf("got Version %d, MatchId %d, Duration %v", a.Version, a.MatchId, a.Duration)
+	}
+
+	wantDiffs := []TeamDiff{
+		{0, 0, 0},
+		{5 * time.Minute, 0, 0},
+		{10 * time.Minute, 1300, 1300},
+		{16 * time.Minute, 1300, 1300},
+	}
+	if len(a.Diffs) != len(wantDiffs) {
+		t.Fatalf("got %d diffs, want %d", len(a.Diffs), len(wantDiffs))
+	}
+	for i, want := range wantDiffs {
+		if *a.Diffs[i] != want {
+			t.Errorf("diff %d: got %+v, want %+v", i, *a.Diffs[i], want)
+		}
+	}
+
+	var ids []int
+	for _, pa := range a.Participants {
+		ids = append(ids, pa.ParticipantId)
+	}
+	if !reflect.DeepEqual(ids, []int{1, 2, 3, 4}) {
+		t.Fatalf("got participants %v", ids)
+	}
+	one, two, three, four := a.Participants[0], a.Participants[1], a.Participants[2], a.Participants[3]
+
+	if one.SummonerId != 1001 || one.SummonerName != "One" || one.ChampionId != 10 {
+		t.Errorf("participant 1: got %+v", one)
+	}
+	if got := a.ParticipantBySummonerId(1003); got != three {
+		t.Errorf("ParticipantBySummonerId(1003): got %+v", got)
+	}
+
+	if *one.AtEarly != (Snapshot{10, 82, 4000, 5000}) {
+		t.Errorf("participant 1 at 10: got %+v", *one.AtEarly)
+	}
+	if *two.AtMid != (Snapshot{15, 110, 5500, 7500}) {
+		t.Errorf("participant 2 at 15: got %+v", *two.AtMid)
+	}
+
+	if one.Kills != 1 || one.Deaths != 0 || one.Assists != 1 {
+		t.Errorf("participant 1: got %d/%d/%d", one.Kills, one.Deaths, one.Assists)
+	}
+	if two.Kills != 1 || two.Deaths != 1 || two.Assists != 1 {
+		t.Errorf("participant 2: got %d/%d/%d", two.Kills, two.Deaths, two.Assists)
+	}
+	if one.KillParticipation != 1.0 || four.KillParticipation != 1.0 ||
+		three.KillParticipation != 0 {
+		t.Errorf("got kill participation %v, %v, %v",
+			one.KillParticipation, three.KillParticipation, four.KillParticipation)
+	}
+
+	if two.WardsPlaced != 1 || four.WardsPlaced != 0 || three.WardsKilled != 1 {
+		t.Errorf("got wards placed %d, %d and killed %d",
+			two.WardsPlaced, four.WardsPlaced, three.WardsKilled)
+	}
+	if blue := a.Teams[0]; blue.TeamId != riot.BlueTeamId || blue.Kills != 2 ||
+		blue.WardsPlaced != 1 {
+		t.Errorf("blue team: got %+v", blue)
+	}
+
+	var items []ItemPurchase
+	for _, item := range one.Items {
+		items = append(items, *item)
+	}
+	wantItems := []ItemPurchase{{time.Second, 1055}, {4 * time.Second, 3340}}
+	if !reflect.DeepEqual(items, wantItems) {
+		t.Errorf("got items %v, want %v", items, wantItems)
+	}
+
+	objectives := []struct {
+		name string
+		got  *Objective
+		want *Objective
+	}{
+		{"first blood", a.FirstBlood, &Objective{4 * time.Minute, riot.BlueTeamId, 1}},
+		{"first dragon", a.FirstDragon, &Objective{9 * time.Minute, riot.PurpleTeamId, 4}},
+		// Taken by minions, so nobody is credited.
+		{"first tower", a.FirstTower, &Objective{10 * time.Minute, riot.PurpleTeamId, 0}},
+		{"first baron", a.FirstBaron, nil},
+	}
+	for _, o := range objectives {
+		if !reflect.DeepEqual(o.got, o.want) {
+			t.Errorf("%s: got %+v, want %+v", o.name, o.got, o.want)
+		}
+	}
+}
+
+func TestAnalyzeShortGame(t *testing.T) {
+	match := testMatch()
+	match.Timeline.Frames = match.Timeline.Frames[:2]
+	a := Analyze(match)
+	if a.Participants[0].AtEarly != nil || a.Participants[0].AtMid != nil {
+		t.Errorf("got snapshots for a game that ended before 10 minutes")
+	}
+}
+
+func TestAnalysisRoundTripsThroughJson(t *testing.T) {
+	a := Analyze(testMatch())
+	data, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := new(MatchAnalysis)
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, decoded) {
+		t.Errorf("got %+v after a round trip, want %+v", decoded, a)
+	}
+}
@@ -0,0 +1,286 @@
+package analysis
+
+import (
+	"github.com/OwenDurni/loltools/riot"
+	"sort"
+)
+
+// A champion picked by one side of a game.
+type Pick struct {
+	ChampionId   int
+	SummonerId   int64
+	SummonerName string
+}
+
+// The picks and bans of one side of a game.
+type SideDraft struct {
+	TeamId int
+	Won    bool
+
+	// Champions banned by this side and by the opposing side, in pick turn order.
+	Bans         []int
+	OpponentBans []int
+
+	// Champions picked by this side and by the opposing side, in participant order. In
+	// draft games that is the order each side picked in.
+	Picks         []*Pick
+	OpponentPicks []*Pick
+}
+
+// Blue side picks first in draft games.
+func (d *SideDraft) HadFirstPick() bool {
+	return d.TeamId == riot.BlueTeamId
+}
+
+// Returns the first champion picked by this side, or nil if it picked none.
+func (d *SideDraft) FirstPick() *Pick {
+	if len(d.Picks) == 0 {
+		return nil
+	}
+	return d.Picks[0]
+}
+
+type riotParticipantsById []*riot.Participant
+
+func (a riotParticipantsById) Len() int           { return len(a) }
+func (a riotParticipantsById) Less(i, j int) bool { return a[i].ParticipantId < a[j].ParticipantId }
+func (a riotParticipantsById) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+type bansByPickTurn []*riot.BannedChampion
+
+func (a bansByPickTurn) Len() int           { return len(a) }
+func (a bansByPickTurn) Less(i, j int) bool { return a[i].PickTurn < a[j].PickTurn }
+func (a bansByPickTurn) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// Returns the draft of match from the point of view of the side with teamId.
+func Draft(match *riot.MatchDetail, teamId int) *SideDraft {
+	d := &SideDraft{TeamId: teamId}
+
+	for _, team := range match.Teams {
+		bans := make(bansByPickTurn, len(team.Bans))
+		copy(bans, team.Bans)
+		sort.Stable(bans)
+		championIds := make([]int, len(bans))
+		for i, ban := range bans {
+			championIds[i] = ban.ChampionId
+		}
+		if team.TeamId == teamId {
+			d.Won = team.Winner
+			d.Bans = championIds
+		} else {
+			d.OpponentBans = championIds
+		}
+	}
+
+	identities := make(map[int]*riot.Player)
+	for _, pi := range match.ParticipantIdentities {
+		if pi.Player != nil {
+			identities[pi.ParticipantId] = pi.Player
+		}
+	}
+	participants := make(riotParticipantsById, len(match.Participants))
+	copy(participants, match.Participants)
+	sort.Sort(participants)
+	for _, p := range participants {
+		pick := &Pick{ChampionId: p.ChampionId}
+		if player := identities[p.ParticipantId]; player != nil {
+			pick.SummonerId = player.SummonerId
+			pick.SummonerName = player.SummonerName
+		}
+		if p.TeamId == teamId {
+			d.Picks = append(d.Picks, pick)
+		} else {
+			d.OpponentPicks = append(d.OpponentPicks, pick)
+		}
+	}
+	return d
+}
+
+// The number of times a champion was picked or banned.
+type ChampionCount struct {
+	ChampionId int
+	Count      int
+}
+
+// The games won and played with a champion.
+type ChampionRecord struct {
+	ChampionId int
+	Games      int
+	Wins       int
+}
+
+func (r *ChampionRecord) WinRate() float64 {
+	if r.Games == 0 {
+		return 0
+	}
+	return float64(r.Wins) / float64(r.Games)
+}
+
+// The champions a single summoner played.
+type PlayerPool struct {
+	SummonerId   int64
+	SummonerName string
+	Games        int
+	Champions    []*ChampionRecord
+}
+
+// Pick and ban statistics across the drafts of one side of many games.
+//
+// Every list is sorted most frequent first.
+type DraftReport struct {
+	Games int
+	Wins  int
+
+	// Games where the side had first pick.
+	FirstPickGames int
+	FirstPickWins  int
+
+	BansFor     []*ChampionCount
+	BansAgainst []*ChampionCount
+
+	// The champion the side picked first.
+	FirstPicks []*ChampionCount
+
+	// The champions the side picked.
+	Champions []*ChampionRecord
+
+	Players []*PlayerPool
+}
+
+type championCounter map[int]*ChampionCount
+
+func (m championCounter) add(championId int) {
+	if m[championId] == nil {
+		m[championId] = &ChampionCount{ChampionId: championId}
+	}
+	m[championId].Count++
+}
+
+func (m championCounter) sorted() []*ChampionCount {
+	ret := make([]*ChampionCount, 0, len(m))
+	for _, count := range m {
+		ret = append(ret, count)
+	}
+	sort.Sort(championCountsByCount(ret))
+	return ret
+}
+
+type championCountsByCount []*ChampionCount
+
+func (a championCountsByCount) Len() int { return len(a) }
+func (a championCountsByCount) Less(i, j int) bool {
+	if a[i].Count != a[j].Count {
+		return a[i].Count > a[j].Count
+	}
+	return a[i].ChampionId < a[j].ChampionId
+}
+func (a championCountsByCount) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+
+type championRecorder map[int]*ChampionRecord
+
+func (m championRecorder) add(championId int, won bool) {
+	if m[championId] == nil {
+		m[championId] = &ChampionRecord{ChampionId: championId}
+	}
+	m[championId].Games++
+	if won {
+		m[championId].Wins++
+	}
+}
+
+func (m championRecorder) sorted() []*ChampionRecord {
+	ret := make([]*ChampionRecord, 0, len(m))
+	for _, record := range m {
+		ret = append(ret, record)
+	}
+	sort.Sort(championRecordsByGames(ret))
+	return ret
+}
+
+type championRecordsByGames []*ChampionRecord
+
+func (a championRecordsByGames) Len() int { return len(a) }
+func (a championRecordsByGames) Less(i, j int) bool {
+	if a[i].Games != a[j].Games {
+		return a[i].Games > a[j].Games
+	}
+	if a[i].Wins != a[j].Wins {
+		return a[i].Wins > a[j].Wins
+	}
+	return a[i].ChampionId < a[j].ChampionId
+}
+func (a championRecordsByGames) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+
+type playerPoolsByGames []*PlayerPool
+
+func (a playerPoolsByGames) Len() int { return len(a) }
+func (a playerPoolsByGames) Less(i, j int) bool {
+	if a[i].Games != a[j].Games {
+		return a[i].Games > a[j].Games
+	}
+	return a[i].SummonerName < a[j].SummonerName
+}
+func (a playerPoolsByGames) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+
+// Summarizes drafts, each of which is from the point of view of the same team.
+//
+// Picks by unidentified summoners are left out of Players.
+func NewDraftReport(drafts []*SideDraft) *DraftReport {
+	r := new(DraftReport)
+	bansFor := make(championCounter)
+	bansAgainst := make(championCounter)
+	firstPicks := make(championCounter)
+	champions := make(championRecorder)
+	players := make(map[int64]*PlayerPool)
+	pools := make(map[int64]championRecorder)
+
+	for _, d := range drafts {
+		r.Games++
+		if d.Won {
+			r.Wins++
+		}
+		if d.HadFirstPick() {
+			r.FirstPickGames++
+			if d.Won {
+				r.FirstPickWins++
+			}
+		}
+		for _, championId := range d.Bans {
+			bansFor.add(championId)
+		}
+		for _, championId := range d.OpponentBans {
+			bansAgainst.add(championId)
+		}
+		if pick := d.FirstPick(); pick != nil {
+			firstPicks.add(pick.ChampionId)
+		}
+		for _, pick := range d.Picks {
+			champions.add(pick.ChampionId, d.Won)
+			if pick.SummonerId == 0 {
+				continue
+			}
+			player := players[pick.SummonerId]
+			if player == nil {
+				player = &PlayerPool{SummonerId: pick.SummonerId}
+				players[pick.SummonerId] = player
+				pools[pick.SummonerId] = make(championRecorder)
+			}
+			player.Games++
+			if pick.SummonerName != "" {
+				player.SummonerName = pick.SummonerName
+			}
+			pools[pick.SummonerId].add(pick.ChampionId, d.Won)
+		}
+	}
+
+	r.BansFor = bansFor.sorted()
+	r.BansAgainst = bansAgainst.sorted()
+	r.FirstPicks = firstPicks.sorted()
+	r.Champions = champions.sorted()
+	for summonerId, player := range players {
+		player.Champions = pools[summonerId].sorted()
+		r.Players = append(r.Players, player)
+	}
+	sort.Sort(playerPoolsByGames(r.Players))
+	return r
+}
@@ -0,0 +1,146 @@
+package analysis
+
+import (
+	"github.com/OwenDurni/loltools/riot"
+	"reflect"
+	"testing"
+)
+
+// Two players a side. Blue is listed second and its bans are out of order to check
+// sorting.
+func draftMatch(blueWon bool, blue, purple [2]int, blueBans, purpleBans []*riot.BannedChampion) *riot.MatchDetail {
+	return &riot.MatchDetail{
+		Participants: []*riot.Participant{
+			{ParticipantId: 3, TeamId: riot.PurpleTeamId, ChampionId: purple[0]},
+			{ParticipantId: 4, TeamId: riot.PurpleTeamId, ChampionId: purple[1]},
+			{ParticipantId: 2, TeamId: riot.BlueTeamId, ChampionId: blue[1]},
+			{ParticipantId: 1, TeamId: riot.BlueTeamId, ChampionId: blue[0]},
+		},
+		ParticipantIdentities: []*riot.ParticipantIdentity{
+			{ParticipantId: 1, Player: &riot.Player{SummonerId: 1001, SummonerName: "One"}},
+			{ParticipantId: 2, Player: &riot.Player{SummonerId: 1002, SummonerName: "Two"}},
+			{ParticipantId: 3, Player: &riot.Player{SummonerId: 1003, SummonerName: "Three"}},
+		},
+		Teams: []*riot.Team{
+			{TeamId: riot.PurpleTeamId, Winner: !blueWon, Bans: purpleBans},
+			{TeamId: riot.BlueTeamId, Winner: blueWon, Bans: blueBans},
+		},
+	}
+}
+
+func bans(championIds ...int) []*riot.BannedChampion {
+	ret := make([]*riot.BannedChampion, len(championIds))
+	for i, id := range championIds {
+		ret[i] = &riot.BannedChampion{ChampionId: id, PickTurn: 2*i + 1}
+	}
+	return ret
+}
+
+func TestDraft(t *testing.T) {
+	blueBans := []*riot.BannedChampion{{ChampionId: 52, PickTurn: 3}, {ChampionId: 51, PickTurn: 1}}
+	match := draftMatch(true, [2]int{10, 20}, [2]int{30, 40}, blueBans, bans(61, 62))
+
+	d := Draft(match, riot.BlueTeamId)
+	if !d.Won || !d.HadFirstPick() {
+		t.Errorf("got Won %v, HadFirstPick %v", d.Won, d.HadFirstPick())
+	}
+	if !reflect.DeepEqual(d.Bans, []int{51, 52}) || !reflect.DeepEqual(d.OpponentBans, []int{61, 62}) {
+		t.Errorf("got bans %v against %v", d.Bans, d.OpponentBans)
+	}
+	wantPicks := []*Pick{{10, 1001, "One"}, {20, 1002, "Two"}}
+	if !reflect.DeepEqual(d.Picks, wantPicks) {
+		t.Errorf("got picks %+v, want %+v", d.Picks, wantPicks)
+	}
+	if d.FirstPick().ChampionId != 10 {
+		t.Errorf("got first pick %+v", d.FirstPick())
+	}
+
+	d = Draft(match, riot.PurpleTeamId)
+	if d.Won || d.HadFirstPick() {
+		t.Errorf("purple: got Won %v, HadFirstPick %v", d.Won, d.HadFirstPick())
+	}
+	wantPicks = []*Pick{{30, 1003, "Three"}, {40, 0, ""}}
+	if !reflect.DeepEqual(d.Picks, wantPicks) {
+		t.Errorf("purple: got picks %+v, want %+v", d.Picks, wantPicks)
+	}
+}
+
+func TestDraftWithoutPicks(t *testing.T) {
+	d := Draft(&riot.MatchDetail{}, riot.BlueTeamId)
+	if d.FirstPick() != nil || d.Won {
+		t.Errorf("got %+v for an empty match", d)
+	}
+}
+
+func TestNewDraftReport(t *testing.T) {
+	drafts := []*SideDraft{
+		// Won on blue.
+		Draft(draftMatch(true, [2]int{10, 20}, [2]int{30, 40}, bans(51, 52), bans(61, 62)),
+			riot.BlueTeamId),
+		// Lost on purple.
+		Draft(draftMatch(true, [2]int{30, 50}, [2]int{10, 40}, bans(61, 63), bans(51, 53)),
+			riot.PurpleTeamId),
+		// Won on blue.
+		Draft(draftMatch(true, [2]int{10, 40}, [2]int{20, 30}, bans(51, 53), bans(61, 62)),
+			riot.BlueTeamId),
+	}
+	r := NewDraftReport(drafts)
+
+	if r.Games != 3 || r.Wins != 2 || r.FirstPickGames != 2 || r.FirstPickWins != 2 {
+		t.Errorf("got %d/%d games, %d/%d first pick games",
+			r.Wins, r.Games, r.FirstPickWins, r.FirstPickGames)
+	}
+
+	counts := func(a []*ChampionCount) [][2]int {
+		var ret [][2]int
+		for _, c := range a {
+			ret = append(ret, [2]int{c.ChampionId, c.Count})
+		}
+		return ret
+	}
+	if got, want := counts(r.BansFor), [][2]int{{51, 3}, {53, 2}, {52, 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got bans for %v, want %v", got, want)
+	}
+	if got, want := counts(r.BansAgainst), [][2]int{{61, 3}, {62, 2}, {63, 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got bans against %v, want %v", got, want)
+	}
+	if got, want := counts(r.FirstPicks), [][2]int{{10, 3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got first picks %v, want %v", got, want)
+	}
+
+	records := func(a []*ChampionRecord) [][3]int {
+		var ret [][3]int
+		for _, r := range a {
+			ret = append(ret, [3]int{r.ChampionId, r.Games, r.Wins})
+		}
+		return ret
+	}
+	wantChampions := [][3]int{{10, 3, 2}, {40, 2, 1}, {20, 1, 1}}
+	if got := records(r.Champions); !reflect.DeepEqual(got, wantChampions) {
+		t.Errorf("got champions %v, want %v", got, wantChampions)
+	}
+	if wr := r.Champions[0].WinRate(); wr < 0.66 || wr > 0.67 {
+		t.Errorf("got win rate %v for %d", wr, r.Champions[0].ChampionId)
+	}
+
+	// Participant 4 is unidentified so only Three is attributed a pick on purple.
+	var players []string
+	for _, p := range r.Players {
+		players = append(players, p.SummonerName)
+	}
+	if !reflect.DeepEqual(players, []string{"One", "Two", "Three"}) {
+		t.Fatalf("got players %v", players)
+	}
+	if got, want := records(r.Players[0].Champions), [][3]int{{10, 2, 2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got pool of One %v, want %v", got, want)
+	}
+	if got, want := records(r.Players[1].Champions), [][3]int{{20, 1, 1}, {40, 1, 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got pool of Two %v, want %v", got, want)
+	}
+}
+
+func TestChampionRecordWinRateWithoutGames(t *testing.T) {
+	if wr := new(ChampionRecord).WinRate(); wr != 0 {
+		t.Errorf("got %v", wr)
+	}
+}
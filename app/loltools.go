@@ -41,6 +41,8 @@ func init() {
 	dispatcher.Add("/", view.HomeHandler)
 	dispatcher.Add("/admin", view.AdminIndexHandler)
 	dispatcher.Add("/api/admin/riotapikey/set", view.ApiAdminRiotKeySetHandler)
+	dispatcher.Add("/api/brackets/choose-winner", view.ApiBracketChooseWinnerHandler)
+	dispatcher.Add("/api/brackets/create", view.ApiBracketCreateHandler)
 	dispatcher.Add("/api/groups/add-user", view.ApiGroupAddUserHandler)
 	dispatcher.Add("/api/groups/create", view.ApiGroupCreateHandler)
 	dispatcher.Add("/api/groups/del-user", view.ApiGroupDelUserHandler)
@@ -49,9 +51,23 @@ func init() {
 	dispatcher.Add("/api/leagues/create", view.ApiLeagueCreateHandler)
 	dispatcher.Add("/api/leagues/group-acl-grant", view.ApiLeagueGroupAclGrantHandler)
 	dispatcher.Add("/api/leagues/group-acl-revoke", view.ApiLeagueGroupAclRevokeHandler)
+	dispatcher.Add("/api/leagues/set-eligibility", view.ApiLeagueSetEligibilityHandler)
+	dispatcher.Add("/api/leagues/set-roster-rules", view.ApiLeagueSetRosterRulesHandler)
+	dispatcher.Add("/api/leagues/set-scoring", view.ApiLeagueSetScoringHandler)
 	dispatcher.Add("/api/leagues/teams/add-player", view.ApiTeamAddPlayerHandler)
 	dispatcher.Add("/api/leagues/teams/del-player", view.ApiTeamDelPlayerHandler)
+	dispatcher.Add("/api/leagues/teams/update-player", view.ApiTeamUpdatePlayerHandler)
 	dispatcher.Add("/api/matches/create", view.ApiMatchCreateHandler)
+	dispatcher.Add("/api/matches/generate", view.ApiMatchGenerateHandler)
+	dispatcher.Add("/api/matches/report", view.ApiMatchReportHandler)
+	dispatcher.Add("/api/matches/resolve", view.ApiMatchResolveHandler)
+	dispatcher.Add("/api/matches/respond", view.ApiMatchRespondHandler)
+	dispatcher.Add("/api/matches/review-game", view.ApiMatchReviewGameHandler)
+	dispatcher.Add("/api/seasons/create-division", view.ApiSeasonCreateDivisionHandler)
+	dispatcher.Add("/api/seasons/set-team", view.ApiSeasonSetTeamHandler)
+	dispatcher.Add("/api/seasons/start", view.ApiSeasonStartHandler)
+	dispatcher.Add("/api/swiss/create", view.ApiSwissCreateHandler)
+	dispatcher.Add("/api/swiss/next-round", view.ApiSwissNextRoundHandler)
 	dispatcher.Add("/api/user/add-summoner", view.ApiUserAddSummoner)
 	dispatcher.Add("/api/user/set-primary-summoner", view.ApiUserSetPrimarySummoner)
 	dispatcher.Add("/api/user/verify-summoner", view.ApiUserVerifySummoner)
@@ -62,14 +78,28 @@ func init() {
 	dispatcher.Add("/groups/<groupId>", view.GroupViewHandler)
 	dispatcher.Add("/leagues", view.LeagueIndexHandler)
 	dispatcher.Add("/leagues/<leagueId>", view.LeagueViewHandler)
+	dispatcher.Add("/leagues/<leagueId>/brackets", view.LeagueBracketsHandler)
+	dispatcher.Add("/leagues/<leagueId>/brackets/<bracketId>", view.BracketViewHandler)
+	dispatcher.Add("/leagues/<leagueId>/draft", view.LeagueDraftHandler)
 	dispatcher.Add("/leagues/<leagueId>/games/<gameId>", view.LeagueGameViewHandler)
 	dispatcher.Add("/leagues/<leagueId>/matches/create", view.MatchCreateHandler)
+	dispatcher.Add("/leagues/<leagueId>/matches/generate", view.MatchGenerateHandler)
+	dispatcher.Add("/leagues/<leagueId>/matches/<matchId>", view.MatchViewHandler)
+	dispatcher.Add("/leagues/<leagueId>/seasons", view.LeagueSeasonsHandler)
+	dispatcher.Add("/leagues/<leagueId>/standings", view.LeagueStandingsHandler)
+	dispatcher.Add("/leagues/<leagueId>/swiss", view.LeagueSwissHandler)
+	dispatcher.Add("/leagues/<leagueId>/swiss/<swissId>", view.SwissViewHandler)
 	dispatcher.Add("/leagues/<leagueId>/teams/<teamId>", view.TeamViewHandler)
 	dispatcher.Add("/leagues/<leagueId>/teams/<teamId>/history", view.TeamGameHistory)
+	dispatcher.Add("/task/cron/all-league-eligibility", task.AllLeagueEligibility)
+	dispatcher.Add("/task/cron/all-match-details", task.AllMatchDetails)
 	dispatcher.Add("/task/cron/all-match-sync", task.AllMatchSync)
 	dispatcher.Add("/task/cron/all-team-histories", task.AllTeamHistories)
 	dispatcher.Add("/task/cron/get-missing-game-stats", task.MissingGameStats)
+	dispatcher.Add("/task/leagues/eligibility", task.LeagueEligibilityHandler)
+	dispatcher.Add("/task/riot/get/match-detail", task.FetchMatchDetailHandler)
 	dispatcher.Add("/task/riot/get/team/history", task.FetchTeamMatchHistoryHandler)
+	dispatcher.Add("/task/seasons/migrate", task.MigrateSeasonHandler)
 	dispatcher.Add("/task/match/sync", task.MatchSync)
 	dispatcher.Add("/settings", view.SettingsIndexHandler)
 
@@ -83,8 +113,8 @@ func init() {
 	view.AddTemplate("home.html",
 		"base.html")
 	view.AddTemplate("games/index.html",
-		"games/gamelong.html", "games/champsmall.html", "games/itemsmall.html",
-		"games/summonersmall.html", "base.html")
+		"games/gamelong.html", "games/analysis.html", "games/champsmall.html",
+		"games/itemsmall.html", "games/summonersmall.html", "base.html")
 	view.AddTemplate("groups/index.html",
 		"form.html", "base.html")
 	view.AddTemplate("groups/join.html",
@@ -95,18 +125,37 @@ func init() {
 		"form.html", "base.html")
 	view.AddTemplate("leagues/index.html",
 		"form.html", "base.html")
+	view.AddTemplate("leagues/brackets/index.html",
+		"leagues/seasonfilter.html", "form.html", "base.html")
+	view.AddTemplate("leagues/brackets/view.html",
+		"base.html")
+	view.AddTemplate("leagues/draft.html",
+		"leagues/draftreport.html", "games/champsmall.html", "base.html")
 	view.AddTemplate("leagues/games/index.html",
-		"games/gamelong.html", "games/champsmall.html", "games/itemsmall.html",
-		"games/summonersmall.html", "form.html", "base.html")
+		"games/gamelong.html", "games/analysis.html", "games/champsmall.html",
+		"games/itemsmall.html", "games/summonersmall.html", "form.html", "base.html")
 	view.AddTemplate("leagues/matches/create.html",
+		"form.html", "types.html", "base.html")
+	view.AddTemplate("leagues/matches/generate.html",
+		"form.html", "types.html", "base.html")
+	view.AddTemplate("leagues/matches/view.html",
+		"form.html", "base.html")
+	view.AddTemplate("leagues/seasons.html",
+		"leagues/seasonfilter.html", "form.html", "base.html")
+	view.AddTemplate("leagues/standings.html",
+		"leagues/seasonfilter.html", "base.html")
+	view.AddTemplate("leagues/swiss/index.html",
+		"leagues/seasonfilter.html", "form.html", "base.html")
+	view.AddTemplate("leagues/swiss/view.html",
 		"form.html", "base.html")
 	view.AddTemplate("leagues/teams/history.html",
 		"games/gamelong.html", "games/champsmall.html", "games/itemsmall.html",
-		"games/summonersmall.html", "base.html")
+		"games/summonersmall.html", "leagues/seasonfilter.html", "base.html")
 	view.AddTemplate("leagues/teams/view.html",
-		"games/gameshort.html", "games/champsmall.html", "form.html", "base.html")
+		"games/gameshort.html", "games/champsmall.html", "leagues/draftreport.html",
+		"leagues/seasonfilter.html", "form.html", "types.html", "base.html")
 	view.AddTemplate("leagues/view.html",
-		"form.html", "types.html", "base.html")
+		"leagues/seasonfilter.html", "form.html", "types.html", "base.html")
 	view.AddTemplate("settings/index.html",
 		"common/region_dropdown.html", "form.html", "base.html")
 }
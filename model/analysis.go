@@ -0,0 +1,99 @@
+package model
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"encoding/json"
+	"github.com/OwenDurni/loltools/analysis"
+	"github.com/OwenDurni/loltools/util/errwrap"
+	"golang.org/x/net/context"
+	"time"
+)
+
+// A stored analysis.MatchAnalysis of a Game.
+//
+// The id of the Game is the key.
+type GameAnalysis struct {
+	GameKey *datastore.Key
+
+	// The analysis.Version that produced Data. Analyses from other versions are
+	// recomputed when they are next read.
+	Version int
+
+	// The gzipped JSON encoding of the analysis.MatchAnalysis.
+	Data    []byte    `datastore:",noindex"`
+	Created time.Time `datastore:",noindex"`
+}
+
+func KeyForGameAnalysis(c appengine.Context, gameKey *datastore.Key) *datastore.Key {
+	return datastore.NewKey(c, "GameAnalysis", gameKey.StringID(), 0, nil)
+}
+
+// Returns the analysis of game, computing and storing it if it is missing or was
+// produced by an older analysis.Version. Analyses are computed from the archived
+// GameMatchDetail of the game. Analyses of details without a timeline are not stored.
+// Returns nil if Riot has no details of the game.
+func GetGameAnalysis(
+	c appengine.Context,
+	ctx context.Context,
+	game *Game,
+	gameKey *datastore.Key) (*analysis.MatchAnalysis, error) {
+	key := KeyForGameAnalysis(c, gameKey)
+	stored := new(GameAnalysis)
+	err := datastore.Get(c, key, stored)
+	if err == nil && stored.Version == analysis.Version {
+		a, decodeErr := decodeGameAnalysis(stored.Data)
+		if decodeErr == nil {
+			return a, nil
+		}
+		c.Warningf("GetGameAnalysis: corrupt analysis of %s: %v", game.Id(), decodeErr)
+	} else if err != nil && err != datastore.ErrNoSuchEntity {
+		return nil, errwrap.Wrap(err)
+	}
+
+	match, err := GetOrFetchGameMatchDetail(c, ctx, game, gameKey)
+	if err != nil {
+		return nil, err
+	}
+	if match == nil {
+		return nil, nil
+	}
+	a := analysis.Analyze(match)
+
+	// Without a timeline most of the analysis is missing. It is shown but not stored, so
+	// the game is analyzed again once its details include the timeline.
+	if match.Timeline == nil {
+		return a, nil
+	}
+
+	data, err := json.Marshal(a)
+	if err != nil {
+		return nil, errwrap.Wrap(err)
+	}
+	compressed, err := gzipBytes(data)
+	if err != nil {
+		return nil, errwrap.Wrap(err)
+	}
+	stored = &GameAnalysis{
+		GameKey: gameKey,
+		Version: analysis.Version,
+		Data:    compressed,
+		Created: time.Now(),
+	}
+	if _, err := datastore.Put(c, key, stored); err != nil {
+		return a, errwrap.Wrap(err)
+	}
+	return a, nil
+}
+
+func decodeGameAnalysis(compressed []byte) (*analysis.MatchAnalysis, error) {
+	data, err := gunzip(compressed)
+	if err != nil {
+		return nil, err
+	}
+	a := new(analysis.MatchAnalysis)
+	if err := json.Unmarshal(data, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
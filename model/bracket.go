@@ -0,0 +1,605 @@
+package model
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"errors"
+	"fmt"
+	"github.com/OwenDurni/loltools/util/errwrap"
+	"sort"
+	"time"
+)
+
+type BracketType int
+
+const (
+	SingleElimination BracketType = iota
+	DoubleElimination
+)
+
+var bracketTypeNames = map[BracketType]string{
+	SingleElimination: "single-elimination",
+	DoubleElimination: "double-elimination",
+}
+
+func (t BracketType) String() string {
+	if name, ok := bracketTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("BracketType(%d)", int(t))
+}
+
+func ParseBracketType(name string) (BracketType, error) {
+	for t, n := range bracketTypeNames {
+		if n == name {
+			return t, nil
+		}
+	}
+	return 0, errors.New(fmt.Sprintf("Unknown bracket type '%s'", name))
+}
+
+type BracketSection int
+
+const (
+	// The only section of a single elimination bracket.
+	WinnersSection BracketSection = iota
+	LosersSection
+	// The match between the winners of the winners and losers sections.
+	GrandFinalSection
+)
+
+// A playoff bracket between a league's teams, seeded from its standings.
+//
+// Ancestor: League
+type Bracket struct {
+	Name string
+	Type BracketType
+
+	// The teams of the bracket, first seed first.
+	Seeds []*datastore.Key
+
+	// The season the bracket's matches are part of. Nil before the league's first season.
+	Season *datastore.Key
+
+	// Copied to the match of each slot.
+	Summary  string
+	NumGames int
+	MapId    int
+	GameMode string
+
+	// How many days after both of its teams are known a slot's match may be played.
+	WindowDays int
+
+	Created time.Time
+}
+
+func BracketId(bracketKey *datastore.Key) string {
+	return EncodeKeyShort(bracketKey)
+}
+func LeagueBracketUri(leagueKey *datastore.Key, bracketKey *datastore.Key) string {
+	return fmt.Sprintf("%s/brackets/%s", LeagueUri(leagueKey), BracketId(bracketKey))
+}
+
+// A match in a bracket. Once both of its teams are known the slot is backed by a
+// ScheduledMatch, and its winner advances once the match's results are final.
+//
+// The key is KeyForBracketSlot.
+//
+// Ancestor: League
+type BracketSlot struct {
+	Bracket *datastore.Key
+
+	// Unique within the bracket. Example: "W1-0" is the first slot of the first round of
+	// the winners section.
+	Id       string
+	Section  BracketSection
+	Round    int // From 1, within the section.
+	Position int // From 0, within the round.
+
+	// Nil until known, or if there is no team (a bye).
+	HomeTeam *datastore.Key
+	AwayTeam *datastore.Key
+
+	// Set once HomeTeam and AwayTeam will not change.
+	HomeReady bool
+	AwayReady bool
+
+	// The slots the winner and loser advance to, if any.
+	WinnerTo     string
+	WinnerToHome bool
+	LoserTo      string
+	LoserToHome  bool
+
+	ScheduledMatch *datastore.Key
+
+	// Set once the winner has advanced. A slot with a single team is resolved without a
+	// match.
+	Resolved bool
+	Winner   *datastore.Key
+	Loser    *datastore.Key
+
+	// Set if a league editor chose the winner, such as for a tied match.
+	WinnerChosen bool
+}
+
+func KeyForBracketSlot(
+	c appengine.Context, bracketKey *datastore.Key, slotId string) *datastore.Key {
+	return datastore.NewKey(
+		c, "BracketSlot", fmt.Sprintf("%s/%s", BracketId(bracketKey), slotId), 0,
+		bracketKey.Parent())
+}
+
+// Returns the order in which seeds 1 to size (a power of two) are placed in the first
+// round, so the top seeds meet as late as possible.
+func seedOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		next := make([]int, 0, 2*len(order))
+		for _, seed := range order {
+			next = append(next, seed, 2*len(order)+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+func bracketSlotId(section BracketSection, round int, position int) string {
+	switch section {
+	case LosersSection:
+		return fmt.Sprintf("L%d-%d", round, position)
+	case GrandFinalSection:
+		return "GF"
+	}
+	return fmt.Sprintf("W%d-%d", round, position)
+}
+
+// Lays out the slots of a bracket between seeds, first seed first. Seeds without an
+// opponent in the first round have a bye.
+//
+// A double elimination bracket has a single grand final, without a reset.
+func newBracketSlots(typ BracketType, seeds []*datastore.Key) ([]*BracketSlot, error) {
+	minTeams := 2
+	if typ == DoubleElimination {
+		minTeams = 3
+	}
+	if len(seeds) < minTeams {
+		return nil, errors.New(fmt.Sprintf(
+			"A %v bracket needs at least %d teams", typ, minTeams))
+	}
+
+	size, rounds := 1, 0
+	for size < len(seeds) {
+		size *= 2
+		rounds++
+	}
+
+	var slots []*BracketSlot
+	newSlot := func(section BracketSection, round int, position int) *BracketSlot {
+		slot := &BracketSlot{
+			Id:       bracketSlotId(section, round, position),
+			Section:  section,
+			Round:    round,
+			Position: position,
+		}
+		slots = append(slots, slot)
+		return slot
+	}
+
+	order := seedOrder(size)
+	for r := 1; r <= rounds; r++ {
+		for p := 0; p < size>>uint(r); p++ {
+			slot := newSlot(WinnersSection, r, p)
+			if r == 1 {
+				slot.HomeReady, slot.AwayReady = true, true
+				if seed := order[2*p]; seed <= len(seeds) {
+					slot.HomeTeam = seeds[seed-1]
+				}
+				if seed := order[2*p+1]; seed <= len(seeds) {
+					slot.AwayTeam = seeds[seed-1]
+				}
+			}
+			if r < rounds {
+				slot.WinnerTo = bracketSlotId(WinnersSection, r+1, p/2)
+				slot.WinnerToHome = p%2 == 0
+			}
+		}
+	}
+	if typ == SingleElimination {
+		return slots, nil
+	}
+
+	// Losers of winners round 1 play each other in losers round 1. Losers of each later
+	// winners round r join the even losers round 2(r-1). Odd losers rounds after the
+	// first halve the remaining teams.
+	for _, slot := range slots {
+		switch {
+		case slot.Round == 1:
+			slot.LoserTo = bracketSlotId(LosersSection, 1, slot.Position/2)
+			slot.LoserToHome = slot.Position%2 == 0
+		default:
+			slot.LoserTo = bracketSlotId(LosersSection, 2*(slot.Round-1), slot.Position)
+			slot.LoserToHome = false
+		}
+		if slot.Round == rounds {
+			slot.WinnerTo = bracketSlotId(GrandFinalSection, 1, 0)
+			slot.WinnerToHome = true
+		}
+	}
+	losersRounds := 2 * (rounds - 1)
+	for j := 1; j <= losersRounds; j++ {
+		count := size >> uint((j+1)/2+1)
+		for p := 0; p < count; p++ {
+			slot := newSlot(LosersSection, j, p)
+			switch {
+			case j == losersRounds:
+				slot.WinnerTo = bracketSlotId(GrandFinalSection, 1, 0)
+				slot.WinnerToHome = false
+			case j%2 == 1:
+				slot.WinnerTo = bracketSlotId(LosersSection, j+1, p)
+				slot.WinnerToHome = true
+			default:
+				slot.WinnerTo = bracketSlotId(LosersSection, j+1, p/2)
+				slot.WinnerToHome = p%2 == 0
+			}
+		}
+	}
+	newSlot(GrandFinalSection, 1, 0)
+	return slots, nil
+}
+
+// Resolves every slot that has fewer than two teams, or whose teams are known and whose
+// id is in homeWon, which is whether the home team won. Winners and losers advance to
+// their next slots. Returns the slots that changed.
+func advanceBracketSlots(slots []*BracketSlot, homeWon map[string]bool) []*BracketSlot {
+	byId := make(map[string]*BracketSlot)
+	for _, slot := range slots {
+		byId[slot.Id] = slot
+	}
+	changed := make(map[string]bool)
+	advance := func(to string, home bool, team *datastore.Key) {
+		next := byId[to]
+		if next == nil {
+			return
+		}
+		if home {
+			next.HomeTeam, next.HomeReady = team, true
+		} else {
+			next.AwayTeam, next.AwayReady = team, true
+		}
+		changed[to] = true
+	}
+
+	for progress := true; progress; {
+		progress = false
+		for _, slot := range slots {
+			if slot.Resolved || !slot.HomeReady || !slot.AwayReady {
+				continue
+			}
+			switch {
+			case slot.HomeTeam == nil:
+				slot.Winner = slot.AwayTeam
+			case slot.AwayTeam == nil:
+				slot.Winner = slot.HomeTeam
+			default:
+				won, decided := homeWon[slot.Id]
+				if !decided {
+					continue
+				}
+				if won {
+					slot.Winner, slot.Loser = slot.HomeTeam, slot.AwayTeam
+				} else {
+					slot.Winner, slot.Loser = slot.AwayTeam, slot.HomeTeam
+				}
+			}
+			slot.Resolved = true
+			changed[slot.Id] = true
+			progress = true
+			advance(slot.WinnerTo, slot.WinnerToHome, slot.Winner)
+			advance(slot.LoserTo, slot.LoserToHome, slot.Loser)
+		}
+	}
+
+	var ret []*BracketSlot
+	for _, slot := range slots {
+		if changed[slot.Id] {
+			ret = append(ret, slot)
+		}
+	}
+	return ret
+}
+
+// Resolves the slot with slotId, whose teams must be known, as won by the home team or
+// the away team. Returns the slots that changed.
+func chooseBracketSlotWinner(
+	slots []*BracketSlot, slotId string, homeWon bool) ([]*BracketSlot, error) {
+	for _, slot := range slots {
+		if slot.Id != slotId {
+			continue
+		}
+		if slot.Resolved {
+			return nil, errors.New(fmt.Sprintf("Slot %s already has a winner", slotId))
+		}
+		if !slot.HomeReady || !slot.AwayReady || slot.HomeTeam == nil || slot.AwayTeam == nil {
+			return nil, errors.New(fmt.Sprintf("Slot %s does not have both teams yet", slotId))
+		}
+		slot.WinnerChosen = true
+		return advanceBracketSlots(slots, map[string]bool{slotId: homeWon}), nil
+	}
+	return nil, errors.New(fmt.Sprintf("Unknown bracket slot '%s'", slotId))
+}
+
+// Returns whether the home team won a match, given the results of the home and away
+// teams. decided is false if either result is missing or not final, or the match is tied.
+func matchResultHomeWon(home *MatchResult, away *MatchResult) (won bool, decided bool) {
+	if home == nil || away == nil || !home.IsFinal || !away.IsFinal {
+		return false, false
+	}
+	if home.GamesWon == away.GamesWon {
+		return false, false
+	}
+	return home.GamesWon > away.GamesWon, true
+}
+
+// Ordered by section, round and position.
+type bracketSlotsByPosition []*BracketSlot
+
+func (a bracketSlotsByPosition) Len() int      { return len(a) }
+func (a bracketSlotsByPosition) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a bracketSlotsByPosition) Less(i, j int) bool {
+	if a[i].Section != a[j].Section {
+		return a[i].Section < a[j].Section
+	}
+	if a[i].Round != a[j].Round {
+		return a[i].Round < a[j].Round
+	}
+	return a[i].Position < a[j].Position
+}
+
+// Creates a bracket between the top numTeams teams of the league's standings within
+// scope, and the matches of its first round.
+func CreateBracket(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	scope *LeagueScope,
+	bracket *Bracket,
+	numTeams int) (*datastore.Key, error) {
+	if err := canEditLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, err
+	}
+	if bracket.Name == "" {
+		return nil, errors.New("A bracket needs a name")
+	}
+	if bracket.NumGames%2 == 0 && bracket.NumGames > 0 {
+		return nil, errors.New(fmt.Sprintf(
+			"Bracket matches need an odd number of games so that they cannot tie, not %d",
+			bracket.NumGames))
+	}
+
+	standings, _, err := LeagueStandings(c, userAcls, league, leagueKey, scope)
+	if err != nil {
+		return nil, err
+	}
+	bracket.Season = league.CurrentSeason
+	if scope != nil {
+		bracket.Season = scope.SeasonKey
+	}
+	if numTeams <= 0 || numTeams > len(standings) {
+		numTeams = len(standings)
+	}
+	bracket.Seeds = make([]*datastore.Key, numTeams)
+	for i := range bracket.Seeds {
+		bracket.Seeds[i] = standings[i].TeamKey
+	}
+	slots, err := newBracketSlots(bracket.Type, bracket.Seeds)
+	if err != nil {
+		return nil, err
+	}
+	bracket.Created = time.Now()
+
+	var bracketKey *datastore.Key
+	err = datastore.RunInTransaction(c, func(c appengine.Context) error {
+		var err error
+		bracketKey, err = datastore.Put(
+			c, datastore.NewIncompleteKey(c, "Bracket", leagueKey), bracket)
+		if err != nil {
+			return errwrap.Wrap(err)
+		}
+		for _, slot := range slots {
+			slot.Bracket = bracketKey
+		}
+		return putBracketSlots(c, bracket, bracketKey, slots, advanceBracketSlots(slots, nil))
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return bracketKey, nil
+}
+
+// Saves slots, creating the match of every changed slot whose teams are known.
+func putBracketSlots(
+	c appengine.Context,
+	bracket *Bracket,
+	bracketKey *datastore.Key,
+	slots []*BracketSlot,
+	changed []*BracketSlot) error {
+	isChanged := make(map[string]bool)
+	for _, slot := range changed {
+		isChanged[slot.Id] = true
+	}
+
+	now := time.Now()
+	keys := make([]*datastore.Key, len(slots))
+	for i, slot := range slots {
+		keys[i] = KeyForBracketSlot(c, bracketKey, slot.Id)
+		if !isChanged[slot.Id] || slot.Resolved || slot.ScheduledMatch != nil ||
+			!slot.HomeReady || !slot.AwayReady {
+			continue
+		}
+		match := &ScheduledMatch{
+			Summary:          bracket.Summary,
+			Description:      fmt.Sprintf("%s, %s", bracket.Name, slot.Id),
+			PrimaryTag:       bracket.Name,
+			Season:           bracket.Season,
+			TeamKeys:         []*datastore.Key{slot.HomeTeam, slot.AwayTeam},
+			NumGames:         bracket.NumGames,
+			MapId:            bracket.MapId,
+			GameMode:         bracket.GameMode,
+			OfficialDatetime: now,
+			DateEarliest:     now,
+			DateLatest:       now.AddDate(0, 0, bracket.WindowDays),
+		}
+		matchKey, err := datastore.Put(
+			c, datastore.NewIncompleteKey(c, "ScheduledMatch", bracketKey.Parent()), match)
+		if err != nil {
+			return errwrap.Wrap(err)
+		}
+		slot.ScheduledMatch = matchKey
+	}
+	_, err := datastore.PutMulti(c, keys, slots)
+	return errwrap.Wrap(err)
+}
+
+// Advances the winners of the bracket's slots whose match results are final.
+func advanceBracket(c appengine.Context, bracketKey *datastore.Key) error {
+	return datastore.RunInTransaction(c, func(c appengine.Context) error {
+		bracket := new(Bracket)
+		if err := datastore.Get(c, bracketKey, bracket); err != nil {
+			return errwrap.Wrap(err)
+		}
+		slots, err := getBracketSlots(c, bracketKey)
+		if err != nil {
+			return err
+		}
+
+		homeWon := make(map[string]bool)
+		for _, slot := range slots {
+			if slot.Resolved || slot.ScheduledMatch == nil {
+				continue
+			}
+			results := make([]*MatchResult, 2)
+			for i, teamKey := range []*datastore.Key{slot.HomeTeam, slot.AwayTeam} {
+				results[i] = new(MatchResult)
+				resultKey := KeyForMatchResult(c, slot.ScheduledMatch, teamKey)
+				err := datastore.Get(c, resultKey, results[i])
+				if err == datastore.ErrNoSuchEntity {
+					results[i] = nil
+				} else if err != nil {
+					return errwrap.Wrap(err)
+				}
+			}
+			if won, decided := matchResultHomeWon(results[0], results[1]); decided {
+				homeWon[slot.Id] = won
+			}
+		}
+
+		changed := advanceBracketSlots(slots, homeWon)
+		if len(changed) == 0 {
+			return nil
+		}
+		return putBracketSlots(c, bracket, bracketKey, slots, changed)
+	}, nil)
+}
+
+// Chooses the winner of a bracket slot as a league editor, for matches that cannot be
+// decided by their results, such as ties. The winner advances as if they won the match.
+func BracketChooseWinner(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	bracketKey *datastore.Key,
+	slotId string,
+	homeWon bool) error {
+	if err := canEditLeague(c, userAcls, league, leagueKey); err != nil {
+		return err
+	}
+	return datastore.RunInTransaction(c, func(c appengine.Context) error {
+		bracket := new(Bracket)
+		if err := datastore.Get(c, bracketKey, bracket); err != nil {
+			return errwrap.Wrap(err)
+		}
+		slots, err := getBracketSlots(c, bracketKey)
+		if err != nil {
+			return err
+		}
+		changed, err := chooseBracketSlotWinner(slots, slotId, homeWon)
+		if err != nil {
+			return err
+		}
+		return putBracketSlots(c, bracket, bracketKey, slots, changed)
+	}, nil)
+}
+
+// Advances the bracket the match belongs to, if any.
+func AdvanceBracketForMatch(c appengine.Context, matchKey *datastore.Key) error {
+	var slots []*BracketSlot
+	q := datastore.NewQuery("BracketSlot").Ancestor(matchKey.Parent()).
+		Filter("ScheduledMatch =", matchKey)
+	if _, err := q.GetAll(c, &slots); err != nil {
+		return errwrap.Wrap(err)
+	}
+	for _, slot := range slots {
+		if err := advanceBracket(c, slot.Bracket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getBracketSlots(c appengine.Context, bracketKey *datastore.Key) ([]*BracketSlot, error) {
+	var slots []*BracketSlot
+	q := datastore.NewQuery("BracketSlot").Ancestor(bracketKey.Parent()).
+		Filter("Bracket =", bracketKey)
+	if _, err := q.GetAll(c, &slots); err != nil {
+		return nil, errwrap.Wrap(err)
+	}
+	sort.Sort(bracketSlotsByPosition(slots))
+	return slots, nil
+}
+
+// Returns a bracket and its slots ordered by section, round and position.
+func BracketById(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	bracketId string) (*Bracket, *datastore.Key, []*BracketSlot, error) {
+	bracketKey, err := DecodeKeyShort(c, "Bracket", bracketId, leagueKey)
+	if err != nil {
+		return nil, nil, nil, errwrap.Wrap(err)
+	}
+	if err := canViewLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, nil, nil, err
+	}
+
+	bracket := new(Bracket)
+	if err := datastore.Get(c, bracketKey, bracket); err != nil {
+		return nil, nil, nil, errwrap.Wrap(err)
+	}
+	slots, err := getBracketSlots(c, bracketKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return bracket, bracketKey, slots, nil
+}
+
+// Returns every bracket in a league.
+func LeagueBrackets(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key) ([]*Bracket, []*datastore.Key, error) {
+	if err := canViewLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, nil, err
+	}
+
+	var brackets []*Bracket
+	keys, err := datastore.NewQuery("Bracket").Ancestor(leagueKey).Order("Created").
+		GetAll(c, &brackets)
+	if err != nil {
+		return nil, nil, errwrap.Wrap(err)
+	}
+	return brackets, keys, nil
+}
@@ -0,0 +1,178 @@
+package model
+
+import (
+	"appengine/datastore"
+	"reflect"
+	"testing"
+)
+
+func TestSeedOrder(t *testing.T) {
+	if got, want := seedOrder(8), []int{1, 8, 4, 5, 2, 7, 3, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// Plays out a bracket between numTeams teams in which the better seed always wins.
+// Returns the slots and the number of matches each seed lost.
+func playBracket(t *testing.T, typ BracketType, numTeams int) ([]*BracketSlot, map[int]int) {
+	seeds := make([]*datastore.Key, numTeams)
+	seedOf := make(map[*datastore.Key]int)
+	for i := range seeds {
+		// Distinct keys without an appengine.Context.
+		seeds[i] = new(datastore.Key)
+		seedOf[seeds[i]] = i + 1
+	}
+	slots, err := newBracketSlots(typ, seeds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	homeWon := make(map[string]bool)
+	losses := make(map[int]int)
+	for round := 0; round < 2*len(slots); round++ {
+		advanceBracketSlots(slots, homeWon)
+		played := false
+		for _, slot := range slots {
+			if _, decided := homeWon[slot.Id]; decided ||
+				slot.Resolved || !slot.HomeReady || !slot.AwayReady {
+				continue
+			}
+			homeWon[slot.Id] = seedOf[slot.HomeTeam] < seedOf[slot.AwayTeam]
+			if homeWon[slot.Id] {
+				losses[seedOf[slot.AwayTeam]]++
+			} else {
+				losses[seedOf[slot.HomeTeam]]++
+			}
+			played = true
+		}
+		if !played {
+			break
+		}
+	}
+	for _, slot := range slots {
+		if !slot.Resolved {
+			t.Errorf("%v, %d teams: slot %s is not resolved", typ, numTeams, slot.Id)
+		}
+	}
+	return slots, losses
+}
+
+func bracketChampion(slots []*BracketSlot) *BracketSlot {
+	for _, slot := range slots {
+		if slot.WinnerTo == "" {
+			return slot
+		}
+	}
+	return nil
+}
+
+func TestSingleEliminationBracket(t *testing.T) {
+	for numTeams := 2; numTeams <= 9; numTeams++ {
+		slots, losses := playBracket(t, SingleElimination, numTeams)
+		final := bracketChampion(slots)
+		if final == nil || final.Winner == nil || final.Winner != final.HomeTeam {
+			t.Errorf("%d teams: got final %+v", numTeams, final)
+		}
+		for seed := 2; seed <= numTeams; seed++ {
+			if losses[seed] != 1 {
+				t.Errorf("%d teams: seed %d lost %d times", numTeams, seed, losses[seed])
+			}
+		}
+		if losses[1] != 0 {
+			t.Errorf("%d teams: the first seed lost", numTeams)
+		}
+	}
+
+	// The top seeds have byes.
+	slots, _ := playBracket(t, SingleElimination, 6)
+	if slots[0].Id != "W1-0" || slots[0].AwayTeam != nil || slots[0].ScheduledMatch != nil {
+		t.Errorf("got first slot %+v", slots[0])
+	}
+}
+
+func TestDoubleEliminationBracket(t *testing.T) {
+	for numTeams := 3; numTeams <= 9; numTeams++ {
+		slots, losses := playBracket(t, DoubleElimination, numTeams)
+		final := bracketChampion(slots)
+		if final == nil || final.Section != GrandFinalSection || final.Winner != final.HomeTeam {
+			t.Errorf("%d teams: got final %+v", numTeams, final)
+			continue
+		}
+		if losses[1] != 0 {
+			t.Errorf("%d teams: the first seed lost", numTeams)
+		}
+		// The second seed only loses to the first seed, in the winners final and the grand
+		// final.
+		if losses[2] != 2 {
+			t.Errorf("%d teams: seed 2 lost %d times", numTeams, losses[2])
+		}
+		for seed := 3; seed <= numTeams; seed++ {
+			if losses[seed] > 2 || losses[seed] < 1 {
+				t.Errorf("%d teams: seed %d lost %d times", numTeams, seed, losses[seed])
+			}
+		}
+	}
+
+	if _, err := newBracketSlots(DoubleElimination, make([]*datastore.Key, 2)); err == nil {
+		t.Errorf("created a double elimination bracket for two teams")
+	}
+}
+
+func TestMatchResultHomeWon(t *testing.T) {
+	home := &MatchResult{IsFinal: true, GamesWon: 2, GamesLost: 1}
+	away := &MatchResult{IsFinal: true, GamesWon: 1, GamesLost: 2}
+	if won, decided := matchResultHomeWon(home, away); !won || !decided {
+		t.Errorf("got %v, %v, want a home win", won, decided)
+	}
+	if won, decided := matchResultHomeWon(away, home); won || !decided {
+		t.Errorf("got %v, %v, want an away win", won, decided)
+	}
+	if _, decided := matchResultHomeWon(home, nil); decided {
+		t.Errorf("decided without an away result")
+	}
+	if _, decided := matchResultHomeWon(home, home); decided {
+		t.Errorf("decided a tie")
+	}
+	away.IsFinal = false
+	if _, decided := matchResultHomeWon(home, away); decided {
+		t.Errorf("decided without a final result")
+	}
+}
+
+func TestChooseBracketSlotWinner(t *testing.T) {
+	seeds := make([]*datastore.Key, 4)
+	for i := range seeds {
+		seeds[i] = new(datastore.Key)
+	}
+	slots, err := newBracketSlots(SingleElimination, seeds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	advanceBracketSlots(slots, nil)
+
+	if _, err := chooseBracketSlotWinner(slots, "W2-0", true); err == nil {
+		t.Errorf("chose the winner of a slot without its teams")
+	}
+	if _, err := chooseBracketSlotWinner(slots, "X1-0", true); err == nil {
+		t.Errorf("chose the winner of an unknown slot")
+	}
+
+	changed, err := chooseBracketSlotWinner(slots, "W1-0", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := slots[0]
+	if !first.Resolved || !first.WinnerChosen || first.Winner != first.AwayTeam {
+		t.Errorf("got slot %+v, want the away team chosen", first)
+	}
+	final := bracketChampion(slots)
+	if final.HomeTeam != first.AwayTeam {
+		t.Errorf("the chosen winner did not advance: got final %+v", final)
+	}
+	if len(changed) != 2 {
+		t.Errorf("got %d changed slots, want the slot and the final", len(changed))
+	}
+	if _, err := chooseBracketSlotWinner(slots, "W1-0", true); err == nil {
+		t.Errorf("chose the winner of a resolved slot")
+	}
+}
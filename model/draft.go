@@ -0,0 +1,99 @@
+package model
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"github.com/OwenDurni/loltools/analysis"
+	"github.com/OwenDurni/loltools/riot"
+	"github.com/OwenDurni/loltools/util/errwrap"
+)
+
+// The pick and ban statistics of a league team. Not directly stored in datastore.
+type TeamDraftReport struct {
+	TeamKey *datastore.Key
+	*analysis.DraftReport
+}
+
+// Pick and ban statistics across a set of league games. Not directly stored in
+// datastore.
+type DraftReport struct {
+	// Statistics of both sides of every game.
+	Overall *analysis.DraftReport
+
+	// In the order the teams were requested in.
+	Teams []*TeamDraftReport
+
+	// The number of games whose details have been archived.
+	Games int
+
+	// The number of games left out because their details have not been archived yet or
+	// Riot has none.
+	MissingGames int
+}
+
+// Computes draft statistics for teamKeys across gameKeys from the archived
+// GameMatchDetail of each game. A team is credited with a game only if it has a
+// GameByTeam for it, which also says which side the team played on.
+func GetDraftReport(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	teamKeys []*datastore.Key,
+	gameKeys []*datastore.Key) (*DraftReport, error) {
+	if userAcls != nil {
+		if *userAcls.UserKey != *league.Owner {
+			if err := userAcls.Can(c, PermissionView, leagueKey); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	matches, err := GetGameMatchDetails(c, gameKeys)
+	if err != nil {
+		return nil, err
+	}
+	matchByGameId := make(map[string]*riot.MatchDetail)
+	report := new(DraftReport)
+	var allDrafts []*analysis.SideDraft
+	for i, match := range matches {
+		if match == nil {
+			report.MissingGames++
+			continue
+		}
+		report.Games++
+		matchByGameId[gameKeys[i].StringID()] = match
+		allDrafts = append(allDrafts,
+			analysis.Draft(match, riot.BlueTeamId),
+			analysis.Draft(match, riot.PurpleTeamId))
+	}
+	report.Overall = analysis.NewDraftReport(allDrafts)
+
+	report.Teams = make([]*TeamDraftReport, len(teamKeys))
+	for i, teamKey := range teamKeys {
+		q := datastore.NewQuery("GameByTeam").Ancestor(leagueKey).
+			Filter("TeamKey =", teamKey)
+		var gameByTeams []*GameByTeam
+		if _, err := q.GetAll(c, &gameByTeams); err != nil {
+			return nil, errwrap.Wrap(err)
+		}
+
+		var drafts []*analysis.SideDraft
+		for _, g := range gameByTeams {
+			match := matchByGameId[g.GameKey.StringID()]
+			if match == nil {
+				continue
+			}
+			// Games where the team made up both sides are scrimmages, not league games.
+			if len(g.RiotTeamIds) != 1 {
+				continue
+			}
+			drafts = append(drafts, analysis.Draft(match, g.RiotTeamIds[0]))
+		}
+		report.Teams[i] = &TeamDraftReport{
+			TeamKey:     teamKey,
+			DraftReport: analysis.NewDraftReport(drafts),
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,444 @@
+package model
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"errors"
+	"fmt"
+	"github.com/OwenDurni/loltools/riot"
+	"github.com/OwenDurni/loltools/util/errwrap"
+	"golang.org/x/net/context"
+	"strings"
+	"time"
+)
+
+// Solo queue tiers, lowest first, as named by riot.Rank.
+var RankTiers = []string{
+	"UNRANKED", "BRONZE", "SILVER", "GOLD", "PLATINUM", "DIAMOND", "MASTER", "CHALLENGER",
+}
+
+// Divisions within a tier, lowest first.
+var rankDivisions = []string{"V", "IV", "III", "II", "I"}
+
+// Returns the index of a tier in RankTiers.
+func ParseRankTier(name string) (int, error) {
+	for i, tier := range RankTiers {
+		if strings.EqualFold(tier, name) {
+			return i, nil
+		}
+	}
+	return 0, errors.New(fmt.Sprintf("Unknown tier '%s'", name))
+}
+
+// Orders ranks: five steps per tier, one per division. Tiers without divisions count as
+// their lowest division.
+func rankValue(rank *riot.Rank) (int, error) {
+	tier, err := ParseRankTier(rank.Tier)
+	if err != nil {
+		return 0, err
+	}
+	value := 5 * tier
+	for i, division := range rankDivisions {
+		if rank.Division == division {
+			value += i
+		}
+	}
+	return value, nil
+}
+
+// Who may play in a league. Zero values do not restrict anything.
+type EligibilityRules struct {
+	// The lowest and highest solo queue tiers players may have, from RankTiers.
+	MinTier string
+	MaxTier string
+
+	MinLevel int
+
+	// Players may only be on one team's roster per season.
+	OneTeamPerLeague bool
+
+	// The highest tier the average rank of a team's ranked players may reach.
+	MaxAverageTier string
+}
+
+func (r *EligibilityRules) usesRanks() bool {
+	return r.MinTier != "" || r.MaxTier != "" || r.MaxAverageTier != ""
+}
+
+func (r *EligibilityRules) validate() error {
+	tiers := make([]int, 3)
+	for i, name := range []string{r.MinTier, r.MaxTier, r.MaxAverageTier} {
+		if name == "" {
+			continue
+		}
+		var err error
+		if tiers[i], err = ParseRankTier(name); err != nil {
+			return err
+		}
+	}
+	if r.MinTier != "" && r.MaxTier != "" && tiers[0] > tiers[1] {
+		return errors.New(fmt.Sprintf(
+			"The minimum tier (%s) is above the maximum tier (%s)", r.MinTier, r.MaxTier))
+	}
+	if r.MinLevel < 0 {
+		return errors.New("The minimum level must be non-negative")
+	}
+	return nil
+}
+
+// What eligibility is checked against for a player on a team.
+type playerEligibility struct {
+	player *Player
+	// Nil unless the rules use ranks.
+	rank *riot.Rank
+	// The number of other teams in the league the player is on.
+	otherTeams int
+}
+
+// Returns the reasons a player is not eligible to play in the league.
+func (r *EligibilityRules) playerViolations(p *playerEligibility) []string {
+	var violations []string
+	if r.MinLevel > 0 && p.player.Level < r.MinLevel {
+		violations = append(violations, fmt.Sprintf(
+			"%s is level %d; players must be at least level %d",
+			p.player.Summoner, p.player.Level, r.MinLevel))
+	}
+	if r.OneTeamPerLeague && p.otherTeams > 0 {
+		violations = append(violations, fmt.Sprintf(
+			"%s is already on another team; players may only be on one team",
+			p.player.Summoner))
+	}
+	if p.rank != nil {
+		tier, err := ParseRankTier(p.rank.Tier)
+		if err != nil {
+			return append(violations, err.Error())
+		}
+		if r.MinTier != "" {
+			if min, err := ParseRankTier(r.MinTier); err == nil && tier < min {
+				violations = append(violations, fmt.Sprintf(
+					"%s is %s; players must be at least %s",
+					p.player.Summoner, p.rank.String(), r.MinTier))
+			}
+		}
+		if r.MaxTier != "" {
+			if max, err := ParseRankTier(r.MaxTier); err == nil && tier > max {
+				violations = append(violations, fmt.Sprintf(
+					"%s is %s; players may be at most %s",
+					p.player.Summoner, p.rank.String(), r.MaxTier))
+			}
+		}
+	}
+	return violations
+}
+
+// Returns why a roster's average rank is above the cap, or "". Unranked players are not
+// part of the average.
+func (r *EligibilityRules) averageViolation(roster []*playerEligibility) string {
+	if r.MaxAverageTier == "" {
+		return ""
+	}
+	max, err := ParseRankTier(r.MaxAverageTier)
+	if err != nil {
+		return err.Error()
+	}
+	total, count := 0, 0
+	for _, p := range roster {
+		if p.rank == nil {
+			continue
+		}
+		value, err := rankValue(p.rank)
+		if err != nil || value < 5 {
+			continue
+		}
+		total += value
+		count++
+	}
+	if count == 0 || total < 5*(max+1)*count {
+		return ""
+	}
+	return fmt.Sprintf("The team's average rank is %s; it may be at most %s",
+		RankTiers[total/count/5], r.MaxAverageTier)
+}
+
+// Returns every eligibility violation of a roster.
+func (r *EligibilityRules) rosterViolations(roster []*playerEligibility) []string {
+	var violations []string
+	for _, p := range roster {
+		violations = append(violations, r.playerViolations(p)...)
+	}
+	if v := r.averageViolation(roster); v != "" {
+		violations = append(violations, v)
+	}
+	return violations
+}
+
+// How long PlayerRank is used before it is fetched from Riot again.
+const PlayerRankMaxAge = 24 * time.Hour
+
+// A player's solo queue rank, cached so that eligibility checks do not call Riot for
+// every player every time.
+//
+// ("%s-%s", Region, RiotId) of the player is the key.
+type PlayerRank struct {
+	PlayerKey *datastore.Key
+	Rank      riot.Rank
+
+	LastUpdated time.Time
+}
+
+func KeyForPlayerRank(c appengine.Context, player *Player) *datastore.Key {
+	return datastore.NewKey(c, "PlayerRank", player.Id(), 0, nil)
+}
+
+// Returns the cached solo queue rank of player, refreshing it from Riot if it is missing
+// or older than PlayerRankMaxAge.
+func GetPlayerRank(
+	c appengine.Context, ctx context.Context, player *Player) (*riot.Rank, error) {
+	key := KeyForPlayerRank(c, player)
+	cached := new(PlayerRank)
+	err := datastore.Get(c, key, cached)
+	if err != nil && err != datastore.ErrNoSuchEntity {
+		return nil, errwrap.Wrap(err)
+	}
+	if err == nil && time.Since(cached.LastUpdated) < PlayerRankMaxAge {
+		return &cached.Rank, nil
+	}
+
+	rank, err := RiotClient(c, player.Region).SoloQueueRankBySummonerId(ctx, player.RiotId)
+	if err != nil {
+		// Unwrapped so that callers can tell a Riot outage from an ineligible player.
+		return nil, err
+	}
+	fresh := &PlayerRank{
+		PlayerKey:   KeyForPlayer(c, player.Region, player.RiotId),
+		Rank:        *rank,
+		LastUpdated: time.Now(),
+	}
+	if _, err := datastore.Put(c, key, fresh); err != nil {
+		return rank, errwrap.Wrap(err)
+	}
+	return rank, nil
+}
+
+// Returns how many teams other than teamKey a player is on in the league's current
+// season.
+func playerOtherTeams(
+	c appengine.Context,
+	league *League,
+	leagueKey *datastore.Key,
+	teamKey *datastore.Key,
+	playerKey *datastore.Key) (int, error) {
+	var memberships []*TeamMembership
+	q := datastore.NewQuery("TeamMembership").Ancestor(leagueKey).
+		Filter("PlayerKey =", playerKey)
+	if league.CurrentSeason != nil {
+		q = q.Filter("Season =", league.CurrentSeason)
+	}
+	if _, err := q.GetAll(c, &memberships); err != nil {
+		return 0, errwrap.Wrap(err)
+	}
+	teams := make(map[string]bool)
+	for _, m := range memberships {
+		if m.IsCurrent() && !m.TeamKey.Equal(teamKey) {
+			teams[m.TeamKey.Encode()] = true
+		}
+	}
+	return len(teams), nil
+}
+
+// Returns an error listing why a player may not join a team's current roster, or nil if
+// they are eligible.
+func checkPlayerEligibility(
+	c appengine.Context,
+	ctx context.Context,
+	league *League,
+	leagueKey *datastore.Key,
+	teamKey *datastore.Key,
+	player *Player,
+	playerKey *datastore.Key) error {
+	rules := &league.EligibilityRules
+	candidate := &playerEligibility{player: player}
+	var roster []*playerEligibility
+
+	if rules.OneTeamPerLeague {
+		var err error
+		candidate.otherTeams, err = playerOtherTeams(c, league, leagueKey, teamKey, playerKey)
+		if err != nil {
+			return err
+		}
+	}
+	if rules.usesRanks() {
+		var err error
+		if candidate.rank, err = GetPlayerRank(c, ctx, player); err != nil {
+			return err
+		}
+	}
+	if rules.MaxAverageTier != "" {
+		players, _, err := teamPlayers(
+			c, nil, league, leagueKey, teamKey, league.CurrentSeason, KeysAndEntities)
+		if err != nil {
+			return err
+		}
+		for _, p := range players {
+			rank, err := GetPlayerRank(c, ctx, p)
+			if err != nil {
+				return err
+			}
+			roster = append(roster, &playerEligibility{player: p, rank: rank})
+		}
+	}
+
+	// Only the new player's violations, and what they do to the team, block adding them;
+	// players already on the roster are flagged by CheckLeagueEligibility.
+	violations := rules.playerViolations(candidate)
+	if v := rules.averageViolation(append(roster, candidate)); v != "" {
+		violations = append(violations, v)
+	}
+	if len(violations) > 0 {
+		return errors.New(fmt.Sprintf(
+			"%s is not eligible: %s", player.Summoner, strings.Join(violations, ". ")))
+	}
+	return nil
+}
+
+// The result of the last eligibility check of a team's current roster.
+//
+// EncodeKeyShort(TeamKey) is the key.
+//
+// Ancestor: League
+type TeamEligibility struct {
+	TeamKey *datastore.Key
+
+	// Empty if the team is eligible.
+	Violations []string
+
+	Checked time.Time
+}
+
+func KeyForTeamEligibility(c appengine.Context, teamKey *datastore.Key) *datastore.Key {
+	return datastore.NewKey(
+		c, "TeamEligibility", EncodeKeyShort(teamKey), 0, teamKey.Parent())
+}
+
+// Returns the result of the last eligibility check of a team, or nil if it has not been
+// checked.
+func GetTeamEligibility(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	teamKey *datastore.Key) (*TeamEligibility, error) {
+	if err := canViewLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, err
+	}
+	eligibility := new(TeamEligibility)
+	err := datastore.Get(c, KeyForTeamEligibility(c, teamKey), eligibility)
+	if err == datastore.ErrNoSuchEntity {
+		return nil, nil
+	}
+	return eligibility, errwrap.Wrap(err)
+}
+
+// Returns the teams of a league that failed their last eligibility check.
+func LeagueIneligibleTeams(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key) ([]*TeamEligibility, error) {
+	if err := canViewLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, err
+	}
+	var all []*TeamEligibility
+	if _, err := datastore.NewQuery("TeamEligibility").Ancestor(leagueKey).
+		GetAll(c, &all); err != nil {
+		return nil, errwrap.Wrap(err)
+	}
+	var ineligible []*TeamEligibility
+	for _, e := range all {
+		if len(e.Violations) > 0 {
+			ineligible = append(ineligible, e)
+		}
+	}
+	return ineligible, nil
+}
+
+// Checks the current roster of every team in a league against the league's eligibility
+// rules, which players can drift out of as their rank or other rosters change, and
+// records the result as each team's TeamEligibility.
+func CheckLeagueEligibility(
+	c appengine.Context,
+	ctx context.Context,
+	league *League,
+	leagueKey *datastore.Key) ([]*TeamEligibility, error) {
+	teams, teamKeys, err := LeagueAllTeams(c, nil, league, leagueKey)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := &league.EligibilityRules
+	rosters := make([][]*playerEligibility, len(teams))
+	rosterKeys := make([][]*datastore.Key, len(teams))
+	teamsOf := make(map[string]int)
+	for i := range teams {
+		players, playerKeys, err := teamPlayers(
+			c, nil, league, leagueKey, teamKeys[i], league.CurrentSeason, KeysAndEntities)
+		if err != nil {
+			return nil, err
+		}
+		rosterKeys[i] = playerKeys
+		for j, p := range players {
+			e := &playerEligibility{player: p}
+			if rules.usesRanks() {
+				if e.rank, err = GetPlayerRank(c, ctx, p); err != nil {
+					return nil, err
+				}
+			}
+			rosters[i] = append(rosters[i], e)
+			teamsOf[playerKeys[j].Encode()]++
+		}
+	}
+
+	results := make([]*TeamEligibility, len(teams))
+	resultKeys := make([]*datastore.Key, len(teams))
+	now := time.Now()
+	for i := range teams {
+		for j, e := range rosters[i] {
+			e.otherTeams = teamsOf[rosterKeys[i][j].Encode()] - 1
+		}
+		results[i] = &TeamEligibility{
+			TeamKey:    teamKeys[i],
+			Violations: rules.rosterViolations(rosters[i]),
+			Checked:    now,
+		}
+		resultKeys[i] = KeyForTeamEligibility(c, teamKeys[i])
+	}
+	if _, err := datastore.PutMulti(c, resultKeys, results); err != nil {
+		return nil, errwrap.Wrap(err)
+	}
+	return results, nil
+}
+
+// Sets the eligibility rules of a league. Rosters are not checked against the new rules
+// until the next CheckLeagueEligibility.
+func LeagueSetEligibilityRules(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	rules *EligibilityRules) error {
+	if err := canEditLeague(c, userAcls, league, leagueKey); err != nil {
+		return err
+	}
+	if err := rules.validate(); err != nil {
+		return err
+	}
+	return datastore.RunInTransaction(c, func(c appengine.Context) error {
+		league := new(League)
+		if err := datastore.Get(c, leagueKey, league); err != nil {
+			return errwrap.Wrap(err)
+		}
+		league.EligibilityRules = *rules
+		_, err := datastore.Put(c, leagueKey, league)
+		return errwrap.Wrap(err)
+	}, nil)
+}
@@ -0,0 +1,124 @@
+package model
+
+import (
+	"github.com/OwenDurni/loltools/riot"
+	"testing"
+)
+
+func TestRankValue(t *testing.T) {
+	// Lowest first.
+	ranks := []*riot.Rank{
+		{Tier: "Unranked"},
+		{Tier: "BRONZE", Division: "V"},
+		{Tier: "BRONZE", Division: "I"},
+		{Tier: "SILVER", Division: "V"},
+		{Tier: "PLATINUM", Division: "II"},
+		{Tier: "PLATINUM", Division: "I"},
+		{Tier: "DIAMOND", Division: "V"},
+		{Tier: "CHALLENGER", Division: "I"},
+	}
+	prev := -1
+	for _, rank := range ranks {
+		value, err := rankValue(rank)
+		if err != nil {
+			t.Fatalf("%v: %v", rank, err)
+		}
+		if value <= prev {
+			t.Errorf("%v: got %d, want more than %d", rank, value, prev)
+		}
+		prev = value
+	}
+	if _, err := rankValue(&riot.Rank{Tier: "WOOD"}); err == nil {
+		t.Errorf("unknown tiers should fail")
+	}
+}
+
+func TestEligibilityRulesValidate(t *testing.T) {
+	tests := []struct {
+		rules EligibilityRules
+		ok    bool
+	}{
+		{EligibilityRules{}, true},
+		{EligibilityRules{MinTier: "SILVER", MaxTier: "PLATINUM", MinLevel: 30}, true},
+		{EligibilityRules{MinTier: "GOLD", MaxTier: "GOLD"}, true},
+		{EligibilityRules{MinTier: "DIAMOND", MaxTier: "GOLD"}, false},
+		{EligibilityRules{MaxAverageTier: "WOOD"}, false},
+		{EligibilityRules{MinLevel: -1}, false},
+	}
+	for _, test := range tests {
+		if err := test.rules.validate(); (err == nil) != test.ok {
+			t.Errorf("%+v: got %v, want ok=%v", test.rules, err, test.ok)
+		}
+	}
+}
+
+func TestPlayerViolations(t *testing.T) {
+	rules := EligibilityRules{
+		MinTier:          "SILVER",
+		MaxTier:          "PLATINUM",
+		MinLevel:         30,
+		OneTeamPerLeague: true,
+	}
+	player := &Player{Summoner: "p", Level: 30}
+	gold := &riot.Rank{Tier: "GOLD", Division: "II"}
+
+	tests := []struct {
+		p    *playerEligibility
+		want int
+	}{
+		{&playerEligibility{player: player, rank: gold}, 0},
+		{&playerEligibility{player: &Player{Summoner: "p", Level: 29}, rank: gold}, 1},
+		{&playerEligibility{player: player, rank: gold, otherTeams: 1}, 1},
+		{&playerEligibility{player: player, rank: &riot.Rank{Tier: "DIAMOND"}}, 1},
+		{&playerEligibility{player: player, rank: &riot.Rank{Tier: "PLATINUM"}}, 0},
+		{&playerEligibility{player: player, rank: &riot.Rank{Tier: "Unranked"}}, 1},
+		{&playerEligibility{player: &Player{Level: 1}, rank: &riot.Rank{Tier: "MASTER"},
+			otherTeams: 2}, 3},
+	}
+	for i, test := range tests {
+		if got := rules.playerViolations(test.p); len(got) != test.want {
+			t.Errorf("test %d: got %q, want %d violation(s)", i, got, test.want)
+		}
+	}
+
+	// Without rank rules no rank is needed.
+	if got := (&EligibilityRules{}).playerViolations(
+		&playerEligibility{player: &Player{}, otherTeams: 3}); len(got) != 0 {
+		t.Errorf("empty rules: got %q", got)
+	}
+}
+
+func TestAverageViolation(t *testing.T) {
+	rules := EligibilityRules{MaxAverageTier: "GOLD"}
+	roster := func(ranks ...*riot.Rank) []*playerEligibility {
+		var r []*playerEligibility
+		for _, rank := range ranks {
+			r = append(r, &playerEligibility{player: &Player{}, rank: rank})
+		}
+		return r
+	}
+	gold := &riot.Rank{Tier: "GOLD", Division: "I"}
+	plat := &riot.Rank{Tier: "PLATINUM", Division: "V"}
+	silver := &riot.Rank{Tier: "SILVER", Division: "V"}
+	unranked := &riot.Rank{Tier: "Unranked"}
+
+	tests := []struct {
+		roster []*playerEligibility
+		ok     bool
+	}{
+		{nil, true},
+		{roster(gold, gold), true},
+		{roster(plat, plat), false},
+		// Averages to gold.
+		{roster(plat, silver), true},
+		// Unranked players do not lower the average.
+		{roster(plat, unranked, unranked), false},
+		{roster(unranked), true},
+	}
+	for i, test := range tests {
+		got := rules.averageViolation(test.roster)
+		if (got == "") != test.ok {
+			t.Errorf("test %d: got %q, want ok=%v", i, got, test.ok)
+		}
+	}
+}
@@ -265,6 +265,10 @@ func (ginfo *GameInfo) AddGamePlayer(
 
 	gtinfo.Players = append(gtinfo.Players, pinfo)
 	gtinfo.PlayerStats = append(gtinfo.PlayerStats, psinfo)
+	if pstats == nil {
+		// Stats have not been fetched yet.
+		return
+	}
 	gtinfo.IsWinner = pstats.RiotData.Win
 	gtinfo.ChampionsKilled += pstats.RiotData.ChampionsKilled
 	gtinfo.NumDeaths += pstats.RiotData.NumDeaths
@@ -375,6 +379,15 @@ func GetGameInfo(
 	game *Game) (*GameInfo, []error) {
 	info := NewGameInfo()
 	info.Game = game
+	return info.addGamePlayers(c, playerCache, game)
+}
+
+// Adds every player of game with their stats. Players of the app team must be added
+// before this.
+func (info *GameInfo) addGamePlayers(
+	c appengine.Context,
+	playerCache *PlayerCache,
+	game *Game) (*GameInfo, []error) {
 	errors := make([]error, 0, 8)
 
 	for _, playerDto := range game.Players {
@@ -398,6 +411,9 @@ func GetGameInfo(
 	return info, errors
 }
 
+// Returns a team's n most recent games within scope, each viewed with the team's roster
+// when it was played.
+//
 // Note that sometimes partial results are returned even if there is an error.
 func TeamRecentGameInfo(
 	c appengine.Context,
@@ -406,11 +422,12 @@ func TeamRecentGameInfo(
 	playerCache *PlayerCache,
 	league *League,
 	leagueKey *datastore.Key,
-	teamKey *datastore.Key) ([]*GameInfo, []error) {
+	teamKey *datastore.Key,
+	scope *LeagueScope) ([]*GameInfo, []error) {
 	infos := make([]*GameInfo, 0, n)
 	errors := make([]error, 0)
 
-	players, _, err := TeamAllPlayers(c, userAcls, league, leagueKey, teamKey, KeysAndEntities)
+	history, err := GetTeamRosterHistory(c, userAcls, league, leagueKey, teamKey)
 	if err != nil {
 		errors = append(errors, errwrap.Wrap(err))
 		return nil, errors
@@ -421,9 +438,14 @@ func TeamRecentGameInfo(
 		var gamesByTeam []*GameByTeam
 		q := datastore.NewQuery("GameByTeam").Ancestor(leagueKey).
 			Project("GameKey").
-			Filter("TeamKey =", teamKey).
-			Order("-DateTime").
-			Limit(n)
+			Filter("TeamKey =", teamKey)
+		if scope != nil {
+			q = q.Filter("DateTime >=", scope.Season.Start)
+			if !scope.Season.End.IsZero() {
+				q = q.Filter("DateTime <", scope.Season.End)
+			}
+		}
+		q = q.Order("-DateTime").Limit(n)
 		if _, err := q.GetAll(c, &gamesByTeam); err != nil {
 			errors = append(errors, errwrap.Wrap(err))
 			return infos, errors
@@ -453,20 +475,33 @@ func TeamRecentGameInfo(
 		if game == nil {
 			continue
 		}
-		info, errs := GetGameInfo(c, playerCache, game)
+		players := history.PlayersAt(game.StartDateTime)
+		info, errs := TeamGameInfo(c, playerCache, leagueKey, players, game)
 		infos = append(infos, info)
 		errors = append(errors, errs...)
-
-		info.LeagueId = EncodeKeyShort(leagueKey)
-		for _, p := range players {
-			info.AddAppTeamPlayer(p)
-		}
-		info.computeDerivedData()
 	}
 
 	return infos, errors
 }
 
+// Returns the GameInfo of game viewed by the league team with players.
+func TeamGameInfo(
+	c appengine.Context,
+	playerCache *PlayerCache,
+	leagueKey *datastore.Key,
+	players []*Player,
+	game *Game) (*GameInfo, []error) {
+	info := NewGameInfo()
+	info.Game = game
+	info.LeagueId = EncodeKeyShort(leagueKey)
+	for _, p := range players {
+		info.AddAppTeamPlayer(p)
+	}
+	_, errors := info.addGamePlayers(c, playerCache, game)
+	info.computeDerivedData()
+	return info, errors
+}
+
 type CollectiveGameStats struct {
 	// map[GameId]map[RiotSummonerId]*riot.GameDto
 	games map[string]*GameStats
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"github.com/OwenDurni/loltools/riot"
 	"github.com/OwenDurni/loltools/util/errwrap"
+	"golang.org/x/net/context"
 	"time"
 )
 
@@ -20,6 +21,20 @@ type League struct {
 
 	// The datastore key for the User who owns this league.
 	Owner *datastore.Key
+
+	// How teams earn points for matches, unless a match overrides it.
+	TeamScoring TeamScoringType
+
+	// The season new teams, matches and roster changes belong to. Nil until the league's
+	// first season starts.
+	CurrentSeason *datastore.Key
+
+	// Limits on team rosters, enforced when players are added, removed or updated.
+	RosterRules RosterRules
+
+	// Who may play in the league, checked when players are added and periodically by
+	// CheckLeagueEligibility.
+	EligibilityRules EligibilityRules
 }
 
 // Teams are identified by their datastore.Key.
@@ -71,12 +86,34 @@ type GameTags struct {
 }
 
 // A table associating summoners to teams. Summoners may be on more than one team
-// per league. Teams may have any number of summoners.
+// per league unless the league's EligibilityRules say otherwise. The league's RosterRules
+// limit the size of rosters.
+//
+// Players leaving a team keep their membership, with Left set, so it records the
+// roster's history.
 //
 // Ancestor: League
 type TeamMembership struct {
 	TeamKey   *datastore.Key
 	PlayerKey *datastore.Key
+
+	// The season of the roster. Nil before the league's first season.
+	Season *datastore.Key
+
+	Role RosterRole
+	// Substitutes are on the roster but not in the starting lineup.
+	Substitute bool
+	// At most one player per team is its captain.
+	Captain bool
+
+	// Zero for memberships from before join dates were recorded.
+	Joined time.Time
+	// Zero while the player is on the roster.
+	Left time.Time
+}
+
+func (m *TeamMembership) IsCurrent() bool {
+	return m.Left.IsZero()
 }
 
 // Various accumulated data about a team. Not directly stored in datastore.
@@ -115,6 +152,10 @@ func LeagueTeamUri(leagueKey *datastore.Key, teamKey *datastore.Key) string {
 		EncodeKeyShort(teamKey))
 }
 
+func LeagueGameUri(leagueKey *datastore.Key, gameKey *datastore.Key) string {
+	return fmt.Sprintf("%s/games/%s", LeagueUri(leagueKey), gameKey.StringID())
+}
+
 func LeaguesForUser(
 	c appengine.Context, userAcls *RequestorAclCache) ([]*League, []*datastore.Key, error) {
 
@@ -156,6 +197,33 @@ func LeaguesForUser(
 	return leagues, leagueKeys, nil
 }
 
+// Sets how teams earn points for matches in a league.
+func LeagueSetTeamScoring(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	leagueKey *datastore.Key,
+	scoringType TeamScoringType) error {
+	if _, err := TeamScoringFor(scoringType); err != nil {
+		return err
+	}
+	return datastore.RunInTransaction(c, func(c appengine.Context) error {
+		league := new(League)
+		if err := datastore.Get(c, leagueKey, league); err != nil {
+			return errwrap.Wrap(err)
+		}
+		if userAcls != nil {
+			if *userAcls.UserKey != *league.Owner {
+				if err := userAcls.Can(c, PermissionEdit, leagueKey); err != nil {
+					return err
+				}
+			}
+		}
+		league.TeamScoring = scoringType
+		_, err := datastore.Put(c, leagueKey, league)
+		return errwrap.Wrap(err)
+	}, nil)
+}
+
 func LeagueById(
 	c appengine.Context,
 	leagueId string) (*League, *datastore.Key, error) {
@@ -236,7 +304,7 @@ func LeagueAddTeam(
 		if err != nil {
 			return errwrap.Wrap(err)
 		}
-		return nil
+		return addTeamToCurrentSeason(c, league, teamKey)
 	}, nil)
 	if err != nil {
 		return nil, nil, errwrap.Wrap(err)
@@ -269,6 +337,7 @@ func LeagueAllTeams(
 	return teams, teamKeys, errwrap.Wrap(err)
 }
 
+// Returns the players on a team's roster for the league's current season.
 func TeamAllPlayers(
 	c appengine.Context,
 	userAcls *RequestorAclCache,
@@ -276,6 +345,34 @@ func TeamAllPlayers(
 	leagueKey *datastore.Key,
 	teamKey *datastore.Key,
 	keysOnly KeysOnlyOption) ([]*Player, []*datastore.Key, error) {
+	return teamPlayers(c, userAcls, league, leagueKey, teamKey, league.CurrentSeason, keysOnly)
+}
+
+// Returns the players on a team's roster for the season of scope, or for the league's
+// current season if scope is nil.
+func TeamSeasonPlayers(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	teamKey *datastore.Key,
+	scope *LeagueScope,
+	keysOnly KeysOnlyOption) ([]*Player, []*datastore.Key, error) {
+	seasonKey := league.CurrentSeason
+	if scope != nil {
+		seasonKey = scope.SeasonKey
+	}
+	return teamPlayers(c, userAcls, league, leagueKey, teamKey, seasonKey, keysOnly)
+}
+
+func teamPlayers(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	teamKey *datastore.Key,
+	seasonKey *datastore.Key,
+	keysOnly KeysOnlyOption) ([]*Player, []*datastore.Key, error) {
 
 	if userAcls != nil {
 		if *userAcls.UserKey != *league.Owner {
@@ -285,12 +382,9 @@ func TeamAllPlayers(
 		}
 	}
 
-	var memberships []TeamMembership
-	q := datastore.NewQuery("TeamMembership").Ancestor(leagueKey).
-		Filter("TeamKey =", teamKey)
-	_, err := q.GetAll(c, &memberships)
+	memberships, _, err := teamRoster(c, leagueKey, teamKey, seasonKey)
 	if err != nil {
-		return nil, nil, errwrap.Wrap(err)
+		return nil, nil, err
 	}
 
 	playerKeys := make([]*datastore.Key, len(memberships))
@@ -312,13 +406,22 @@ func TeamAllPlayers(
 	return players, playerKeys, nil
 }
 
+// Adds a player to a team's roster for the league's current season. Making the player
+// captain replaces the team's previous captain.
+//
+// Players that break the league's EligibilityRules are not added.
 func TeamAddPlayer(
 	c appengine.Context,
+	ctx context.Context,
 	userAcls *RequestorAclCache,
 	league *League,
 	leagueKey *datastore.Key,
 	teamKey *datastore.Key,
-	playerKey *datastore.Key) error {
+	playerKey *datastore.Key,
+	role RosterRole,
+	substitute bool,
+	captain bool,
+	reason string) error {
 
 	if userAcls != nil {
 		if *userAcls.UserKey != *league.Owner {
@@ -328,22 +431,60 @@ func TeamAddPlayer(
 		}
 	}
 
-	m := &TeamMembership{
-		TeamKey:   teamKey,
-		PlayerKey: playerKey,
+	player := new(Player)
+	if err := datastore.Get(c, playerKey, player); err != nil {
+		return errwrap.Wrap(err)
 	}
-	key := datastore.NewIncompleteKey(c, "TeamMembership", leagueKey)
-	_, err := datastore.Put(c, key, m)
-	return err
+	err := checkPlayerEligibility(c, ctx, league, leagueKey, teamKey, player, playerKey)
+	if err != nil {
+		return err
+	}
+
+	return datastore.RunInTransaction(c, func(c appengine.Context) error {
+		roster, rosterKeys, err := teamRoster(c, leagueKey, teamKey, league.CurrentSeason)
+		if err != nil {
+			return err
+		}
+		if findRosterPlayer(roster, playerKey) >= 0 {
+			return errors.New("The player is already on this team's roster")
+		}
+
+		now := time.Now()
+		m := &TeamMembership{
+			TeamKey:    teamKey,
+			PlayerKey:  playerKey,
+			Season:     league.CurrentSeason,
+			Role:       role,
+			Substitute: substitute,
+			Captain:    captain,
+			Joined:     now,
+		}
+		if err := league.RosterRules.checkAdd(roster, m, now); err != nil {
+			return err
+		}
+		if captain {
+			err := putCaptain(c, userAcls, leagueKey, roster, rosterKeys, -1, now)
+			if err != nil {
+				return err
+			}
+		}
+		key := datastore.NewIncompleteKey(c, "TeamMembership", leagueKey)
+		if _, err := datastore.Put(c, key, m); err != nil {
+			return errwrap.Wrap(err)
+		}
+		return putRosterTransaction(c, userAcls, leagueKey, m, RosterAdd, reason, now)
+	}, nil)
 }
 
+// Removes a player from a team's roster for the league's current season.
 func TeamDelPlayer(
 	c appengine.Context,
 	userAcls *RequestorAclCache,
 	league *League,
 	leagueKey *datastore.Key,
 	teamKey *datastore.Key,
-	playerKey *datastore.Key) error {
+	playerKey *datastore.Key,
+	reason string) error {
 
 	if userAcls != nil {
 		if *userAcls.UserKey != *league.Owner {
@@ -354,19 +495,26 @@ func TeamDelPlayer(
 	}
 
 	return datastore.RunInTransaction(c, func(c appengine.Context) error {
-		q := datastore.NewQuery("TeamMembership").Ancestor(leagueKey).
-			Filter("TeamKey =", teamKey).
-			Filter("PlayerKey =", playerKey).
-			Limit(1).
-			KeysOnly()
-		keys, err := q.GetAll(c, nil)
+		roster, rosterKeys, err := teamRoster(c, leagueKey, teamKey, league.CurrentSeason)
 		if err != nil {
 			return err
 		}
-		if len(keys) > 0 {
-			return datastore.Delete(c, keys[0])
+		i := findRosterPlayer(roster, playerKey)
+		if i < 0 {
+			return nil
+		}
+
+		now := time.Now()
+		if err := league.RosterRules.checkRemove(roster, now); err != nil {
+			return err
+		}
+		roster[i].Left = now
+		roster[i].Captain = false
+		if _, err := datastore.Put(c, rosterKeys[i], roster[i]); err != nil {
+			return errwrap.Wrap(err)
 		}
-		return nil
+		return putRosterTransaction(
+			c, userAcls, leagueKey, roster[i], RosterRemove, reason, now)
 	}, nil)
 }
 
@@ -0,0 +1,178 @@
+package model
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"appengine/memcache"
+	"fmt"
+	"github.com/OwenDurni/loltools/riot"
+	"github.com/OwenDurni/loltools/util/errwrap"
+	"golang.org/x/net/context"
+	"time"
+)
+
+// The number of a team's members that must be on the same side of a game for it to
+// count as a game of that team. This matches how GameByTeam is populated.
+const TeamGameMinPlayers = 3
+
+// How long the result of TeamCurrentGame is cached.
+const teamCurrentGameCacheTime = time.Minute
+
+// A game in progress with at least TeamGameMinPlayers members of a team on one side.
+type TeamLiveGame struct {
+	Game *riot.CurrentGameInfo
+
+	// The side (riot.BlueTeamId or riot.PurpleTeamId) the team's members are on.
+	RiotTeamId int
+
+	// Members of the team on RiotTeamId.
+	Players []*Player
+
+	// Every participant of the game in the order Riot lists them.
+	Picks []*LiveGamePick
+}
+
+type LiveGamePick struct {
+	SummonerId   int64
+	SummonerName string
+	ChampionId   int
+	RiotTeamId   int
+
+	// Set if the participant is a member of the team.
+	Player *Player
+}
+
+// Returns the bans of the game made by side riotTeamId in pick order.
+func (g *TeamLiveGame) bansFor(riotTeamId int) []*riot.CurrentGameBannedChampion {
+	var bans []*riot.CurrentGameBannedChampion
+	for _, b := range g.Game.BannedChampions {
+		if b.TeamId == riotTeamId {
+			bans = append(bans, b)
+		}
+	}
+	return bans
+}
+
+func (g *TeamLiveGame) TeamBans() []*riot.CurrentGameBannedChampion {
+	return g.bansFor(g.RiotTeamId)
+}
+
+func (g *TeamLiveGame) OpponentBans() []*riot.CurrentGameBannedChampion {
+	return g.bansFor(riot.BlueTeamId + riot.PurpleTeamId - g.RiotTeamId)
+}
+
+// Returns the picks on side riotTeamId.
+func (g *TeamLiveGame) picksFor(riotTeamId int) []*LiveGamePick {
+	var picks []*LiveGamePick
+	for _, p := range g.Picks {
+		if p.RiotTeamId == riotTeamId {
+			picks = append(picks, p)
+		}
+	}
+	return picks
+}
+
+func (g *TeamLiveGame) TeamPicks() []*LiveGamePick {
+	return g.picksFor(g.RiotTeamId)
+}
+
+func (g *TeamLiveGame) OpponentPicks() []*LiveGamePick {
+	return g.picksFor(riot.BlueTeamId + riot.PurpleTeamId - g.RiotTeamId)
+}
+
+// Returns a TeamLiveGame if at least TeamGameMinPlayers of players are on the same side
+// of game, or nil otherwise.
+func findTeamLiveGame(game *riot.CurrentGameInfo, players []*Player) *TeamLiveGame {
+	playersById := make(map[int64]*Player)
+	for _, p := range players {
+		playersById[p.RiotId] = p
+	}
+
+	membersBySide := make(map[int][]*Player)
+	for _, participant := range game.Participants {
+		if p, ok := playersById[participant.SummonerId]; ok {
+			membersBySide[participant.TeamId] = append(
+				membersBySide[participant.TeamId], p)
+		}
+	}
+
+	for _, side := range []int{riot.BlueTeamId, riot.PurpleTeamId} {
+		if len(membersBySide[side]) < TeamGameMinPlayers {
+			continue
+		}
+		liveGame := &TeamLiveGame{
+			Game:       game,
+			RiotTeamId: side,
+			Players:    membersBySide[side],
+		}
+		for _, participant := range game.Participants {
+			pick := &LiveGamePick{
+				SummonerId:   participant.SummonerId,
+				SummonerName: participant.SummonerName,
+				ChampionId:   participant.ChampionId,
+				RiotTeamId:   participant.TeamId,
+			}
+			if participant.TeamId == side {
+				pick.Player = playersById[participant.SummonerId]
+			}
+			liveGame.Picks = append(liveGame.Picks, pick)
+		}
+		return liveGame
+	}
+	return nil
+}
+
+// Returns the game a team is playing right now, or nil if fewer than
+// TeamGameMinPlayers of the team's members are in the same game. Results are cached for
+// a minute.
+func TeamCurrentGame(
+	c appengine.Context,
+	ctx context.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	teamKey *datastore.Key) (*TeamLiveGame, error) {
+	players, _, err := TeamAllPlayers(c, userAcls, league, leagueKey, teamKey, KeysAndEntities)
+	if err != nil {
+		return nil, err
+	}
+
+	// A nil Game is cached too so that idle teams are not checked on every view.
+	cached := new(struct{ Game *TeamLiveGame })
+	mkey := fmt.Sprintf("TeamCurrentGame/%s", teamKey.Encode())
+	if _, err := memcache.JSON.Get(c, mkey, cached); err == nil {
+		return cached.Game, nil
+	}
+
+	client := RiotClient(c, league.Region)
+	checked := make(map[int64]bool)
+	for _, p := range players {
+		if checked[p.RiotId] {
+			continue
+		}
+		checked[p.RiotId] = true
+
+		game, err := client.CurrentGameBySummonerId(ctx, p.RiotId)
+		if err != nil {
+			return nil, errwrap.Wrap(err)
+		}
+		if game == nil {
+			continue
+		}
+		// Anyone else in this game does not need to be checked separately.
+		for _, participant := range game.Participants {
+			checked[participant.SummonerId] = true
+		}
+		if cached.Game = findTeamLiveGame(game, players); cached.Game != nil {
+			break
+		}
+	}
+
+	// Best effort put into memcache.
+	memcache.JSON.Set(c, &memcache.Item{
+		Key:        mkey,
+		Object:     cached,
+		Expiration: teamCurrentGameCacheTime,
+	})
+	return cached.Game, nil
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"github.com/OwenDurni/loltools/util/errwrap"
+	"golang.org/x/net/context"
+	"time"
+)
+
+// The number of champions kept in PlayerTopChampions.
+const TopChampionsCount = 3
+
+// How long PlayerTopChampions is used before it is fetched from Riot again.
+const TopChampionsMaxAge = 24 * time.Hour
+
+// A player's highest mastery champions, cached so that team pages do not call Riot on
+// every view.
+//
+// ("%s-%s", Region, RiotId) of the player is the key.
+type PlayerTopChampions struct {
+	PlayerKey *datastore.Key
+
+	// Sum of the player's champion mastery levels.
+	Score int
+
+	// Highest mastery first.
+	Champions []ChampionMastery
+
+	LastUpdated time.Time
+}
+
+type ChampionMastery struct {
+	ChampionId   int
+	Level        int
+	Points       int
+	LastPlayTime time.Time
+}
+
+func KeyForPlayerTopChampions(c appengine.Context, player *Player) *datastore.Key {
+	return datastore.NewKey(c, "PlayerTopChampions", player.Id(), 0, nil)
+}
+
+// Returns the cached top champions for player, refreshing them from Riot if they are
+// missing or older than TopChampionsMaxAge. If the refresh fails a stale copy is
+// returned along with the error when there is one.
+func GetPlayerTopChampions(
+	c appengine.Context, ctx context.Context, player *Player) (*PlayerTopChampions, error) {
+	key := KeyForPlayerTopChampions(c, player)
+	cached := new(PlayerTopChampions)
+	err := datastore.Get(c, key, cached)
+	if err == datastore.ErrNoSuchEntity {
+		cached = nil
+	} else if err != nil {
+		return nil, errwrap.Wrap(err)
+	} else if time.Since(cached.LastUpdated) < TopChampionsMaxAge {
+		return cached, nil
+	}
+
+	fresh, err := fetchPlayerTopChampions(c, ctx, player)
+	if err != nil {
+		return cached, errwrap.Wrap(err)
+	}
+	fresh.PlayerKey = KeyForPlayer(c, player.Region, player.RiotId)
+	if _, err := datastore.Put(c, key, fresh); err != nil {
+		return fresh, errwrap.Wrap(err)
+	}
+	return fresh, nil
+}
+
+func fetchPlayerTopChampions(
+	c appengine.Context, ctx context.Context, player *Player) (*PlayerTopChampions, error) {
+	client := RiotClient(c, player.Region)
+	dtos, err := client.TopChampionMasteries(ctx, player.RiotId, TopChampionsCount)
+	if err != nil {
+		return nil, err
+	}
+	score, err := client.ChampionMasteryScore(ctx, player.RiotId)
+	if err != nil {
+		return nil, err
+	}
+
+	top := new(PlayerTopChampions)
+	top.Score = score
+	top.LastUpdated = time.Now()
+	for _, dto := range dtos {
+		top.Champions = append(top.Champions, ChampionMastery{
+			ChampionId:   dto.ChampionId,
+			Level:        dto.ChampionLevel,
+			Points:       dto.ChampionPoints,
+			LastPlayTime: time.Time(dto.LastPlayTime),
+		})
+	}
+	return top, nil
+}
@@ -5,6 +5,7 @@ import (
 	"appengine/datastore"
 	"errors"
 	"fmt"
+	"github.com/OwenDurni/loltools/util/errwrap"
 	"time"
 )
 
@@ -26,9 +27,17 @@ type ScheduledMatch struct {
 	// The teams involved in the match.
 	TeamKeys []*datastore.Key
 
+	// The season the match is part of. Nil before the league's first season.
+	Season *datastore.Key
+
 	// The number of games in the match. Zero or less means "no limit".
 	NumGames int
 
+	// The map and game mode the games are played on, as reported by riot.
+	// Example: 11 and "CLASSIC". Zero and "" match any map or mode.
+	MapId    int
+	GameMode string
+
 	// The official suggested date(time) of the match.
 	OfficialDatetime time.Time
 
@@ -37,6 +46,10 @@ type ScheduledMatch struct {
 
 	// The latest the match should be played. This is not enforced.
 	DateLatest time.Time
+
+	// If set, TeamScoring is used for this match instead of the league's.
+	OverrideTeamScoring bool
+	TeamScoring         TeamScoringType
 }
 
 func (m *ScheduledMatch) HomeTeam() *datastore.Key {
@@ -45,15 +58,57 @@ func (m *ScheduledMatch) HomeTeam() *datastore.Key {
 func (m *ScheduledMatch) AwayTeam() *datastore.Key {
 	return m.TeamKeys[1]
 }
+
+// Returns how teams earn points for this match in league.
+func (m *ScheduledMatch) TeamScoringType(league *League) TeamScoringType {
+	if m.OverrideTeamScoring {
+		return m.TeamScoring
+	}
+	return league.TeamScoring
+}
+
+// Returns whether teamKey is one of the teams in the match.
+func (m *ScheduledMatch) HasTeam(teamKey *datastore.Key) bool {
+	for _, k := range m.TeamKeys {
+		if k.Equal(teamKey) {
+			return true
+		}
+	}
+	return false
+}
 func MatchId(matchKey *datastore.Key) string {
 	return EncodeKeyShort(matchKey)
 }
+func LeagueMatchUri(leagueKey *datastore.Key, matchKey *datastore.Key) string {
+	return fmt.Sprintf("%s/matches/%s", LeagueUri(leagueKey), MatchId(matchKey))
+}
 
+// The result of a ScheduledMatch for one of its teams.
+//
+// The key is KeyForMatchResult.
+//
+// Ancestor: League
 type MatchResult struct {
 	ScheduledMatch *datastore.Key
 	Team           *datastore.Key
 	Points         int
 	ManualResult   bool
+
+	// True if the result will not change if additional games are played.
+	IsFinal bool
+
+	// The games of the match won and lost by Team.
+	GamesWon  int
+	GamesLost int
+
+	Updated time.Time
+}
+
+func KeyForMatchResult(
+	c appengine.Context, matchKey *datastore.Key, teamKey *datastore.Key) *datastore.Key {
+	return datastore.NewKey(
+		c, "MatchResult", fmt.Sprintf("%s/%s", MatchId(matchKey), EncodeKeyShort(teamKey)), 0,
+		matchKey.Parent())
 }
 
 // Creates a scheduled match.
@@ -73,6 +128,10 @@ func CreateScheduledMatch(
 		}
 	}
 
+	if match.Season == nil {
+		match.Season = league.CurrentSeason
+	}
+
 	// Ensure specified teams are in this league.
 	for _, teamKey := range match.TeamKeys {
 		if *teamKey.Parent() != *leagueKey {
@@ -85,3 +144,185 @@ func CreateScheduledMatch(
 	c.Debugf("model.CreateScheduledMatch end")
 	return err
 }
+
+func ScheduledMatchById(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	matchId string) (*ScheduledMatch, *datastore.Key, error) {
+	matchKey, err := DecodeKeyShort(c, "ScheduledMatch", matchId, leagueKey)
+	if err != nil {
+		return nil, nil, errwrap.Wrap(err)
+	}
+	if err := canViewLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, nil, err
+	}
+
+	match := new(ScheduledMatch)
+	if err := datastore.Get(c, matchKey, match); err != nil {
+		return nil, matchKey, errwrap.Wrap(err)
+	}
+	return match, matchKey, nil
+}
+
+// Returns the result of a match for each of its teams, in the order of match.TeamKeys.
+// An entry is nil if the team has no result yet.
+func GetMatchResults(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	match *ScheduledMatch,
+	matchKey *datastore.Key) ([]*MatchResult, error) {
+	if err := canViewLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, err
+	}
+
+	keys := make([]*datastore.Key, len(match.TeamKeys))
+	results := make([]*MatchResult, len(match.TeamKeys))
+	for i, teamKey := range match.TeamKeys {
+		keys[i] = KeyForMatchResult(c, matchKey, teamKey)
+		results[i] = new(MatchResult)
+	}
+	err := datastore.GetMulti(c, keys, results)
+	if me, ok := err.(appengine.MultiError); ok {
+		for i, merr := range me {
+			if merr == datastore.ErrNoSuchEntity {
+				results[i] = nil
+			} else if merr != nil {
+				return nil, errwrap.Wrap(merr)
+			}
+		}
+	} else if err != nil {
+		return nil, errwrap.Wrap(err)
+	}
+	return results, nil
+}
+
+// Returns every scheduled match in a league within scope.
+func LeagueScheduledMatches(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	scope *LeagueScope) ([]*ScheduledMatch, []*datastore.Key, error) {
+	if userAcls != nil {
+		if *userAcls.UserKey != *league.Owner {
+			if err := userAcls.Can(c, PermissionView, leagueKey); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	var matches []*ScheduledMatch
+	q := datastore.NewQuery("ScheduledMatch").Ancestor(leagueKey)
+	if scope != nil {
+		q = q.Filter("Season =", scope.SeasonKey)
+	}
+	matchKeys, err := q.GetAll(c, &matches)
+	if err != nil {
+		return nil, nil, err
+	}
+	if scope == nil || scope.DivisionKey == nil {
+		return matches, matchKeys, nil
+	}
+
+	var scopeMatches []*ScheduledMatch
+	var scopeMatchKeys []*datastore.Key
+	for i, m := range matches {
+		if scope.HasMatch(m) {
+			scopeMatches = append(scopeMatches, m)
+			scopeMatchKeys = append(scopeMatchKeys, matchKeys[i])
+		}
+	}
+	return scopeMatches, scopeMatchKeys, nil
+}
+
+// Orders matches and their keys by OfficialDatetime.
+type ScheduledMatchesByTime struct {
+	Matches []*ScheduledMatch
+	Keys    []*datastore.Key
+}
+
+func (a ScheduledMatchesByTime) Len() int { return len(a.Matches) }
+func (a ScheduledMatchesByTime) Swap(i, j int) {
+	a.Matches[i], a.Matches[j] = a.Matches[j], a.Matches[i]
+	a.Keys[i], a.Keys[j] = a.Keys[j], a.Keys[i]
+}
+func (a ScheduledMatchesByTime) Less(i, j int) bool {
+	return a.Matches[i].OfficialDatetime.Before(a.Matches[j].OfficialDatetime)
+}
+
+// Creates or replaces the result of a match for a team. An automatically computed
+// result never replaces a manual one.
+func SaveMatchResult(c appengine.Context, result *MatchResult) error {
+	return datastore.RunInTransaction(c, func(c appengine.Context) error {
+		return putMatchResult(c, result)
+	}, nil)
+}
+
+// Implements SaveMatchResult within a transaction.
+func putMatchResult(c appengine.Context, result *MatchResult) error {
+	key := KeyForMatchResult(c, result.ScheduledMatch, result.Team)
+	stored := new(MatchResult)
+	err := datastore.Get(c, key, stored)
+	if err == nil && stored.ManualResult && !result.ManualResult {
+		return nil
+	} else if err != nil && err != datastore.ErrNoSuchEntity {
+		return errwrap.Wrap(err)
+	}
+	result.Updated = time.Now()
+	_, err = datastore.Put(c, key, result)
+	return errwrap.Wrap(err)
+}
+
+// Returns every match result in a league within scope.
+func LeagueMatchResults(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	scope *LeagueScope) ([]*MatchResult, error) {
+	if userAcls != nil {
+		if *userAcls.UserKey != *league.Owner {
+			if err := userAcls.Can(c, PermissionView, leagueKey); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var results []*MatchResult
+	q := datastore.NewQuery("MatchResult").Ancestor(leagueKey)
+	if _, err := q.GetAll(c, &results); err != nil {
+		return nil, errwrap.Wrap(err)
+	}
+	if scope == nil {
+		return results, nil
+	}
+
+	_, matchKeys, err := LeagueScheduledMatches(c, userAcls, league, leagueKey, scope)
+	if err != nil {
+		return nil, err
+	}
+	matchIds := make(map[string]bool)
+	for _, k := range matchKeys {
+		matchIds[k.Encode()] = true
+	}
+	var scopeResults []*MatchResult
+	for _, r := range results {
+		if matchIds[r.ScheduledMatch.Encode()] {
+			scopeResults = append(scopeResults, r)
+		}
+	}
+	return scopeResults, nil
+}
+
+// Advances the bracket or Swiss tournament the match belongs to, if any, once its results
+// change.
+func AdvanceForMatch(c appengine.Context, matchKey *datastore.Key) error {
+	if err := AdvanceBracketForMatch(c, matchKey); err != nil {
+		return err
+	}
+	return AdvanceSwissForMatch(c, matchKey)
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"appengine/datastore"
+	"testing"
+)
+
+// Encoded keys of players and of entities in league 1 of app dev~loltools. Tests decode
+// them separately for each use, as keys loaded from different entities are never the
+// same *Key.
+const (
+	testTeamKey1   = "agxkZXZ-bG9sdG9vbHNyFgsSBkxlYWd1ZRgBDAsSBFRlYW0YAgw"
+	testTeamKey2   = "agxkZXZ-bG9sdG9vbHNyFgsSBkxlYWd1ZRgBDAsSBFRlYW0YAww"
+	testSeasonKey1 = "agxkZXZ-bG9sdG9vbHNyGAsSBkxlYWd1ZRgBDAsSBlNlYXNvbhgEDA"
+	testSeasonKey2 = "agxkZXZ-bG9sdG9vbHNyGAsSBkxlYWd1ZRgBDAsSBlNlYXNvbhgFDA"
+	testPlayerKey1 = "agxkZXZ-bG9sdG9vbHNyDAsSBlBsYXllchgHDA"
+	testPlayerKey2 = "agxkZXZ-bG9sdG9vbHNyDAsSBlBsYXllchgIDA"
+	testPlayerKey3 = "agxkZXZ-bG9sdG9vbHNyDAsSBlBsYXllchgJDA"
+)
+
+func decodeTestKey(t *testing.T, encoded string) *datastore.Key {
+	k, err := datastore.DecodeKey(encoded)
+	if err != nil {
+		t.Fatalf("DecodeKey(%q): %v", encoded, err)
+	}
+	return k
+}
+
+func TestScheduledMatchHasTeam(t *testing.T) {
+	match := &ScheduledMatch{
+		TeamKeys: []*datastore.Key{
+			decodeTestKey(t, testTeamKey1), decodeTestKey(t, testTeamKey2)},
+	}
+	if !match.HasTeam(decodeTestKey(t, testTeamKey1)) {
+		t.Errorf("HasTeam(home): got false")
+	}
+	if !match.HasTeam(decodeTestKey(t, testTeamKey2)) {
+		t.Errorf("HasTeam(away): got false")
+	}
+	if match.HasTeam(decodeTestKey(t, testSeasonKey1)) {
+		t.Errorf("HasTeam(season): got true")
+	}
+}
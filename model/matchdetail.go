@@ -0,0 +1,197 @@
+package model
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/OwenDurni/loltools/riot"
+	"github.com/OwenDurni/loltools/util/errwrap"
+	"golang.org/x/net/context"
+	"time"
+)
+
+// The schema of GameMatchDetail.Data written by SaveGameMatchDetail.
+//
+// Version 1: the gzipped JSON encoding of a riot.MatchDetail (match-v2.2) including
+// the timeline.
+//
+// Bump this, and teach decodeGameMatchDetail to read the previous version, whenever
+// the encoding changes. Archived details are never refetched just because the schema
+// changed since Riot may no longer have them.
+const MatchDetailSchemaVersion = 1
+
+// The full details of a Game as returned by riot.Client.LookupMatch, archived so that
+// league games can be re-analyzed after Riot stops serving them.
+//
+// The id of the Game is the key.
+type GameMatchDetail struct {
+	GameKey *datastore.Key
+
+	// The MatchDetailSchemaVersion Data was written with.
+	SchemaVersion int
+
+	// Set if Riot had no details for the game. Data is empty.
+	NotAvailable bool
+
+	Data    []byte    `datastore:",noindex"`
+	Fetched time.Time `datastore:",noindex"`
+}
+
+func KeyForGameMatchDetail(c appengine.Context, gameKey *datastore.Key) *datastore.Key {
+	return datastore.NewKey(c, "GameMatchDetail", gameKey.StringID(), 0, nil)
+}
+
+// Returns the archived details of a game, or nil if they have not been archived yet
+// or Riot had none.
+func GetGameMatchDetail(
+	c appengine.Context, gameKey *datastore.Key) (*riot.MatchDetail, error) {
+	stored := new(GameMatchDetail)
+	err := datastore.Get(c, KeyForGameMatchDetail(c, gameKey), stored)
+	if err == datastore.ErrNoSuchEntity {
+		return nil, nil
+	} else if err != nil {
+		return nil, errwrap.Wrap(err)
+	}
+	if stored.NotAvailable {
+		return nil, nil
+	}
+	match, err := decodeGameMatchDetail(stored)
+	return match, errwrap.Wrap(err)
+}
+
+func decodeGameMatchDetail(stored *GameMatchDetail) (*riot.MatchDetail, error) {
+	switch stored.SchemaVersion {
+	case 1:
+		data, err := gunzip(stored.Data)
+		if err != nil {
+			return nil, err
+		}
+		match := new(riot.MatchDetail)
+		if err := json.Unmarshal(data, match); err != nil {
+			return nil, err
+		}
+		return match, nil
+	default:
+		return nil, errors.New(fmt.Sprintf(
+			"GameMatchDetail for %s has unknown schema version %d",
+			stored.GameKey.StringID(), stored.SchemaVersion))
+	}
+}
+
+// Archives match as the details of a game. A nil match records that Riot has none.
+func SaveGameMatchDetail(
+	c appengine.Context, gameKey *datastore.Key, match *riot.MatchDetail) error {
+	stored := &GameMatchDetail{
+		GameKey:       gameKey,
+		SchemaVersion: MatchDetailSchemaVersion,
+		NotAvailable:  match == nil,
+		Fetched:       time.Now(),
+	}
+	if match != nil {
+		data, err := json.Marshal(match)
+		if err != nil {
+			return errwrap.Wrap(err)
+		}
+		if stored.Data, err = gzipBytes(data); err != nil {
+			return errwrap.Wrap(err)
+		}
+	}
+	_, err := datastore.Put(c, KeyForGameMatchDetail(c, gameKey), stored)
+	return errwrap.Wrap(err)
+}
+
+// Returns the archived details of a game, looking them up from Riot and archiving them
+// first if needed. Returns nil if Riot has no details for the game.
+func GetOrFetchGameMatchDetail(
+	c appengine.Context,
+	ctx context.Context,
+	game *Game,
+	gameKey *datastore.Key) (*riot.MatchDetail, error) {
+	stored := new(GameMatchDetail)
+	err := datastore.Get(c, KeyForGameMatchDetail(c, gameKey), stored)
+	if err == nil {
+		if stored.NotAvailable {
+			return nil, nil
+		}
+		match, err := decodeGameMatchDetail(stored)
+		return match, errwrap.Wrap(err)
+	} else if err != datastore.ErrNoSuchEntity {
+		return nil, errwrap.Wrap(err)
+	}
+
+	match, err := RiotClient(c, game.Region).LookupMatch(ctx, game.RiotId)
+	if riot.IsNotFound(err) {
+		match = nil
+	} else if err != nil {
+		// Unwrapped so that tasks can retry rate limits and Riot outages.
+		return nil, err
+	}
+	if err := SaveGameMatchDetail(c, gameKey, match); err != nil {
+		return match, err
+	}
+	return match, nil
+}
+
+// Returns whether the details of the game with gameKey have been archived (or are
+// known to be unavailable), for each of gameKeys.
+func HasGameMatchDetails(
+	c appengine.Context, gameKeys []*datastore.Key) ([]bool, error) {
+	keys := make([]*datastore.Key, len(gameKeys))
+	stored := make([]*GameMatchDetail, len(gameKeys))
+	for i, gameKey := range gameKeys {
+		keys[i] = KeyForGameMatchDetail(c, gameKey)
+		stored[i] = new(GameMatchDetail)
+	}
+	has := make([]bool, len(gameKeys))
+	err := datastore.GetMulti(c, keys, stored)
+	if me, ok := err.(appengine.MultiError); ok {
+		for i, merr := range me {
+			if merr == nil {
+				has[i] = true
+			} else if merr != datastore.ErrNoSuchEntity {
+				return nil, errwrap.Wrap(merr)
+			}
+		}
+		return has, nil
+	} else if err != nil {
+		return nil, errwrap.Wrap(err)
+	}
+	for i := range has {
+		has[i] = true
+	}
+	return has, nil
+}
+
+// Returns the archived details of each of gameKeys. An entry is nil if the details of
+// that game have not been archived yet or Riot had none.
+func GetGameMatchDetails(
+	c appengine.Context, gameKeys []*datastore.Key) ([]*riot.MatchDetail, error) {
+	keys := make([]*datastore.Key, len(gameKeys))
+	stored := make([]*GameMatchDetail, len(gameKeys))
+	for i, gameKey := range gameKeys {
+		keys[i] = KeyForGameMatchDetail(c, gameKey)
+		stored[i] = new(GameMatchDetail)
+	}
+	err := datastore.GetMulti(c, keys, stored)
+	me, _ := err.(appengine.MultiError)
+	if err != nil && me == nil {
+		return nil, errwrap.Wrap(err)
+	}
+	matches := make([]*riot.MatchDetail, len(gameKeys))
+	for i := range stored {
+		if me != nil && me[i] == datastore.ErrNoSuchEntity {
+			continue
+		} else if me != nil && me[i] != nil {
+			return nil, errwrap.Wrap(me[i])
+		}
+		if stored[i].NotAvailable {
+			continue
+		}
+		if matches[i], err = decodeGameMatchDetail(stored[i]); err != nil {
+			return nil, errwrap.Wrap(err)
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"appengine/datastore"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type MatchGameVerdict int
+
+const (
+	// The game is counted as part of the match.
+	MatchGameAccepted MatchGameVerdict = iota
+	// The game may be part of the match and should be reviewed by a league editor.
+	MatchGameNeedsReview
+	// The game is not part of the match.
+	MatchGameRejected
+)
+
+func (v MatchGameVerdict) String() string {
+	switch v {
+	case MatchGameAccepted:
+		return "accepted"
+	case MatchGameNeedsReview:
+		return "needs review"
+	case MatchGameRejected:
+		return "rejected"
+	}
+	return fmt.Sprintf("MatchGameVerdict(%d)", int(v))
+}
+
+// The weight of each check towards the confidence that a game is part of a match. A
+// game is only accepted if every check passes.
+const (
+	matchGameOppositeSidesWeight = 40
+	matchGameCustomWeight        = 40
+	matchGameMapWeight           = 10
+	matchGameModeWeight          = 10
+)
+
+// Whether a game played by both teams of a match is part of the match. Not directly
+// stored in datastore.
+type MatchGameDetection struct {
+	GameKey *datastore.Key
+	Verdict MatchGameVerdict
+
+	// From 0 to 100.
+	Confidence int
+
+	// Human readable explanations of the checks, passed or not.
+	Reasons []string
+
+	game *Game
+}
+
+func (d *MatchGameDetection) Reason() string {
+	return strings.Join(d.Reasons, "; ")
+}
+
+// Riot reports Summoner's Rift as map 1 or map 11 depending on when the game was played.
+func sameMap(a int, b int) bool {
+	isSummonersRift := func(id int) bool { return id == 1 || id == 11 }
+	return a == b || (isSummonersRift(a) && isSummonersRift(b))
+}
+
+// Checks whether game, with the home and away teams' GameByTeam rows, is part of match.
+func DetectMatchGame(
+	match *ScheduledMatch,
+	gameKey *datastore.Key,
+	game *Game,
+	home *GameByTeam,
+	away *GameByTeam) *MatchGameDetection {
+	d := &MatchGameDetection{GameKey: gameKey, game: game}
+
+	// Teams with members on both sides, or on the same side, played with each other.
+	if len(home.RiotTeamIds) != 1 || len(away.RiotTeamIds) != 1 ||
+		home.RiotTeamIds[0] == away.RiotTeamIds[0] {
+		d.Verdict = MatchGameRejected
+		d.Reasons = append(d.Reasons, "teams were not on opposite sides")
+		return d
+	}
+	d.Confidence += matchGameOppositeSidesWeight
+	d.Reasons = append(d.Reasons, "teams were on opposite sides")
+
+	if !game.HasRiotData {
+		d.Verdict = MatchGameNeedsReview
+		d.Reasons = append(d.Reasons, "game details are not available yet")
+		return d
+	}
+
+	passed := true
+	if game.GameType == "CUSTOM_GAME" {
+		d.Confidence += matchGameCustomWeight
+		d.Reasons = append(d.Reasons, "custom game")
+	} else {
+		passed = false
+		d.Reasons = append(d.Reasons, fmt.Sprintf("not a custom game (%s)", game.GameType))
+	}
+
+	if match.MapId <= 0 || sameMap(match.MapId, game.MapId) {
+		d.Confidence += matchGameMapWeight
+	} else {
+		passed = false
+		d.Reasons = append(d.Reasons, fmt.Sprintf(
+			"played on map %d instead of map %d", game.MapId, match.MapId))
+	}
+
+	if match.GameMode == "" || match.GameMode == game.GameMode {
+		d.Confidence += matchGameModeWeight
+	} else {
+		passed = false
+		d.Reasons = append(d.Reasons, fmt.Sprintf(
+			"played in mode %s instead of %s", game.GameMode, match.GameMode))
+	}
+
+	if passed {
+		d.Verdict = MatchGameAccepted
+	} else {
+		d.Verdict = MatchGameNeedsReview
+	}
+	return d
+}
+
+type matchGameDetectionsByTime []*MatchGameDetection
+
+func (a matchGameDetectionsByTime) Len() int      { return len(a) }
+func (a matchGameDetectionsByTime) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a matchGameDetectionsByTime) Less(i, j int) bool {
+	return a[i].game.StartDateTime.Before(a[j].game.StartDateTime)
+}
+
+// Checks each game played by both teams of match. home and away are the GameByTeam rows
+// of the home and away teams for each game.
+//
+// At most match.NumGames games are accepted, earliest first. Later games that would
+// otherwise be accepted need review instead.
+func DetectMatchGames(
+	match *ScheduledMatch,
+	gameKeys []*datastore.Key,
+	games []*Game,
+	home []*GameByTeam,
+	away []*GameByTeam) []*MatchGameDetection {
+	detections := make([]*MatchGameDetection, len(games))
+	for i := range games {
+		detections[i] = DetectMatchGame(match, gameKeys[i], games[i], home[i], away[i])
+	}
+	sort.Stable(matchGameDetectionsByTime(detections))
+
+	if match.NumGames > 0 {
+		accepted := 0
+		for _, d := range detections {
+			if d.Verdict != MatchGameAccepted {
+				continue
+			}
+			accepted++
+			if accepted > match.NumGames {
+				d.Verdict = MatchGameNeedsReview
+				d.Confidence /= 2
+				d.Reasons = append(d.Reasons, fmt.Sprintf(
+					"played after the %d game(s) of the match", match.NumGames))
+			}
+		}
+	}
+	return detections
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"appengine/datastore"
+	"github.com/OwenDurni/loltools/riot"
+	"testing"
+	"time"
+)
+
+func detectionGame(gameType string, mapId int, gameMode string, minute int) *Game {
+	return &Game{
+		HasRiotData:   true,
+		GameType:      gameType,
+		MapId:         mapId,
+		GameMode:      gameMode,
+		StartDateTime: time.Date(2015, 1, 1, 19, minute, 0, 0, time.UTC),
+	}
+}
+
+func sides(riotTeamIds ...int) *GameByTeam {
+	return &GameByTeam{RiotTeamIds: riotTeamIds}
+}
+
+func TestDetectMatchGame(t *testing.T) {
+	match := &ScheduledMatch{MapId: 11, GameMode: "CLASSIC"}
+	tests := []struct {
+		game       *Game
+		home, away *GameByTeam
+		verdict    MatchGameVerdict
+		confidence int
+	}{
+		{detectionGame("CUSTOM_GAME", 11, "CLASSIC", 0),
+			sides(riot.BlueTeamId), sides(riot.PurpleTeamId), MatchGameAccepted, 100},
+		// Summoner's Rift was map 1 before it was updated.
+		{detectionGame("CUSTOM_GAME", 1, "CLASSIC", 0),
+			sides(riot.PurpleTeamId), sides(riot.BlueTeamId), MatchGameAccepted, 100},
+		{detectionGame("MATCHED_GAME", 11, "CLASSIC", 0),
+			sides(riot.BlueTeamId), sides(riot.PurpleTeamId), MatchGameNeedsReview, 60},
+		{detectionGame("CUSTOM_GAME", 12, "ARAM", 0),
+			sides(riot.BlueTeamId), sides(riot.PurpleTeamId), MatchGameNeedsReview, 80},
+		{&Game{},
+			sides(riot.BlueTeamId), sides(riot.PurpleTeamId), MatchGameNeedsReview, 40},
+		{detectionGame("CUSTOM_GAME", 11, "CLASSIC", 0),
+			sides(riot.BlueTeamId), sides(riot.BlueTeamId), MatchGameRejected, 0},
+		{detectionGame("CUSTOM_GAME", 11, "CLASSIC", 0),
+			sides(riot.BlueTeamId, riot.PurpleTeamId), sides(riot.PurpleTeamId),
+			MatchGameRejected, 0},
+	}
+	for i, test := range tests {
+		d := DetectMatchGame(match, nil, test.game, test.home, test.away)
+		if d.Verdict != test.verdict || d.Confidence != test.confidence {
+			t.Errorf("%d: got %v (%d), want %v (%d): %s",
+				i, d.Verdict, d.Confidence, test.verdict, test.confidence, d.Reason())
+		}
+		if d.Reason() == "" {
+			t.Errorf("%d: no reason", i)
+		}
+	}
+
+	// Any map and mode.
+	d := DetectMatchGame(&ScheduledMatch{}, nil, detectionGame("CUSTOM_GAME", 12, "ARAM", 0),
+		sides(riot.BlueTeamId), sides(riot.PurpleTeamId))
+	if d.Verdict != MatchGameAccepted {
+		t.Errorf("any map and mode: got %v: %s", d.Verdict, d.Reason())
+	}
+}
+
+func TestDetectMatchGamesNumGames(t *testing.T) {
+	match := &ScheduledMatch{NumGames: 2}
+	games := []*Game{
+		detectionGame("CUSTOM_GAME", 11, "CLASSIC", 50),
+		detectionGame("MATCHED_GAME", 11, "CLASSIC", 0),
+		detectionGame("CUSTOM_GAME", 11, "CLASSIC", 30),
+		detectionGame("CUSTOM_GAME", 11, "CLASSIC", 10),
+	}
+	home := make([]*GameByTeam, len(games))
+	away := make([]*GameByTeam, len(games))
+	for i := range games {
+		home[i] = sides(riot.BlueTeamId)
+		away[i] = sides(riot.PurpleTeamId)
+	}
+
+	detections := DetectMatchGames(match, make([]*datastore.Key, len(games)), games, home, away)
+	want := []struct {
+		minute     int
+		verdict    MatchGameVerdict
+		confidence int
+	}{
+		{0, MatchGameNeedsReview, 60},
+		{10, MatchGameAccepted, 100},
+		{30, MatchGameAccepted, 100},
+		{50, MatchGameNeedsReview, 50},
+	}
+	for i, w := range want {
+		d := detections[i]
+		if d.game.StartDateTime.Minute() != w.minute ||
+			d.Verdict != w.verdict || d.Confidence != w.confidence {
+			t.Errorf("%d: got %d %v (%d), want %d %v (%d)", i, d.game.StartDateTime.Minute(),
+				d.Verdict, d.Confidence, w.minute, w.verdict, w.confidence)
+		}
+	}
+}
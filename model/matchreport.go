@@ -0,0 +1,459 @@
+package model
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"errors"
+	"fmt"
+	"github.com/OwenDurni/loltools/util/errwrap"
+	"time"
+)
+
+type MatchReportState int
+
+const (
+	// Reported by one team and waiting on the other.
+	MatchReportPending MatchReportState = iota
+	// Confirmed by the opposing team. The result is final.
+	MatchReportConfirmed
+	// Disputed by the opposing team and waiting on a league editor.
+	MatchReportDisputed
+	// Accepted by a league editor, possibly with a corrected score. The result is final.
+	MatchReportResolved
+	// Rejected by a league editor. Automatically detected results stand and either team
+	// may report again.
+	MatchReportRejected
+)
+
+func (s MatchReportState) String() string {
+	switch s {
+	case MatchReportPending:
+		return "pending"
+	case MatchReportConfirmed:
+		return "confirmed"
+	case MatchReportDisputed:
+		return "disputed"
+	case MatchReportResolved:
+		return "resolved"
+	case MatchReportRejected:
+		return "rejected"
+	}
+	return fmt.Sprintf("MatchReportState(%d)", int(s))
+}
+
+// A result of a ScheduledMatch reported by one of its teams. Once confirmed by the
+// opposing team, or accepted by a league editor, it is saved as the MatchResult of
+// both teams and replaces any automatically detected result.
+//
+// The id of the ScheduledMatch is the key. A match has at most one report.
+//
+// Ancestor: League
+type MatchReport struct {
+	ScheduledMatch *datastore.Key
+	ReportingTeam  *datastore.Key
+
+	// The user who reported the result. Nil for reports made by tasks.
+	ReportedBy *datastore.Key
+
+	// The games won by each team, in the order of ScheduledMatch.TeamKeys.
+	GamesWon []int
+
+	// Games of the match, if the reporter listed them. They are tagged as the games of the
+	// match once the result is final.
+	GameKeys []*datastore.Key
+
+	State   MatchReportState
+	Created time.Time
+	Updated time.Time
+}
+
+// A state change of a MatchReport. Every change is recorded.
+//
+// Ancestor: League
+type MatchReportEvent struct {
+	ScheduledMatch *datastore.Key
+
+	// The user who made the change. Nil for changes made by tasks.
+	User     *datastore.Key
+	State    MatchReportState
+	GamesWon []int
+	Comment  string `datastore:",noindex"`
+	Time     time.Time
+}
+
+func KeyForMatchReport(c appengine.Context, matchKey *datastore.Key) *datastore.Key {
+	return datastore.NewKey(c, "MatchReport", MatchId(matchKey), 0, matchKey.Parent())
+}
+
+// Returns the report of a match, or nil if there is none.
+func GetMatchReport(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	matchKey *datastore.Key) (*MatchReport, error) {
+	if err := canViewLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, err
+	}
+	report := new(MatchReport)
+	err := datastore.Get(c, KeyForMatchReport(c, matchKey), report)
+	if err == datastore.ErrNoSuchEntity {
+		return nil, nil
+	} else if err != nil {
+		return nil, errwrap.Wrap(err)
+	}
+	return report, nil
+}
+
+// Returns the history of the report of a match, oldest first.
+func GetMatchReportEvents(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	matchKey *datastore.Key) ([]*MatchReportEvent, error) {
+	if err := canViewLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, err
+	}
+	var events []*MatchReportEvent
+	q := datastore.NewQuery("MatchReportEvent").Ancestor(leagueKey).
+		Filter("ScheduledMatch =", matchKey).
+		Order("Time")
+	if _, err := q.GetAll(c, &events); err != nil {
+		return nil, errwrap.Wrap(err)
+	}
+	return events, nil
+}
+
+// Reports the result of a match on behalf of teamKey, one of the teams of the match.
+// gamesWon are in the order of match.TeamKeys.
+func ReportMatchResult(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	match *ScheduledMatch,
+	matchKey *datastore.Key,
+	teamKey *datastore.Key,
+	gamesWon []int,
+	gameKeys []*datastore.Key,
+	comment string) error {
+	if !match.HasTeam(teamKey) {
+		return errors.New("Only teams in the match can report its result")
+	}
+	if err := validateGamesWon(match, gamesWon); err != nil {
+		return err
+	}
+	if err := canActForTeam(c, userAcls, league, leagueKey, teamKey); err != nil {
+		return err
+	}
+	if len(gameKeys) > 0 {
+		games := make([]*Game, len(gameKeys))
+		for i := range games {
+			games[i] = new(Game)
+		}
+		if err := datastore.GetMulti(c, gameKeys, games); err != nil {
+			return errors.New(fmt.Sprintf("Unknown game in %v: %v", gameKeys, err))
+		}
+	}
+
+	return datastore.RunInTransaction(c, func(c appengine.Context) error {
+		key := KeyForMatchReport(c, matchKey)
+		report := new(MatchReport)
+		err := datastore.Get(c, key, report)
+		if err == nil && report.State != MatchReportRejected {
+			return errors.New(fmt.Sprintf(
+				"A result has already been reported for this match (%v)", report.State))
+		} else if err != nil && err != datastore.ErrNoSuchEntity {
+			return errwrap.Wrap(err)
+		}
+
+		now := time.Now()
+		report = &MatchReport{
+			ScheduledMatch: matchKey,
+			ReportingTeam:  teamKey,
+			GamesWon:       gamesWon,
+			GameKeys:       gameKeys,
+			State:          MatchReportPending,
+			Created:        now,
+			Updated:        now,
+		}
+		if userAcls != nil {
+			report.ReportedBy = userAcls.UserKey
+		}
+		if _, err := datastore.Put(c, key, report); err != nil {
+			return errwrap.Wrap(err)
+		}
+		return putMatchReportEvent(c, userAcls, report, comment)
+	}, nil)
+}
+
+// Confirms or disputes the pending report of a match on behalf of teamKey, which must
+// be the team that did not report it. A confirmed result advances the match's bracket.
+func RespondToMatchReport(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	match *ScheduledMatch,
+	matchKey *datastore.Key,
+	teamKey *datastore.Key,
+	confirm bool,
+	comment string) error {
+	if !match.HasTeam(teamKey) {
+		return errors.New("Only teams in the match can respond to its report")
+	}
+	if err := canActForTeam(c, userAcls, league, leagueKey, teamKey); err != nil {
+		return err
+	}
+	if !confirm && comment == "" {
+		return errors.New("Explain why the reported result is disputed")
+	}
+
+	err := datastore.RunInTransaction(c, func(c appengine.Context) error {
+		report, err := getMatchReportInState(c, matchKey, MatchReportPending)
+		if err != nil {
+			return err
+		}
+		if report.ReportingTeam.Equal(teamKey) {
+			return errors.New("The opposing team must respond to a reported result")
+		}
+
+		if confirm {
+			report.State = MatchReportConfirmed
+			if err := putManualMatchResults(c, league, match, matchKey, report); err != nil {
+				return err
+			}
+		} else {
+			report.State = MatchReportDisputed
+		}
+		report.Updated = time.Now()
+		if _, err := datastore.Put(c, KeyForMatchReport(c, matchKey), report); err != nil {
+			return errwrap.Wrap(err)
+		}
+		return putMatchReportEvent(c, userAcls, report, comment)
+	}, nil)
+	if err != nil {
+		return err
+	}
+	return AdvanceForMatch(c, matchKey)
+}
+
+// Resolves the pending or disputed report of a match as a league editor. If accepted,
+// gamesWon replaces the reported score unless it is nil.
+func ResolveMatchReport(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	match *ScheduledMatch,
+	matchKey *datastore.Key,
+	accept bool,
+	gamesWon []int,
+	comment string) error {
+	if err := canEditLeague(c, userAcls, league, leagueKey); err != nil {
+		return err
+	}
+	if gamesWon != nil {
+		if err := validateGamesWon(match, gamesWon); err != nil {
+			return err
+		}
+	}
+
+	err := datastore.RunInTransaction(c, func(c appengine.Context) error {
+		report, err := getMatchReportInState(
+			c, matchKey, MatchReportPending, MatchReportDisputed)
+		if err != nil {
+			return err
+		}
+
+		if accept {
+			if gamesWon != nil {
+				report.GamesWon = gamesWon
+			}
+			report.State = MatchReportResolved
+			if err := putManualMatchResults(c, league, match, matchKey, report); err != nil {
+				return err
+			}
+		} else {
+			report.State = MatchReportRejected
+		}
+		report.Updated = time.Now()
+		if _, err := datastore.Put(c, KeyForMatchReport(c, matchKey), report); err != nil {
+			return errwrap.Wrap(err)
+		}
+		return putMatchReportEvent(c, userAcls, report, comment)
+	}, nil)
+	if err != nil {
+		return err
+	}
+	return AdvanceForMatch(c, matchKey)
+}
+
+func getMatchReportInState(
+	c appengine.Context,
+	matchKey *datastore.Key,
+	states ...MatchReportState) (*MatchReport, error) {
+	report := new(MatchReport)
+	err := datastore.Get(c, KeyForMatchReport(c, matchKey), report)
+	if err == datastore.ErrNoSuchEntity {
+		return nil, errors.New("No result has been reported for this match")
+	} else if err != nil {
+		return nil, errwrap.Wrap(err)
+	}
+	for _, state := range states {
+		if report.State == state {
+			return report, nil
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("The reported result is %v", report.State))
+}
+
+func putMatchReportEvent(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	report *MatchReport,
+	comment string) error {
+	event := &MatchReportEvent{
+		ScheduledMatch: report.ScheduledMatch,
+		State:          report.State,
+		GamesWon:       report.GamesWon,
+		Comment:        comment,
+		Time:           report.Updated,
+	}
+	if userAcls != nil {
+		event.User = userAcls.UserKey
+	}
+	key := datastore.NewIncompleteKey(c, "MatchReportEvent", report.ScheduledMatch.Parent())
+	_, err := datastore.Put(c, key, event)
+	return errwrap.Wrap(err)
+}
+
+// Saves the score of report as the final, manual MatchResult of each team.
+func putManualMatchResults(
+	c appengine.Context,
+	league *League,
+	match *ScheduledMatch,
+	matchKey *datastore.Key,
+	report *MatchReport) error {
+	scoring, err := TeamScoringFor(match.TeamScoringType(league))
+	if err != nil {
+		return err
+	}
+	for i, teamKey := range match.TeamKeys {
+		won := report.GamesWon[i]
+		lost := 0
+		for j, opponentWon := range report.GamesWon {
+			if j != i {
+				lost += opponentWon
+			}
+		}
+		result := &MatchResult{
+			ScheduledMatch: matchKey,
+			Team:           teamKey,
+			Points:         scoring.Score(match, reportedGames(won, lost)).Points,
+			ManualResult:   true,
+			IsFinal:        true,
+			GamesWon:       won,
+			GamesLost:      lost,
+		}
+		if err := putMatchResult(c, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns games viewed by a team that won and lost the given number of games, for
+// scoring reported results.
+func reportedGames(won int, lost int) []*GameInfo {
+	games := make([]*GameInfo, won+lost)
+	for i := range games {
+		games[i] = NewGameInfo()
+		if i < won {
+			games[i].ThisTeam.IsWinner = true
+		} else {
+			games[i].OtherTeam.IsWinner = true
+		}
+	}
+	return games
+}
+
+func validateGamesWon(match *ScheduledMatch, gamesWon []int) error {
+	if len(gamesWon) != len(match.TeamKeys) {
+		return errors.New(fmt.Sprintf(
+			"Expected games won for %d teams, got %d", len(match.TeamKeys), len(gamesWon)))
+	}
+	total := 0
+	for _, won := range gamesWon {
+		if won < 0 {
+			return errors.New("Games won must be non-negative")
+		}
+		total += won
+	}
+	if total == 0 {
+		return errors.New("At least one game must have been won")
+	}
+	if match.NumGames > 0 && total > match.NumGames {
+		return errors.New(fmt.Sprintf(
+			"%d games reported for a match of %d games", total, match.NumGames))
+	}
+	return nil
+}
+
+func canViewLeague(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key) error {
+	if userAcls == nil || *userAcls.UserKey == *league.Owner {
+		return nil
+	}
+	return userAcls.Can(c, PermissionView, leagueKey)
+}
+
+func canEditLeague(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key) error {
+	if userAcls == nil || *userAcls.UserKey == *league.Owner {
+		return nil
+	}
+	return userAcls.Can(c, PermissionEdit, leagueKey)
+}
+
+// Returns nil if the user may act on behalf of a league team: league editors may act
+// for every team and users for the teams their verified summoners captain.
+func canActForTeam(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	teamKey *datastore.Key) error {
+	// Internal callers, with nil userAcls, may act for every team.
+	if userAcls == nil || canEditLeague(c, userAcls, league, leagueKey) == nil {
+		return nil
+	}
+
+	var verified []*VerifiedSummoner
+	q := datastore.NewQuery("VerifiedSummoner").Filter("User =", userAcls.UserKey)
+	if _, err := q.GetAll(c, &verified); err != nil {
+		return errwrap.Wrap(err)
+	}
+	for _, v := range verified {
+		var memberships []*TeamMembership
+		q := datastore.NewQuery("TeamMembership").Ancestor(leagueKey).
+			Filter("TeamKey =", teamKey).
+			Filter("PlayerKey =", v.Player)
+		if _, err := q.GetAll(c, &memberships); err != nil {
+			return errwrap.Wrap(err)
+		}
+		for _, m := range memberships {
+			if m.IsCurrent() && m.Captain {
+				return nil
+			}
+		}
+	}
+	return errors.New("Only the team's captain, with a verified summoner, may act for it")
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"appengine/datastore"
+	"testing"
+)
+
+func TestValidateGamesWon(t *testing.T) {
+	match := &ScheduledMatch{NumGames: 3, TeamKeys: make([]*datastore.Key, 2)}
+	for _, gamesWon := range [][]int{{2, 1}, {2, 0}, {0, 1}} {
+		if err := validateGamesWon(match, gamesWon); err != nil {
+			t.Errorf("%v: %v", gamesWon, err)
+		}
+	}
+	for _, gamesWon := range [][]int{{2}, {2, 0, 0}, {0, 0}, {-1, 2}, {2, 2}} {
+		if err := validateGamesWon(match, gamesWon); err == nil {
+			t.Errorf("%v: accepted", gamesWon)
+		}
+	}
+
+	unbounded := &ScheduledMatch{TeamKeys: make([]*datastore.Key, 2)}
+	if err := validateGamesWon(unbounded, []int{4, 3}); err != nil {
+		t.Errorf("unbounded match: %v", err)
+	}
+}
+
+func TestReportedGamesScoring(t *testing.T) {
+	scoring, err := TeamScoringFor(BestOfSeries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := &ScheduledMatch{NumGames: 3}
+	tests := []struct {
+		won, lost int
+		want      Score
+	}{
+		{2, 0, Score{2, true}},
+		{2, 1, Score{2, true}},
+		{1, 2, Score{0, true}},
+	}
+	for _, test := range tests {
+		if got := scoring.Score(match, reportedGames(test.won, test.lost)); got != test.want {
+			t.Errorf("%d-%d: got %+v, want %+v", test.won, test.lost, got, test.want)
+		}
+	}
+}
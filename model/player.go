@@ -6,8 +6,8 @@ import (
 	"appengine/memcache"
 	"errors"
 	"fmt"
-	"github.com/OwenDurni/loltools/riot"
 	"github.com/OwenDurni/loltools/util/errwrap"
+	"golang.org/x/net/context"
 	"strconv"
 	"strings"
 	"time"
@@ -39,11 +39,11 @@ func (cache *PlayerCache) ById(riotId int64) (*Player, error) {
 	}
 	return p, err
 }
-func (cache *PlayerCache) BySummoner(summoner string) (*Player, error) {
+func (cache *PlayerCache) BySummoner(ctx context.Context, summoner string) (*Player, error) {
 	if p, exists := cache.bySummoner[summoner]; exists {
 		return p, nil
 	}
-	p, _, err := GetOrCreatePlayerBySummoner(cache.c, cache.Region, summoner)
+	p, _, err := GetOrCreatePlayerBySummoner(cache.c, ctx, cache.Region, summoner)
 	if err == nil {
 		cache.Add(p)
 	}
@@ -140,6 +140,7 @@ func GetPlayerByRiotIdOrStub(
 
 func GetOrCreatePlayerByRiotId(
 	c appengine.Context,
+	ctx context.Context,
 	region string,
 	riotId int64) (*Player, *datastore.Key, error) {
 	player := new(Player)
@@ -155,14 +156,7 @@ func GetOrCreatePlayerByRiotId(
 	for attempt := 0; attempt < 3; attempt++ {
 		err := datastore.Get(c, playerKey, player)
 		if err == datastore.ErrNoSuchEntity {
-			if err := RiotApiRateLimiter.Consume(c, 1); err != nil {
-				return nil, nil, errwrap.Wrap(err)
-			}
-			riotApiKey, err := GetRiotApiKey(c)
-			if err != nil {
-				return nil, nil, errwrap.Wrap(err)
-			}
-			riotSummoners, err := riot.SummonersById(c, riotApiKey.Key, region, riotId)
+			riotSummoners, err := RiotClient(c, region).SummonersById(ctx, riotId)
 			if err != nil {
 				return nil, nil, errwrap.Wrap(err)
 			}
@@ -192,17 +186,10 @@ func GetOrCreatePlayerByRiotId(
 
 func GetOrCreatePlayerBySummoner(
 	c appengine.Context,
+	ctx context.Context,
 	region string,
 	summoner string) (*Player, *datastore.Key, error) {
-	if err := RiotApiRateLimiter.Consume(c, 1); err != nil {
-		return nil, nil, errwrap.Wrap(err)
-	}
-	riotApiKey, err := GetRiotApiKey(c)
-	if err != nil {
-		return nil, nil, errwrap.Wrap(err)
-	}
-
-	riotSummoner, err := riot.SummonerByName(c, riotApiKey.Key, region, summoner)
+	riotSummoner, err := RiotClient(c, region).SummonerByName(ctx, summoner)
 	if err != nil {
 		return nil, nil, errwrap.Wrap(err)
 	}
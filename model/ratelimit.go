@@ -5,6 +5,7 @@ import (
 	"appengine/memcache"
 	"errors"
 	"fmt"
+	"golang.org/x/net/context"
 	"math"
 	"time"
 )
@@ -47,12 +48,14 @@ func (r DistributedRateLimiter) Init(c appengine.Context) error {
 	return err
 }
 
-// Blocks for up to 1 minute.
+// Blocks for up to 1 minute, or until ctx is done in which case ctx.Err() is returned.
 func (r *DistributedRateLimiter) Consume(
 	c appengine.Context,
+	ctx context.Context,
 	events int) error {
 
-	tryResult := make(chan error)
+	// Buffered so that an abandoned try does not block forever.
+	tryResult := make(chan error, 1)
 	timeout := time.After(1 * time.Minute)
 
 	try := func() {
@@ -64,7 +67,11 @@ func (r *DistributedRateLimiter) Consume(
 		select {
 		case err := <-tryResult:
 			if _, ok := err.(ErrRateLimitExceeded); ok {
-				time.Sleep(1 * time.Second)
+				select {
+				case <-time.After(1 * time.Second):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
 				continue
 			}
 			return err
@@ -73,6 +80,8 @@ func (r *DistributedRateLimiter) Consume(
 				r,
 				errors.New("Consume() timeout after 1 minute."),
 			}
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
 }
@@ -112,6 +121,37 @@ func (r *DistributedRateLimiter) TryConsume(c appengine.Context, events int) err
 	return errors.New(fmt.Sprintf("RetryLimit for memcache.CompareAndSwap reached: %s", key))
 }
 
+// Removes all tokens from the rate limiter so that no further events are permitted
+// until tokens are added back. No tokens are added back for the first `pause`.
+//
+// This is used when the remote service tells us we have exceeded its limits before
+// our own accounting noticed.
+func (r *DistributedRateLimiter) Drain(c appengine.Context, pause time.Duration) error {
+	e := new(DistributedRateLimiterEntity)
+	key := fmt.Sprintf("DistributedRateLimiterEntity/%s", r.Name)
+	for attempt := 0; attempt < 10; attempt++ {
+		item, err := memcache.JSON.Get(c, key, e)
+		if err != nil {
+			if err == memcache.ErrCacheMiss {
+				// Put a fresh item in memcache and try again.
+				r.Init(c)
+				continue
+			} else {
+				return err
+			}
+		}
+
+		e.drain(time.Now().UTC().Add(pause))
+
+		item.Object = e
+		if err := memcache.JSON.CompareAndSwap(c, item); err != nil {
+			continue
+		}
+		return nil
+	}
+	return errors.New(fmt.Sprintf("RetryLimit for memcache.CompareAndSwap reached: %s", key))
+}
+
 func (r *DistributedRateLimiter) DebugStr(c appengine.Context) string {
 	e := new(DistributedRateLimiterEntity)
 	key := fmt.Sprintf("DistributedRateLimiterEntity/%s", r.Name)
@@ -178,6 +218,14 @@ func (e *DistributedRateLimiterEntity) addTokens(t time.Time) {
 	}
 }
 
+// Empties every bucket and does not refill them until time t.
+func (e *DistributedRateLimiterEntity) drain(t time.Time) {
+	for i, _ := range e.Buckets {
+		e.Buckets[i].Tokens = 0.0
+		e.Buckets[i].LastCheckTime = t
+	}
+}
+
 func (e *DistributedRateLimiterEntity) tryConsume(numTokens int) error {
 	tokens := float64(numTokens)
 
@@ -4,6 +4,12 @@ import (
 	"appengine"
 	"appengine/datastore"
 	"appengine/memcache"
+	"appengine/urlfetch"
+	"github.com/OwenDurni/loltools/riot"
+	"golang.org/x/net/context"
+	"net/http"
+	"os"
+	"time"
 )
 
 type ErrorNoRiotApiKey struct{}
@@ -18,10 +24,43 @@ const (
 	RegionEUNE = "eune"
 )
 
-var Regions = []string{
-	RegionNA,
-	RegionEUW,
-	RegionEUNE,
+// The codes of all regions the app supports.
+var Regions = riot.Regions.Codes()
+
+// The regions RiotClient sends requests to. Setting the RIOT_BASE_URL environment
+// variable sends every region to that server instead of the live API (ex: a local
+// tools/fakeriot at "http://localhost:8081").
+var riotRegions = riotRegionsFromEnv()
+
+func riotRegionsFromEnv() riot.RegionRegistry {
+	if baseUrl := os.Getenv("RIOT_BASE_URL"); baseUrl != "" {
+		return riot.Regions.WithBaseUrl(baseUrl)
+	}
+	return riot.Regions
+}
+
+// How long App Engine lets requests run before aborting them.
+const (
+	FrontendRequestDeadline = 60 * time.Second
+	TaskRequestDeadline     = 10 * time.Minute
+)
+
+// How long before the App Engine deadline NewRequestContext gives up on Riot requests,
+// leaving time to save progress and respond.
+const requestDeadlineMargin = 10 * time.Second
+
+// The longest a single Riot API request made by RiotClient may take.
+const riotRequestTimeout = 15 * time.Second
+
+// Returns a context for the Riot requests made while handling r. It is done shortly
+// before App Engine's deadline for r so handlers can stop cleanly instead of being
+// aborted. Callers must call the returned CancelFunc once they are done.
+func NewRequestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	deadline := FrontendRequestDeadline
+	if r.Header.Get("X-AppEngine-TaskName") != "" {
+		deadline = TaskRequestDeadline
+	}
+	return context.WithTimeout(context.Background(), deadline-requestDeadlineMargin)
 }
 
 var RiotApiRateLimiter = DistributedRateLimiter{
@@ -72,3 +111,34 @@ func SetRiotApiKey(c appengine.Context, apikey string) error {
 	memcache.JSON.Set(c, &memcache.Item{Key: "RiotApiKey/dev", Object: r})
 	return nil
 }
+
+// Returns a riot.Client for the given region that authenticates with the stored
+// RiotApiKey and consumes from RiotApiRateLimiter before each request. When Riot
+// reports that we are rate limited RiotApiRateLimiter is drained to match. Responses
+// are cached in a RiotResponseCache.
+//
+// Each call on the client takes a context, usually from NewRequestContext.
+func RiotClient(c appengine.Context, region string) *riot.Client {
+	return riot.NewClient(riot.ClientOptions{
+		Transport: &urlfetch.Transport{Context: c, Deadline: riotRequestTimeout},
+		ApiKey: func() (string, error) {
+			riotApiKey, err := GetRiotApiKey(c)
+			if err != nil {
+				return "", err
+			}
+			return riotApiKey.Key, nil
+		},
+		RateLimit: func(ctx context.Context) error {
+			return RiotApiRateLimiter.Consume(c, ctx, 1)
+		},
+		OnRateLimited: func(retryAfter time.Duration) {
+			if err := RiotApiRateLimiter.Drain(c, retryAfter); err != nil {
+				c.Warningf("Failed to drain %s: %v", RiotApiRateLimiter.Name, err)
+			}
+		},
+		RequestTimeout: riotRequestTimeout,
+		Region:         region,
+		Regions:        riotRegions,
+		Cache:          NewRiotResponseCache(c),
+	})
+}
@@ -0,0 +1,124 @@
+package model
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"appengine/memcache"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/hex"
+	"github.com/OwenDurni/loltools/riot"
+	"io/ioutil"
+	"time"
+)
+
+// A riot.Cache for the app. Every response goes to memcache. Responses that never
+// expire (ex: match details) are also written to datastore so they survive memcache
+// eviction.
+//
+// Entries are gzipped since match details with timelines approach the 1MB limit of
+// both memcache and datastore.
+type RiotResponseCache struct {
+	c appengine.Context
+}
+
+func NewRiotResponseCache(c appengine.Context) *RiotResponseCache {
+	return &RiotResponseCache{c}
+}
+
+// A Riot API response cached with riot.CacheForever.
+//
+// The hash of the riot cache key is the key.
+type RiotResponse struct {
+	CacheKey string    `datastore:",noindex"`
+	Data     []byte    `datastore:",noindex"`
+	Created  time.Time `datastore:",noindex"`
+}
+
+// Riot cache keys can exceed the memcache and datastore key limits, so hash them.
+func riotResponseId(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func riotResponseMemcacheKey(key string) string {
+	return "RiotResponse/" + riotResponseId(key)
+}
+
+func (rc *RiotResponseCache) Get(key string) ([]byte, bool) {
+	c := rc.c
+	if item, err := memcache.Get(c, riotResponseMemcacheKey(key)); err == nil {
+		if data, err := gunzip(item.Value); err == nil {
+			return data, true
+		}
+	}
+
+	resp := new(RiotResponse)
+	err := datastore.Get(
+		c, datastore.NewKey(c, "RiotResponse", riotResponseId(key), 0, nil), resp)
+	if err != nil {
+		if err != datastore.ErrNoSuchEntity {
+			c.Warningf("RiotResponseCache: %v", err)
+		}
+		return nil, false
+	}
+	if resp.CacheKey != key {
+		return nil, false
+	}
+	data, err := gunzip(resp.Data)
+	if err != nil {
+		c.Warningf("RiotResponseCache: corrupt entry for %s: %v", key, err)
+		return nil, false
+	}
+	// Best effort put back into memcache.
+	memcache.Set(c, &memcache.Item{Key: riotResponseMemcacheKey(key), Value: resp.Data})
+	return data, true
+}
+
+func (rc *RiotResponseCache) Set(key string, data []byte, ttl time.Duration) {
+	c := rc.c
+	compressed, err := gzipBytes(data)
+	if err != nil {
+		c.Warningf("RiotResponseCache: %v", err)
+		return
+	}
+
+	item := &memcache.Item{Key: riotResponseMemcacheKey(key), Value: compressed}
+	if ttl != riot.CacheForever {
+		item.Expiration = ttl
+	}
+	if err := memcache.Set(c, item); err != nil {
+		c.Warningf("RiotResponseCache: %v", err)
+	}
+
+	if ttl == riot.CacheForever {
+		resp := &RiotResponse{CacheKey: key, Data: compressed, Created: time.Now()}
+		_, err := datastore.Put(
+			c, datastore.NewKey(c, "RiotResponse", riotResponseId(key), 0, nil), resp)
+		if err != nil {
+			c.Warningf("RiotResponseCache: %v", err)
+		}
+	}
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
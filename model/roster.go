@@ -0,0 +1,316 @@
+package model
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"errors"
+	"fmt"
+	"github.com/OwenDurni/loltools/util/errwrap"
+	"time"
+)
+
+// The lane a player plays for their team.
+type RosterRole int
+
+const (
+	// The zero value, so memberships from before roles existed are "fill".
+	RoleFill RosterRole = iota
+	RoleTop
+	RoleJungle
+	RoleMid
+	RoleAdc
+	RoleSupport
+)
+
+// Names of the RosterRoles as used in forms.
+var rosterRoleNames = map[RosterRole]string{
+	RoleFill:    "fill",
+	RoleTop:     "top",
+	RoleJungle:  "jungle",
+	RoleMid:     "mid",
+	RoleAdc:     "adc",
+	RoleSupport: "support",
+}
+
+func (r RosterRole) String() string {
+	if name, ok := rosterRoleNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("RosterRole(%d)", int(r))
+}
+
+func ParseRosterRole(name string) (RosterRole, error) {
+	for r, n := range rosterRoleNames {
+		if n == name {
+			return r, nil
+		}
+	}
+	return 0, errors.New(fmt.Sprintf("Unknown role '%s'", name))
+}
+
+// Limits on the rosters of a league's teams. Zero values are unlimited.
+type RosterRules struct {
+	// The fewest players a team may be left with when removing a player.
+	MinSize int
+	MaxSize int
+
+	MaxSubstitutes int
+
+	// Rosters may not change from this time on.
+	LockDate time.Time
+}
+
+func (r *RosterRules) IsLocked(now time.Time) bool {
+	return !r.LockDate.IsZero() && !now.Before(r.LockDate)
+}
+
+func (r *RosterRules) validate() error {
+	if r.MinSize < 0 || r.MaxSize < 0 || r.MaxSubstitutes < 0 {
+		return errors.New("Roster limits must be non-negative")
+	}
+	if r.MaxSize > 0 && r.MinSize > r.MaxSize {
+		return errors.New(fmt.Sprintf(
+			"The minimum roster size (%d) is more than the maximum (%d)",
+			r.MinSize, r.MaxSize))
+	}
+	return nil
+}
+
+func (r *RosterRules) checkLock(now time.Time) error {
+	if r.IsLocked(now) {
+		return errors.New(fmt.Sprintf(
+			"Rosters are locked since %s", r.LockDate.Format("2006-01-02 15:04 MST")))
+	}
+	return nil
+}
+
+func countSubstitutes(roster []*TeamMembership) int {
+	n := 0
+	for _, m := range roster {
+		if m.Substitute {
+			n++
+		}
+	}
+	return n
+}
+
+// Returns an error if the rules do not allow adding m to a team's current roster.
+func (r *RosterRules) checkAdd(
+	roster []*TeamMembership, m *TeamMembership, now time.Time) error {
+	if err := r.checkLock(now); err != nil {
+		return err
+	}
+	if r.MaxSize > 0 && len(roster) >= r.MaxSize {
+		return errors.New(fmt.Sprintf(
+			"The roster is full: teams may have at most %d players", r.MaxSize))
+	}
+	if m.Substitute && r.MaxSubstitutes > 0 && countSubstitutes(roster) >= r.MaxSubstitutes {
+		return errors.New(fmt.Sprintf(
+			"Teams may have at most %d substitutes; add the player as a starter instead",
+			r.MaxSubstitutes))
+	}
+	return nil
+}
+
+// Returns an error if the rules do not allow removing a player from a team's current
+// roster.
+func (r *RosterRules) checkRemove(roster []*TeamMembership, now time.Time) error {
+	if err := r.checkLock(now); err != nil {
+		return err
+	}
+	if r.MinSize > 0 && len(roster) <= r.MinSize {
+		return errors.New(fmt.Sprintf(
+			"Teams must keep at least %d players; add a replacement first", r.MinSize))
+	}
+	return nil
+}
+
+// Returns an error if the rules do not allow changing a member of a team's current
+// roster from old to updated.
+func (r *RosterRules) checkUpdate(
+	roster []*TeamMembership, old *TeamMembership, updated *TeamMembership,
+	now time.Time) error {
+	if err := r.checkLock(now); err != nil {
+		return err
+	}
+	if updated.Substitute && !old.Substitute && r.MaxSubstitutes > 0 &&
+		countSubstitutes(roster) >= r.MaxSubstitutes {
+		return errors.New(fmt.Sprintf(
+			"Teams may have at most %d substitutes", r.MaxSubstitutes))
+	}
+	return nil
+}
+
+// Sets the roster rules of a league. Existing rosters are not changed, even if they break
+// the new rules.
+func LeagueSetRosterRules(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	rules *RosterRules) error {
+	if err := canEditLeague(c, userAcls, league, leagueKey); err != nil {
+		return err
+	}
+	if err := rules.validate(); err != nil {
+		return err
+	}
+	return datastore.RunInTransaction(c, func(c appengine.Context) error {
+		league := new(League)
+		if err := datastore.Get(c, leagueKey, league); err != nil {
+			return errwrap.Wrap(err)
+		}
+		league.RosterRules = *rules
+		_, err := datastore.Put(c, leagueKey, league)
+		return errwrap.Wrap(err)
+	}, nil)
+}
+
+// Returns the current members of a team's roster for a season, or for every season if
+// seasonKey is nil.
+func teamRoster(
+	c appengine.Context,
+	leagueKey *datastore.Key,
+	teamKey *datastore.Key,
+	seasonKey *datastore.Key) ([]*TeamMembership, []*datastore.Key, error) {
+	var memberships []*TeamMembership
+	q := datastore.NewQuery("TeamMembership").Ancestor(leagueKey).
+		Filter("TeamKey =", teamKey)
+	if seasonKey != nil {
+		q = q.Filter("Season =", seasonKey)
+	}
+	keys, err := q.GetAll(c, &memberships)
+	if err != nil {
+		return nil, nil, errwrap.Wrap(err)
+	}
+
+	// Former members are kept for their history.
+	var roster []*TeamMembership
+	var rosterKeys []*datastore.Key
+	for i, m := range memberships {
+		if m.IsCurrent() {
+			roster = append(roster, m)
+			rosterKeys = append(rosterKeys, keys[i])
+		}
+	}
+	return roster, rosterKeys, nil
+}
+
+// Returns the current members of a team's roster for the season of scope, or the
+// league's current season if scope is nil.
+func TeamRoster(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	teamKey *datastore.Key,
+	scope *LeagueScope) ([]*TeamMembership, error) {
+	if err := canViewLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, err
+	}
+	seasonKey := league.CurrentSeason
+	if scope != nil {
+		seasonKey = scope.SeasonKey
+	}
+	roster, _, err := teamRoster(c, leagueKey, teamKey, seasonKey)
+	return roster, err
+}
+
+// Returns the index of playerKey in a roster, or -1.
+func findRosterPlayer(roster []*TeamMembership, playerKey *datastore.Key) int {
+	for i, m := range roster {
+		if *m.PlayerKey == *playerKey {
+			return i
+		}
+	}
+	return -1
+}
+
+// Clears the captain flag of every member of roster but keep. Runs within a transaction.
+func putCaptain(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	leagueKey *datastore.Key,
+	roster []*TeamMembership,
+	rosterKeys []*datastore.Key,
+	keep int,
+	now time.Time) error {
+	for i, m := range roster {
+		if i == keep || !m.Captain {
+			continue
+		}
+		m.Captain = false
+		if _, err := datastore.Put(c, rosterKeys[i], m); err != nil {
+			return errwrap.Wrap(err)
+		}
+		err := putRosterTransaction(
+			c, userAcls, leagueKey, m, RosterCaptainChange, "Replaced by a new captain", now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Changes the role, substitute and captain flags of a player on a team's current roster.
+// Making a player captain replaces the team's previous captain.
+func TeamUpdatePlayer(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	teamKey *datastore.Key,
+	playerKey *datastore.Key,
+	role RosterRole,
+	substitute bool,
+	captain bool,
+	reason string) error {
+	if err := canEditLeague(c, userAcls, league, leagueKey); err != nil {
+		return err
+	}
+
+	return datastore.RunInTransaction(c, func(c appengine.Context) error {
+		roster, rosterKeys, err := teamRoster(c, leagueKey, teamKey, league.CurrentSeason)
+		if err != nil {
+			return err
+		}
+		i := findRosterPlayer(roster, playerKey)
+		if i < 0 {
+			return errors.New("The player is not on this team's roster")
+		}
+		old := roster[i]
+		updated := *old
+		updated.Role = role
+		updated.Substitute = substitute
+		updated.Captain = captain
+		now := time.Now()
+		if err := league.RosterRules.checkUpdate(roster, old, &updated, now); err != nil {
+			return err
+		}
+		if captain {
+			err := putCaptain(c, userAcls, leagueKey, roster, rosterKeys, i, now)
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := datastore.Put(c, rosterKeys[i], &updated); err != nil {
+			return errwrap.Wrap(err)
+		}
+
+		if updated.Role != old.Role || updated.Substitute != old.Substitute {
+			err := putRosterTransaction(
+				c, userAcls, leagueKey, &updated, RosterRoleChange, reason, now)
+			if err != nil {
+				return err
+			}
+		}
+		if updated.Captain != old.Captain {
+			err := putRosterTransaction(
+				c, userAcls, leagueKey, &updated, RosterCaptainChange, reason, now)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil)
+}
@@ -0,0 +1,131 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRosterRole(t *testing.T) {
+	for role, name := range rosterRoleNames {
+		got, err := ParseRosterRole(name)
+		if err != nil || got != role {
+			t.Errorf("ParseRosterRole(%q): got %v, %v; want %v", name, got, err, role)
+		}
+		if role.String() != name {
+			t.Errorf("%d.String(): got %q, want %q", int(role), role.String(), name)
+		}
+	}
+	if _, err := ParseRosterRole("bot"); err == nil {
+		t.Errorf("ParseRosterRole(\"bot\") should fail")
+	}
+}
+
+func TestRosterRulesValidate(t *testing.T) {
+	tests := []struct {
+		rules RosterRules
+		ok    bool
+	}{
+		{RosterRules{}, true},
+		{RosterRules{MinSize: 5, MaxSize: 8, MaxSubstitutes: 3}, true},
+		{RosterRules{MinSize: 5}, true},
+		{RosterRules{MinSize: 6, MaxSize: 5}, false},
+		{RosterRules{MaxSubstitutes: -1}, false},
+	}
+	for _, test := range tests {
+		if err := test.rules.validate(); (err == nil) != test.ok {
+			t.Errorf("%+v: got %v, want ok=%v", test.rules, err, test.ok)
+		}
+	}
+}
+
+// Returns a roster of starters followed by subs substitutes.
+func testRoster(starters int, subs int) []*TeamMembership {
+	var roster []*TeamMembership
+	for i := 0; i < starters; i++ {
+		roster = append(roster, &TeamMembership{})
+	}
+	for i := 0; i < subs; i++ {
+		roster = append(roster, &TeamMembership{Substitute: true})
+	}
+	return roster
+}
+
+func TestRosterRulesCheckAdd(t *testing.T) {
+	now := time.Date(2015, 3, 1, 0, 0, 0, 0, time.UTC)
+	rules := RosterRules{MaxSize: 7, MaxSubstitutes: 2}
+	starter := &TeamMembership{}
+	sub := &TeamMembership{Substitute: true}
+
+	tests := []struct {
+		rules  RosterRules
+		roster []*TeamMembership
+		m      *TeamMembership
+		ok     bool
+	}{
+		{RosterRules{}, testRoster(20, 20), sub, true},
+		{rules, testRoster(5, 1), sub, true},
+		{rules, testRoster(5, 1), starter, true},
+		{rules, testRoster(5, 2), starter, false},
+		{rules, testRoster(4, 2), sub, false},
+		{rules, testRoster(4, 2), starter, true},
+		{RosterRules{LockDate: now.Add(time.Hour)}, nil, starter, true},
+		{RosterRules{LockDate: now}, nil, starter, false},
+	}
+	for i, test := range tests {
+		err := test.rules.checkAdd(test.roster, test.m, now)
+		if (err == nil) != test.ok {
+			t.Errorf("test %d: got %v, want ok=%v", i, err, test.ok)
+		}
+	}
+}
+
+func TestRosterRulesCheckRemove(t *testing.T) {
+	now := time.Date(2015, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		rules  RosterRules
+		roster []*TeamMembership
+		ok     bool
+	}{
+		{RosterRules{}, testRoster(1, 0), true},
+		{RosterRules{MinSize: 5}, testRoster(5, 1), true},
+		{RosterRules{MinSize: 5}, testRoster(4, 1), false},
+		{RosterRules{LockDate: now.Add(-time.Hour)}, testRoster(5, 0), false},
+	}
+	for i, test := range tests {
+		err := test.rules.checkRemove(test.roster, now)
+		if (err == nil) != test.ok {
+			t.Errorf("test %d: got %v, want ok=%v", i, err, test.ok)
+		}
+	}
+}
+
+func TestRosterRulesCheckUpdate(t *testing.T) {
+	now := time.Date(2015, 3, 1, 0, 0, 0, 0, time.UTC)
+	rules := RosterRules{MaxSubstitutes: 2}
+	roster := testRoster(5, 2)
+	starter, sub := roster[0], roster[5]
+
+	tests := []struct {
+		old     *TeamMembership
+		updated *TeamMembership
+		ok      bool
+	}{
+		// Making a starter a substitute needs room for another substitute.
+		{starter, &TeamMembership{Substitute: true}, false},
+		{starter, &TeamMembership{Role: RoleMid, Captain: true}, true},
+		// Existing substitutes can change their role.
+		{sub, &TeamMembership{Substitute: true, Role: RoleSupport}, true},
+		{sub, &TeamMembership{}, true},
+	}
+	for i, test := range tests {
+		err := rules.checkUpdate(roster, test.old, test.updated, now)
+		if (err == nil) != test.ok {
+			t.Errorf("test %d: got %v, want ok=%v", i, err, test.ok)
+		}
+	}
+
+	locked := RosterRules{LockDate: now}
+	if err := locked.checkUpdate(roster, starter, &TeamMembership{}, now); err == nil {
+		t.Errorf("updates to locked rosters should fail")
+	}
+}
@@ -0,0 +1,213 @@
+package model
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"fmt"
+	"github.com/OwenDurni/loltools/util/errwrap"
+	"time"
+)
+
+type RosterTransactionType int
+
+const (
+	RosterAdd RosterTransactionType = iota
+	RosterRemove
+	// A change of role or between starter and substitute.
+	RosterRoleChange
+	RosterCaptainChange
+)
+
+var rosterTransactionTypeNames = map[RosterTransactionType]string{
+	RosterAdd:           "add",
+	RosterRemove:        "remove",
+	RosterRoleChange:    "role",
+	RosterCaptainChange: "captain",
+}
+
+func (t RosterTransactionType) String() string {
+	if name, ok := rosterTransactionTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("RosterTransactionType(%d)", int(t))
+}
+
+// A change to a team's roster. Every add, removal, role change and captain change is
+// recorded.
+//
+// Ancestor: League
+type RosterTransaction struct {
+	TeamKey   *datastore.Key
+	PlayerKey *datastore.Key
+	Season    *datastore.Key
+	Type      RosterTransactionType
+
+	// The player's place on the roster after the change.
+	Role       RosterRole
+	Substitute bool
+	Captain    bool
+
+	// The user who made the change. Nil for changes made by tasks.
+	User   *datastore.Key
+	Reason string `datastore:",noindex"`
+	Time   time.Time
+}
+
+// Records a change to membership m. Runs within the transaction making the change.
+func putRosterTransaction(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	leagueKey *datastore.Key,
+	m *TeamMembership,
+	transactionType RosterTransactionType,
+	reason string,
+	now time.Time) error {
+	t := &RosterTransaction{
+		TeamKey:    m.TeamKey,
+		PlayerKey:  m.PlayerKey,
+		Season:     m.Season,
+		Type:       transactionType,
+		Role:       m.Role,
+		Substitute: m.Substitute,
+		Captain:    m.Captain,
+		Reason:     reason,
+		Time:       now,
+	}
+	if userAcls != nil {
+		t.User = userAcls.UserKey
+	}
+	key := datastore.NewIncompleteKey(c, "RosterTransaction", leagueKey)
+	_, err := datastore.Put(c, key, t)
+	return errwrap.Wrap(err)
+}
+
+// Returns the roster changes of a team in the season of scope, or in every season if
+// scope is nil. Most recent first.
+func TeamRosterTransactions(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	teamKey *datastore.Key,
+	scope *LeagueScope) ([]*RosterTransaction, error) {
+	if err := canViewLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, err
+	}
+	var transactions []*RosterTransaction
+	q := datastore.NewQuery("RosterTransaction").Ancestor(leagueKey).
+		Filter("TeamKey =", teamKey)
+	if scope != nil {
+		q = q.Filter("Season =", scope.SeasonKey)
+	}
+	q = q.Order("-Time")
+	if _, err := q.GetAll(c, &transactions); err != nil {
+		return nil, errwrap.Wrap(err)
+	}
+	return transactions, nil
+}
+
+// Returns the memberships of roster that include time t. Memberships from before join
+// dates were recorded count from the start.
+func membershipsAt(roster []*TeamMembership, t time.Time) []*TeamMembership {
+	var at []*TeamMembership
+	for _, m := range roster {
+		if !m.Joined.IsZero() && t.Before(m.Joined) {
+			continue
+		}
+		if !m.Left.IsZero() && !t.Before(m.Left) {
+			continue
+		}
+		at = append(at, m)
+	}
+	return at
+}
+
+// Every roster a team has had, so that games are viewed with the players who were on the
+// team when they were played.
+type TeamRosterHistory struct {
+	// By the encoded key of their season. "" for memberships without a season.
+	memberships map[string][]*TeamMembership
+	seasons     []*Season
+	seasonKeys  []*datastore.Key
+
+	// By encoded key.
+	players map[string]*Player
+}
+
+func GetTeamRosterHistory(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	teamKey *datastore.Key) (*TeamRosterHistory, error) {
+	if err := canViewLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, err
+	}
+	seasons, seasonKeys, err := LeagueSeasons(c, userAcls, league, leagueKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var memberships []*TeamMembership
+	q := datastore.NewQuery("TeamMembership").Ancestor(leagueKey).
+		Filter("TeamKey =", teamKey)
+	if _, err := q.GetAll(c, &memberships); err != nil {
+		return nil, errwrap.Wrap(err)
+	}
+
+	h := &TeamRosterHistory{
+		memberships: make(map[string][]*TeamMembership),
+		seasons:     seasons,
+		seasonKeys:  seasonKeys,
+		players:     make(map[string]*Player),
+	}
+	var playerKeys []*datastore.Key
+	for _, m := range memberships {
+		seasonId := ""
+		if m.Season != nil {
+			seasonId = m.Season.Encode()
+		}
+		h.memberships[seasonId] = append(h.memberships[seasonId], m)
+		if _, ok := h.players[m.PlayerKey.Encode()]; !ok {
+			h.players[m.PlayerKey.Encode()] = nil
+			playerKeys = append(playerKeys, m.PlayerKey)
+		}
+	}
+
+	players := make([]*Player, len(playerKeys))
+	for i := range players {
+		players[i] = new(Player)
+	}
+	if err := datastore.GetMulti(c, playerKeys, players); err != nil {
+		return nil, errwrap.Wrap(err)
+	}
+	for i, p := range players {
+		h.players[playerKeys[i].Encode()] = p
+	}
+	return h, nil
+}
+
+// Returns the players on the team's roster at time t.
+func (h *TeamRosterHistory) PlayersAt(t time.Time) []*Player {
+	// Rosters carry over between seasons, so only the roster of one season counts: the
+	// latest to start by t, which covers games between seasons. The first season adopted
+	// the rosters from before it, so earlier games use its roster.
+	roster := h.memberships[""]
+	for i, s := range h.seasons {
+		if i > 0 && t.Before(s.Start) {
+			break
+		}
+		roster = h.memberships[h.seasonKeys[i].Encode()]
+	}
+
+	var players []*Player
+	seen := make(map[string]bool)
+	for _, m := range membershipsAt(roster, t) {
+		id := m.PlayerKey.Encode()
+		if !seen[id] && h.players[id] != nil {
+			seen[id] = true
+			players = append(players, h.players[id])
+		}
+	}
+	return players
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"appengine/datastore"
+	"testing"
+	"time"
+)
+
+func TestMembershipsAt(t *testing.T) {
+	start := time.Date(2015, 1, 5, 0, 0, 0, 0, time.UTC)
+	joined := start.AddDate(0, 0, 7)
+	left := start.AddDate(0, 0, 14)
+
+	legacy := &TeamMembership{}
+	current := &TeamMembership{Joined: joined}
+	former := &TeamMembership{Joined: joined, Left: left}
+	formerLegacy := &TeamMembership{Left: left}
+	roster := []*TeamMembership{legacy, current, former, formerLegacy}
+
+	tests := []struct {
+		t    time.Time
+		want []*TeamMembership
+	}{
+		{start, []*TeamMembership{legacy, formerLegacy}},
+		{joined, []*TeamMembership{legacy, current, former, formerLegacy}},
+		{left.Add(-time.Second), []*TeamMembership{legacy, current, former, formerLegacy}},
+		{left, []*TeamMembership{legacy, current}},
+	}
+	for _, test := range tests {
+		got := membershipsAt(roster, test.t)
+		if len(got) != len(test.want) {
+			t.Errorf("%v: got %d membership(s), want %d", test.t, len(got), len(test.want))
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%v: membership %d: got %+v, want %+v",
+					test.t, i, got[i], test.want[i])
+			}
+		}
+	}
+}
+
+func TestRosterTransactionTypeString(t *testing.T) {
+	for transactionType, name := range rosterTransactionTypeNames {
+		if transactionType.String() != name {
+			t.Errorf("got %q, want %q", transactionType.String(), name)
+		}
+	}
+	if got := RosterTransactionType(99).String(); got != "RosterTransactionType(99)" {
+		t.Errorf("unknown type: got %q", got)
+	}
+}
+
+func TestTeamRosterHistoryPlayersAt(t *testing.T) {
+	start := time.Date(2015, 1, 5, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 2, 0)
+	nextStart := end.AddDate(0, 1, 0)
+
+	alice := &Player{Summoner: "alice"}
+	bob := &Player{Summoner: "bob"}
+	carol := &Player{Summoner: "carol"}
+	seasonKeys := []*datastore.Key{
+		decodeTestKey(t, testSeasonKey1), decodeTestKey(t, testSeasonKey2)}
+	member := func(playerKey string) *TeamMembership {
+		return &TeamMembership{PlayerKey: decodeTestKey(t, playerKey)}
+	}
+	h := &TeamRosterHistory{
+		memberships: map[string][]*TeamMembership{
+			seasonKeys[0].Encode(): {member(testPlayerKey1), member(testPlayerKey2)},
+			// Bob left before the next season and Carol replaced him.
+			seasonKeys[1].Encode(): {member(testPlayerKey1), member(testPlayerKey3)},
+		},
+		seasons:    []*Season{{Start: start, End: end}, {Start: nextStart}},
+		seasonKeys: seasonKeys,
+		players: map[string]*Player{
+			testPlayerKey1: alice, testPlayerKey2: bob, testPlayerKey3: carol},
+	}
+
+	tests := []struct {
+		t    time.Time
+		want []*Player
+	}{
+		{start.AddDate(0, 0, -1), []*Player{alice, bob}},
+		{start, []*Player{alice, bob}},
+		{end.Add(-time.Second), []*Player{alice, bob}},
+		// Between the seasons.
+		{end, []*Player{alice, bob}},
+		{nextStart.Add(-time.Second), []*Player{alice, bob}},
+		{nextStart, []*Player{alice, carol}},
+		{nextStart.AddDate(1, 0, 0), []*Player{alice, carol}},
+	}
+	for _, test := range tests {
+		got := h.PlayersAt(test.t)
+		if len(got) != len(test.want) {
+			t.Errorf("%v: got %d player(s), want %d", test.t, len(got), len(test.want))
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%v: player %d: got %s, want %s",
+					test.t, i, got[i].Summoner, test.want[i].Summoner)
+			}
+		}
+	}
+
+	// Leagues without seasons have a single roster.
+	h = &TeamRosterHistory{
+		memberships: map[string][]*TeamMembership{"": {member(testPlayerKey2)}},
+		players:     map[string]*Player{testPlayerKey2: bob},
+	}
+	if got := h.PlayersAt(start); len(got) != 1 || got[0] != bob {
+		t.Errorf("without seasons: got %v, want [bob]", got)
+	}
+}
@@ -0,0 +1,191 @@
+package model
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Options for generating a round-robin schedule.
+type RoundRobinOptions struct {
+	// The start of the first round.
+	Start time.Time
+
+	// The time between the starts of consecutive rounds. Example: one week.
+	Cadence time.Duration
+
+	// How long after the start of its round a match may be played.
+	Window time.Duration
+
+	// If set, every pair of teams plays twice, once as each team's home match.
+	Double bool
+
+	// Copied to every generated match.
+	Summary             string
+	Description         string
+	NumGames            int
+	MapId               int
+	GameMode            string
+	OverrideTeamScoring bool
+	TeamScoring         TeamScoringType
+}
+
+// One round of a generated schedule. Not directly stored in datastore.
+type ScheduleRound struct {
+	// The PrimaryTag of the round's matches. Example: "Week 1"
+	Tag string
+
+	Matches []*ScheduledMatch
+
+	// The team without a match this round, if there is an odd number of teams.
+	Bye *datastore.Key
+}
+
+// Pairs n teams, numbered from 0, so every team plays every other team once per cycle.
+// Each pairing is {home, away}; a pairing with -1 is a bye for the other team.
+//
+// Uses the circle method. Every team's home and away counts differ by at most one, and
+// are equal in a double round robin, whose second cycle swaps home and away.
+func roundRobinPairings(n int, double bool) [][][2]int {
+	ids := make([]int, 0, n+1)
+	if n%2 == 1 {
+		// The bye stays fixed so that every team rotates.
+		ids = append(ids, -1)
+	}
+	for i := 0; i < n; i++ {
+		ids = append(ids, i)
+	}
+	m := len(ids)
+
+	var rounds [][][2]int
+	for r := 0; r < m-1; r++ {
+		round := make([][2]int, m/2)
+		for i := range round {
+			home, away := ids[i], ids[m-1-i]
+			if (i == 0 && r%2 == 1) || (i > 0 && i%2 == 1) {
+				home, away = away, home
+			}
+			round[i] = [2]int{home, away}
+		}
+		rounds = append(rounds, round)
+
+		// Rotate every position but the first.
+		last := ids[m-1]
+		copy(ids[2:], ids[1:m-1])
+		ids[1] = last
+	}
+
+	if double {
+		cycle := len(rounds)
+		for r := 0; r < cycle; r++ {
+			round := make([][2]int, len(rounds[r]))
+			for i, p := range rounds[r] {
+				round[i] = [2]int{p[1], p[0]}
+			}
+			rounds = append(rounds, round)
+		}
+	}
+	return rounds
+}
+
+// Generates a round-robin schedule between teamKeys. Nothing is written to datastore.
+func GenerateRoundRobin(
+	teamKeys []*datastore.Key,
+	opts *RoundRobinOptions) ([]*ScheduleRound, error) {
+	if len(teamKeys) < 2 {
+		return nil, errors.New("A schedule needs at least two teams")
+	}
+	if opts.Cadence <= 0 {
+		return nil, errors.New(fmt.Sprintf("Cadence must be positive: %v", opts.Cadence))
+	}
+	if opts.Window <= 0 {
+		return nil, errors.New(fmt.Sprintf("Window must be positive: %v", opts.Window))
+	}
+	if opts.NumGames < 0 {
+		return nil, errors.New(fmt.Sprintf(
+			"Number of games must be non-negative: %d", opts.NumGames))
+	}
+
+	pairings := roundRobinPairings(len(teamKeys), opts.Double)
+	rounds := make([]*ScheduleRound, len(pairings))
+	for r, pairs := range pairings {
+		start := opts.Start.Add(time.Duration(r) * opts.Cadence)
+		round := &ScheduleRound{Tag: fmt.Sprintf("Week %d", r+1)}
+		for _, p := range pairs {
+			if p[0] < 0 {
+				round.Bye = teamKeys[p[1]]
+				continue
+			}
+			if p[1] < 0 {
+				round.Bye = teamKeys[p[0]]
+				continue
+			}
+			round.Matches = append(round.Matches, &ScheduledMatch{
+				Summary:             opts.Summary,
+				Description:         opts.Description,
+				PrimaryTag:          round.Tag,
+				TeamKeys:            []*datastore.Key{teamKeys[p[0]], teamKeys[p[1]]},
+				NumGames:            opts.NumGames,
+				MapId:               opts.MapId,
+				GameMode:            opts.GameMode,
+				OfficialDatetime:    start,
+				DateEarliest:        start,
+				DateLatest:          start.Add(opts.Window),
+				OverrideTeamScoring: opts.OverrideTeamScoring,
+				TeamScoring:         opts.TeamScoring,
+			})
+		}
+		rounds[r] = round
+	}
+	return rounds, nil
+}
+
+// Generates a round-robin schedule between every team in a league within scope, ordered
+// by name. Returns the teams and their keys along with the schedule. Nothing is written
+// to datastore.
+func LeagueRoundRobin(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	scope *LeagueScope,
+	opts *RoundRobinOptions) ([]*ScheduleRound, []*Team, []*datastore.Key, error) {
+	teams, teamKeys, err := ScopeTeams(c, userAcls, league, leagueKey, scope)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sort.Sort(TeamsByName{teams, teamKeys})
+
+	rounds, err := GenerateRoundRobin(teamKeys, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if scope != nil {
+		for _, round := range rounds {
+			for _, match := range round.Matches {
+				match.Season = scope.SeasonKey
+			}
+		}
+	}
+	return rounds, teams, teamKeys, nil
+}
+
+// Creates every match of a generated schedule.
+func CreateSchedule(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	rounds []*ScheduleRound) error {
+	for _, round := range rounds {
+		for _, match := range round.Matches {
+			if err := CreateScheduledMatch(c, userAcls, league, leagueKey, match); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"appengine/datastore"
+	"testing"
+	"time"
+)
+
+func TestRoundRobinPairings(t *testing.T) {
+	for n := 2; n <= 11; n++ {
+		for _, double := range []bool{false, true} {
+			cycles := 1
+			if double {
+				cycles = 2
+			}
+			rounds := roundRobinPairings(n, double)
+			wantRounds := n - 1 + n%2
+			if len(rounds) != cycles*wantRounds {
+				t.Errorf("%d teams, double %v: got %d rounds, want %d",
+					n, double, len(rounds), cycles*wantRounds)
+				continue
+			}
+
+			home := make([]int, n)
+			away := make([]int, n)
+			byes := make([]int, n)
+			played := make(map[[2]int]int)
+			for r, round := range rounds {
+				seen := make(map[int]bool)
+				for _, p := range round {
+					for _, team := range p {
+						if team >= 0 && seen[team] {
+							t.Errorf("%d teams: team %d plays twice in round %d", n, team, r)
+						}
+						seen[team] = true
+					}
+					switch {
+					case p[0] < 0:
+						byes[p[1]]++
+					case p[1] < 0:
+						byes[p[0]]++
+					default:
+						home[p[0]]++
+						away[p[1]]++
+						played[p]++
+					}
+				}
+			}
+
+			for a := 0; a < n; a++ {
+				for b := a + 1; b < n; b++ {
+					if got := played[[2]int{a, b}] + played[[2]int{b, a}]; got != cycles {
+						t.Errorf("%d teams, double %v: %d and %d play %d times",
+							n, double, a, b, got)
+					}
+				}
+				if double && home[a] != away[a] {
+					t.Errorf("%d teams, double: team %d is %d-%d home-away", n, a, home[a], away[a])
+				}
+				if diff := home[a] - away[a]; diff > 1 || diff < -1 {
+					t.Errorf("%d teams: team %d is %d-%d home-away", n, a, home[a], away[a])
+				}
+				if byes[a] != cycles*(n%2) {
+					t.Errorf("%d teams, double %v: team %d has %d byes", n, double, a, byes[a])
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateRoundRobin(t *testing.T) {
+	teamKeys := make([]*datastore.Key, 3)
+	start := time.Date(2015, 1, 5, 19, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+	rounds, err := GenerateRoundRobin(teamKeys, &RoundRobinOptions{
+		Start:    start,
+		Cadence:  week,
+		Window:   2 * 24 * time.Hour,
+		NumGames: 3,
+		Summary:  "Best of 3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rounds) != 3 {
+		t.Fatalf("got %d rounds, want 3", len(rounds))
+	}
+	for r, round := range rounds {
+		wantStart := start.Add(time.Duration(r) * week)
+		if len(round.Matches) != 1 {
+			t.Fatalf("round %d: got %d matches, want 1", r, len(round.Matches))
+		}
+		m := round.Matches[0]
+		if m.PrimaryTag != round.Tag || !m.DateEarliest.Equal(wantStart) ||
+			!m.DateLatest.Equal(wantStart.Add(2*24*time.Hour)) ||
+			m.NumGames != 3 || m.Summary != "Best of 3" {
+			t.Errorf("round %d: got %+v", r, m)
+		}
+	}
+	if rounds[1].Tag != "Week 2" {
+		t.Errorf("got tag %q, want \"Week 2\"", rounds[1].Tag)
+	}
+
+	if _, err := GenerateRoundRobin(teamKeys[:1], &RoundRobinOptions{Cadence: week, Window: week}); err == nil {
+		t.Errorf("generated a schedule for one team")
+	}
+	if _, err := GenerateRoundRobin(teamKeys, &RoundRobinOptions{Window: week}); err == nil {
+		t.Errorf("generated a schedule without a cadence")
+	}
+}
@@ -1,5 +1,10 @@
 package model
 
+import (
+	"errors"
+	"fmt"
+)
+
 type Score struct {
 	// The number of points earned.
 	Points int
@@ -9,6 +14,8 @@ type Score struct {
 
 // A TeamScoring is used to take a chronological series of games and compute how many points
 // a team has earned for those results. Results may be negative.
+//
+// The team being scored is GameInfo.ThisTeam of each game.
 type TeamScoring interface {
 	Score(match *ScheduledMatch, games []*GameInfo) Score
 }
@@ -26,6 +33,10 @@ type PlayerScoring interface {
 //   G := The list of games played,
 //   S := The first M games of G,
 //   T := The team of interest.
+//
+// A game in S is won by T if T is the winner and lost by T if the other team is the winner.
+// Games without a known winner are neither. If no games have been played in a match of
+// unbounded length, the score is 0 points and not final.
 type TeamScoringType int
 
 const (
@@ -53,3 +64,124 @@ const (
 	// false: otherwise
 	ThreeOneZeroSeries TeamScoringType = 1
 )
+
+var teamScorings = map[TeamScoringType]TeamScoring{
+	BestOfSeries:       bestOfSeries{},
+	ThreeOneZeroSeries: threeOneZeroSeries{},
+}
+
+// Names of the TeamScoringTypes as used in forms.
+var teamScoringNames = map[TeamScoringType]string{
+	BestOfSeries:       "best-of",
+	ThreeOneZeroSeries: "three-one-zero",
+}
+
+func (t TeamScoringType) String() string {
+	if name, ok := teamScoringNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TeamScoringType(%d)", int(t))
+}
+
+func ParseTeamScoringType(name string) (TeamScoringType, error) {
+	for t, n := range teamScoringNames {
+		if n == name {
+			return t, nil
+		}
+	}
+	return 0, errors.New(fmt.Sprintf("unknown team scoring: '%s'", name))
+}
+
+// Returns the TeamScoring implementing a TeamScoringType.
+func TeamScoringFor(t TeamScoringType) (TeamScoring, error) {
+	scoring, ok := teamScorings[t]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("no team scoring for %v", t))
+	}
+	return scoring, nil
+}
+
+// The results of the games of a series from the point of view of T.
+type seriesResult struct {
+	// M, and the number of games in G.
+	numGames int
+	played   int
+
+	// Games in S won and lost by T.
+	wins   int
+	losses int
+}
+
+func newSeriesResult(match *ScheduledMatch, games []*GameInfo) *seriesResult {
+	r := &seriesResult{numGames: match.NumGames, played: len(games)}
+	if r.numGames <= 0 {
+		r.numGames = r.played
+	}
+	for i, game := range games {
+		if i >= r.numGames {
+			break
+		}
+		if game.ThisTeam.IsWinner {
+			r.wins++
+		} else if game.OtherTeam.IsWinner {
+			r.losses++
+		}
+	}
+	return r
+}
+
+func (r *seriesResult) wonMajority() bool {
+	return 2*r.wins > r.numGames
+}
+func (r *seriesResult) lostMajority() bool {
+	return 2*r.losses > r.numGames
+}
+func (r *seriesResult) tied() bool {
+	return r.numGames%2 == 0 && 2*r.wins == r.numGames
+}
+func (r *seriesResult) complete() bool {
+	return r.played >= r.numGames
+}
+
+// Returns the number of games in S won and lost by T, where the games are viewed by T as
+// for TeamScoring.
+func SeriesGames(match *ScheduledMatch, games []*GameInfo) (won int, lost int) {
+	r := newSeriesResult(match, games)
+	return r.wins, r.losses
+}
+
+// Implements BestOfSeries.
+type bestOfSeries struct{}
+
+func (bestOfSeries) Score(match *ScheduledMatch, games []*GameInfo) Score {
+	r := newSeriesResult(match, games)
+	if r.numGames == 0 {
+		return Score{}
+	}
+	var score Score
+	if r.wonMajority() {
+		score.Points = 2
+	} else if r.tied() {
+		score.Points = 1
+	}
+	score.IsFinal = r.complete() || r.wonMajority() || r.lostMajority()
+	return score
+}
+
+// Implements ThreeOneZeroSeries.
+type threeOneZeroSeries struct{}
+
+func (threeOneZeroSeries) Score(match *ScheduledMatch, games []*GameInfo) Score {
+	r := newSeriesResult(match, games)
+	if r.numGames == 0 {
+		return Score{}
+	}
+	var score Score
+	if r.wonMajority() {
+		score.Points = 3
+	} else if r.tied() {
+		score.Points = 1
+	}
+	score.IsFinal = r.complete()
+	return score
+}
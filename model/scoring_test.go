@@ -0,0 +1,115 @@
+package model
+
+import (
+	"testing"
+)
+
+// Builds games from T's point of view: 'W' is a win, 'L' a loss and '?' a game without a
+// known winner.
+func seriesGames(results string) []*GameInfo {
+	games := make([]*GameInfo, len(results))
+	for i, r := range results {
+		games[i] = NewGameInfo()
+		games[i].ThisTeam.IsWinner = r == 'W'
+		games[i].OtherTeam.IsWinner = r == 'L'
+	}
+	return games
+}
+
+var seriesTests = []struct {
+	numGames     int
+	results      string
+	bestOf       Score
+	threeOneZero Score
+}{
+	// Best of 1.
+	{1, "", Score{0, false}, Score{0, false}},
+	{1, "W", Score{2, true}, Score{3, true}},
+	{1, "L", Score{0, true}, Score{0, true}},
+	{1, "?", Score{0, true}, Score{0, true}},
+	{1, "LW", Score{0, true}, Score{0, true}},
+
+	// Two games, where ties are possible.
+	{2, "", Score{0, false}, Score{0, false}},
+	{2, "W", Score{1, false}, Score{1, false}},
+	{2, "L", Score{0, false}, Score{0, false}},
+	{2, "WW", Score{2, true}, Score{3, true}},
+	{2, "WL", Score{1, true}, Score{1, true}},
+	{2, "LW", Score{1, true}, Score{1, true}},
+	{2, "LL", Score{0, true}, Score{0, true}},
+	{2, "W?", Score{1, true}, Score{1, true}},
+	{2, "WLW", Score{1, true}, Score{1, true}},
+
+	// Best of 3.
+	{3, "", Score{0, false}, Score{0, false}},
+	{3, "W", Score{0, false}, Score{0, false}},
+	{3, "L", Score{0, false}, Score{0, false}},
+	{3, "WL", Score{0, false}, Score{0, false}},
+	{3, "W?", Score{0, false}, Score{0, false}},
+	{3, "WW", Score{2, true}, Score{3, false}},
+	{3, "LL", Score{0, true}, Score{0, false}},
+	{3, "WLW", Score{2, true}, Score{3, true}},
+	{3, "LWL", Score{0, true}, Score{0, true}},
+	{3, "WWL", Score{2, true}, Score{3, true}},
+	{3, "W??", Score{0, true}, Score{0, true}},
+	{3, "LLWW", Score{0, true}, Score{0, true}},
+
+	// Best of 5.
+	{5, "WWW", Score{2, true}, Score{3, false}},
+	{5, "WWLL", Score{0, false}, Score{0, false}},
+	{5, "LLL", Score{0, true}, Score{0, false}},
+	{5, "WWLLW", Score{2, true}, Score{3, true}},
+	{5, "WWLLL", Score{0, true}, Score{0, true}},
+
+	// Unbounded: M is the number of games played.
+	{0, "", Score{0, false}, Score{0, false}},
+	{0, "W", Score{2, true}, Score{3, true}},
+	{0, "L", Score{0, true}, Score{0, true}},
+	{0, "WL", Score{1, true}, Score{1, true}},
+	{0, "WLL", Score{0, true}, Score{0, true}},
+	{0, "WLWL", Score{1, true}, Score{1, true}},
+	{-1, "W", Score{2, true}, Score{3, true}},
+}
+
+func TestBestOfSeries(t *testing.T) {
+	scoring, err := TeamScoringFor(BestOfSeries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range seriesTests {
+		match := &ScheduledMatch{NumGames: test.numGames}
+		if got := scoring.Score(match, seriesGames(test.results)); got != test.bestOf {
+			t.Errorf("%d games, %q: got %+v, want %+v",
+				test.numGames, test.results, got, test.bestOf)
+		}
+	}
+}
+
+func TestThreeOneZeroSeries(t *testing.T) {
+	scoring, err := TeamScoringFor(ThreeOneZeroSeries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range seriesTests {
+		match := &ScheduledMatch{NumGames: test.numGames}
+		if got := scoring.Score(match, seriesGames(test.results)); got != test.threeOneZero {
+			t.Errorf("%d games, %q: got %+v, want %+v",
+				test.numGames, test.results, got, test.threeOneZero)
+		}
+	}
+}
+
+func TestTeamScoringTypeNames(t *testing.T) {
+	for _, scoringType := range []TeamScoringType{BestOfSeries, ThreeOneZeroSeries} {
+		parsed, err := ParseTeamScoringType(scoringType.String())
+		if err != nil || parsed != scoringType {
+			t.Errorf("%v: got %v, %v", scoringType, parsed, err)
+		}
+	}
+	if _, err := ParseTeamScoringType("best-of-7"); err == nil {
+		t.Errorf("parsed an unknown scoring")
+	}
+	if _, err := TeamScoringFor(TeamScoringType(-1)); err == nil {
+		t.Errorf("got a scoring for an unknown type")
+	}
+}
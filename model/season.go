@@ -0,0 +1,425 @@
+package model
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"errors"
+	"fmt"
+	"github.com/OwenDurni/loltools/util/errwrap"
+	"time"
+)
+
+// A season of a league. Matches, rosters and the teams taking part belong to a season.
+//
+// Ancestor: League
+type Season struct {
+	Name  string
+	Start time.Time
+
+	// Zero while the season has no end.
+	End time.Time
+
+	Created time.Time
+
+	// The season this one rolled over. Nil for a league's first season.
+	Previous *datastore.Key
+
+	// Set until a task has carried the league's teams, matches and rosters into the
+	// season, in batches recorded by MigrationStep and MigrationCursor.
+	Migrating       bool
+	MigrationStep   int    `datastore:",noindex"`
+	MigrationCursor string `datastore:",noindex"`
+}
+
+func SeasonId(seasonKey *datastore.Key) string {
+	return EncodeKeyShort(seasonKey)
+}
+
+// Returns whether t is within the season.
+func (s *Season) ContainsTime(t time.Time) bool {
+	return !t.Before(s.Start) && (s.End.IsZero() || t.Before(s.End))
+}
+
+// A group of teams within a season. Teams in a division have their own standings and
+// schedule.
+//
+// Ancestor: League
+type Division struct {
+	Season *datastore.Key
+	Name   string
+}
+
+func DivisionId(divisionKey *datastore.Key) string {
+	return EncodeKeyShort(divisionKey)
+}
+
+// A team taking part in a season.
+//
+// The key is KeyForSeasonTeam.
+//
+// Ancestor: League
+type SeasonTeam struct {
+	Season  *datastore.Key
+	TeamKey *datastore.Key
+
+	// Nil if the team is not in a division.
+	Division *datastore.Key
+}
+
+func KeyForSeasonTeam(
+	c appengine.Context, seasonKey *datastore.Key, teamKey *datastore.Key) *datastore.Key {
+	return datastore.NewKey(
+		c, "SeasonTeam", fmt.Sprintf("%s/%s", SeasonId(seasonKey), EncodeKeyShort(teamKey)),
+		0, seasonKey.Parent())
+}
+
+// Restricts standings, schedules and game history to a season, and optionally one of its
+// divisions. A nil *LeagueScope is the whole league.
+type LeagueScope struct {
+	Season    *Season
+	SeasonKey *datastore.Key
+
+	// Nil for the whole season.
+	Division    *Division
+	DivisionKey *datastore.Key
+
+	// The encoded keys of the teams in the season or division.
+	teamIds map[string]bool
+}
+
+// Returns whether a team takes part in the season or division.
+func (s *LeagueScope) HasTeam(teamKey *datastore.Key) bool {
+	return s == nil || s.teamIds[teamKey.Encode()]
+}
+
+// Returns whether a game played at t belongs to the scope.
+func (s *LeagueScope) ContainsTime(t time.Time) bool {
+	return s == nil || s.Season.ContainsTime(t)
+}
+
+// Returns whether a match belongs to the scope: it is part of the season and, for a
+// division, one of its teams is in the division.
+func (s *LeagueScope) HasMatch(match *ScheduledMatch) bool {
+	if s == nil {
+		return true
+	}
+	if match.Season == nil || !match.Season.Equal(s.SeasonKey) {
+		return false
+	}
+	if s.DivisionKey == nil {
+		return true
+	}
+	for _, teamKey := range match.TeamKeys {
+		if s.HasTeam(teamKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the scope of a season and division of a league.
+//
+// An empty seasonId is the league's current season, or the whole league if it has no
+// seasons; "all" is the whole league. An empty divisionId is the whole season.
+func LeagueScopeById(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	seasonId string,
+	divisionId string) (*LeagueScope, error) {
+	if err := canViewLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, err
+	}
+
+	scope := &LeagueScope{SeasonKey: league.CurrentSeason}
+	if seasonId == "all" || (seasonId == "" && league.CurrentSeason == nil) {
+		return nil, nil
+	} else if seasonId != "" {
+		var err error
+		scope.SeasonKey, err = DecodeKeyShort(c, "Season", seasonId, leagueKey)
+		if err != nil {
+			return nil, errwrap.Wrap(err)
+		}
+	}
+	scope.Season = new(Season)
+	if err := datastore.Get(c, scope.SeasonKey, scope.Season); err != nil {
+		return nil, errwrap.Wrap(err)
+	}
+
+	if divisionId != "" {
+		var err error
+		scope.DivisionKey, err = DecodeKeyShort(c, "Division", divisionId, leagueKey)
+		if err != nil {
+			return nil, errwrap.Wrap(err)
+		}
+		scope.Division = new(Division)
+		if err := datastore.Get(c, scope.DivisionKey, scope.Division); err != nil {
+			return nil, errwrap.Wrap(err)
+		}
+		if !scope.Division.Season.Equal(scope.SeasonKey) {
+			return nil, errors.New(fmt.Sprintf(
+				"Division '%s' is not part of season '%s'", scope.Division.Name,
+				scope.Season.Name))
+		}
+	}
+
+	seasonTeams, err := getSeasonTeams(c, scope.SeasonKey)
+	if err != nil {
+		return nil, err
+	}
+	scope.teamIds = make(map[string]bool)
+	for _, t := range seasonTeams {
+		if scope.DivisionKey == nil ||
+			(t.Division != nil && t.Division.Equal(scope.DivisionKey)) {
+			scope.teamIds[t.TeamKey.Encode()] = true
+		}
+	}
+	return scope, nil
+}
+
+// Returns the teams of a league within scope.
+func ScopeTeams(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	scope *LeagueScope) ([]*Team, []*datastore.Key, error) {
+	teams, teamKeys, err := LeagueAllTeams(c, userAcls, league, leagueKey)
+	if err != nil || scope == nil {
+		return teams, teamKeys, err
+	}
+	var scopeTeams []*Team
+	var scopeTeamKeys []*datastore.Key
+	for i, k := range teamKeys {
+		if scope.HasTeam(k) {
+			scopeTeams = append(scopeTeams, teams[i])
+			scopeTeamKeys = append(scopeTeamKeys, k)
+		}
+	}
+	return scopeTeams, scopeTeamKeys, nil
+}
+
+func getSeasonTeams(c appengine.Context, seasonKey *datastore.Key) ([]*SeasonTeam, error) {
+	var seasonTeams []*SeasonTeam
+	q := datastore.NewQuery("SeasonTeam").Ancestor(seasonKey.Parent()).
+		Filter("Season =", seasonKey)
+	if _, err := q.GetAll(c, &seasonTeams); err != nil {
+		return nil, errwrap.Wrap(err)
+	}
+	return seasonTeams, nil
+}
+
+func getSeasonDivisions(
+	c appengine.Context, seasonKey *datastore.Key) ([]*Division, []*datastore.Key, error) {
+	var divisions []*Division
+	q := datastore.NewQuery("Division").Ancestor(seasonKey.Parent()).
+		Filter("Season =", seasonKey).
+		Order("Name")
+	keys, err := q.GetAll(c, &divisions)
+	if err != nil {
+		return nil, nil, errwrap.Wrap(err)
+	}
+	return divisions, keys, nil
+}
+
+// Starts a new season of a league and makes it the current season.
+//
+// The league's first season adopts every existing team, match and roster. Later seasons
+// roll the current season over: its teams, divisions and rosters carry forward, and it
+// ends when the new season starts. The teams, matches and rosters are carried over by
+// a task after the season starts; see MigrateSeasonBatch.
+func StartSeason(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	season *Season) (*datastore.Key, error) {
+	if err := canEditLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, err
+	}
+	if season.Name == "" {
+		return nil, errors.New("A season needs a name")
+	}
+	if !season.End.IsZero() && !season.End.After(season.Start) {
+		return nil, errors.New("A season must end after it starts")
+	}
+	season.Created = time.Now()
+
+	var seasonKey *datastore.Key
+	err := datastore.RunInTransaction(c, func(c appengine.Context) error {
+		league := new(League)
+		if err := datastore.Get(c, leagueKey, league); err != nil {
+			return errwrap.Wrap(err)
+		}
+		if league.CurrentSeason != nil {
+			if err := endSeason(c, league.CurrentSeason, season); err != nil {
+				return err
+			}
+		}
+		season.Previous = league.CurrentSeason
+		season.Migrating = true
+		var err error
+		seasonKey, err = datastore.Put(
+			c, datastore.NewIncompleteKey(c, "Season", leagueKey), season)
+		if err != nil {
+			return errwrap.Wrap(err)
+		}
+		league.CurrentSeason = seasonKey
+		if _, err := datastore.Put(c, leagueKey, league); err != nil {
+			return errwrap.Wrap(err)
+		}
+		return queueSeasonMigration(c, seasonKey)
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return seasonKey, nil
+}
+
+// Ends a league's current season when the next one starts. Runs within a transaction.
+func endSeason(c appengine.Context, fromKey *datastore.Key, to *Season) error {
+	from := new(Season)
+	if err := datastore.Get(c, fromKey, from); err != nil {
+		return errwrap.Wrap(err)
+	}
+	if from.Migrating {
+		return errors.New(fmt.Sprintf(
+			"The current season, %s, is still being set up; try again shortly", from.Name))
+	}
+	if !to.Start.After(from.Start) {
+		return errors.New(fmt.Sprintf(
+			"The new season must start after the current season, %s", from.Name))
+	}
+	if from.End.IsZero() || from.End.After(to.Start) {
+		from.End = to.Start
+		if _, err := datastore.Put(c, fromKey, from); err != nil {
+			return errwrap.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// Creates a division in a season.
+func CreateDivision(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	seasonKey *datastore.Key,
+	name string) (*datastore.Key, error) {
+	if err := canEditLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, errors.New("A division needs a name")
+	}
+
+	var divisionKey *datastore.Key
+	err := datastore.RunInTransaction(c, func(c appengine.Context) error {
+		q := datastore.NewQuery("Division").Ancestor(leagueKey).
+			Filter("Season =", seasonKey).
+			Filter("Name =", name).
+			KeysOnly()
+		keys, err := q.GetAll(c, nil)
+		if err != nil {
+			return errwrap.Wrap(err)
+		}
+		if len(keys) > 0 {
+			return errors.New(fmt.Sprintf("Division already exists: %s", name))
+		}
+		division := &Division{Season: seasonKey, Name: name}
+		divisionKey, err = datastore.Put(
+			c, datastore.NewIncompleteKey(c, "Division", leagueKey), division)
+		return errwrap.Wrap(err)
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return divisionKey, nil
+}
+
+// Adds a team to a season, in a division unless divisionKey is nil, or removes it from
+// the season if remove is set.
+func SetSeasonTeam(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	seasonKey *datastore.Key,
+	teamKey *datastore.Key,
+	divisionKey *datastore.Key,
+	remove bool) error {
+	if err := canEditLeague(c, userAcls, league, leagueKey); err != nil {
+		return err
+	}
+	key := KeyForSeasonTeam(c, seasonKey, teamKey)
+	if remove {
+		return errwrap.Wrap(datastore.Delete(c, key))
+	}
+
+	if divisionKey != nil {
+		division := new(Division)
+		if err := datastore.Get(c, divisionKey, division); err != nil {
+			return errwrap.Wrap(err)
+		}
+		if !division.Season.Equal(seasonKey) {
+			return errors.New(fmt.Sprintf(
+				"Division '%s' is not part of the season", division.Name))
+		}
+	}
+	seasonTeam := &SeasonTeam{Season: seasonKey, TeamKey: teamKey, Division: divisionKey}
+	_, err := datastore.Put(c, key, seasonTeam)
+	return errwrap.Wrap(err)
+}
+
+// Returns every season of a league, ordered by start.
+func LeagueSeasons(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key) ([]*Season, []*datastore.Key, error) {
+	if err := canViewLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, nil, err
+	}
+
+	var seasons []*Season
+	keys, err := datastore.NewQuery("Season").Ancestor(leagueKey).Order("Start").
+		GetAll(c, &seasons)
+	if err != nil {
+		return nil, nil, errwrap.Wrap(err)
+	}
+	return seasons, keys, nil
+}
+
+// Returns the divisions of a season ordered by name, and the teams taking part in it.
+func SeasonDivisionsAndTeams(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	seasonKey *datastore.Key) ([]*Division, []*datastore.Key, []*SeasonTeam, error) {
+	if err := canViewLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, nil, nil, err
+	}
+	divisions, divisionKeys, err := getSeasonDivisions(c, seasonKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	seasonTeams, err := getSeasonTeams(c, seasonKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return divisions, divisionKeys, seasonTeams, nil
+}
+
+// Adds a team to the league's current season, if any. Runs within a transaction.
+func addTeamToCurrentSeason(
+	c appengine.Context, league *League, teamKey *datastore.Key) error {
+	if league.CurrentSeason == nil {
+		return nil
+	}
+	seasonTeam := &SeasonTeam{Season: league.CurrentSeason, TeamKey: teamKey}
+	_, err := datastore.Put(c, KeyForSeasonTeam(c, league.CurrentSeason, teamKey), seasonTeam)
+	return errwrap.Wrap(err)
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"appengine/datastore"
+	"testing"
+	"time"
+)
+
+func TestSeasonContainsTime(t *testing.T) {
+	start := time.Date(2015, 1, 5, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 2, 0)
+	tests := []struct {
+		season *Season
+		t      time.Time
+		want   bool
+	}{
+		{&Season{Start: start, End: end}, start, true},
+		{&Season{Start: start, End: end}, start.Add(-time.Second), false},
+		{&Season{Start: start, End: end}, end.Add(-time.Second), true},
+		{&Season{Start: start, End: end}, end, false},
+		{&Season{Start: start}, start.AddDate(5, 0, 0), true},
+		{&Season{Start: start}, start.AddDate(0, 0, -1), false},
+	}
+	for _, test := range tests {
+		if got := test.season.ContainsTime(test.t); got != test.want {
+			t.Errorf("%v to %v contains %v: got %v, want %v",
+				test.season.Start, test.season.End, test.t, got, test.want)
+		}
+	}
+}
+
+func TestNilLeagueScope(t *testing.T) {
+	var scope *LeagueScope
+	if !scope.HasTeam(nil) || !scope.ContainsTime(time.Time{}) ||
+		!scope.HasMatch(&ScheduledMatch{}) {
+		t.Errorf("the whole league must include every team, game and match")
+	}
+}
+
+func TestLeagueScopeHasMatch(t *testing.T) {
+	season := &LeagueScope{SeasonKey: decodeTestKey(t, testSeasonKey1)}
+	match := &ScheduledMatch{
+		Season: decodeTestKey(t, testSeasonKey1),
+		TeamKeys: []*datastore.Key{
+			decodeTestKey(t, testTeamKey1), decodeTestKey(t, testTeamKey2)},
+	}
+	if !season.HasMatch(match) {
+		t.Errorf("a season must include its matches")
+	}
+	if season.HasMatch(&ScheduledMatch{Season: decodeTestKey(t, testSeasonKey2)}) {
+		t.Errorf("a season must not include other seasons' matches")
+	}
+	if season.HasMatch(&ScheduledMatch{}) {
+		t.Errorf("a season must not include matches without a season")
+	}
+
+	division := &LeagueScope{
+		SeasonKey:   decodeTestKey(t, testSeasonKey1),
+		DivisionKey: new(datastore.Key),
+		teamIds:     map[string]bool{testTeamKey2: true},
+	}
+	if !division.HasMatch(match) {
+		t.Errorf("a division must include matches of its teams")
+	}
+	division.teamIds = nil
+	if division.HasMatch(match) {
+		t.Errorf("a division must not include matches without its teams")
+	}
+}
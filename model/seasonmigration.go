@@ -0,0 +1,351 @@
+package model
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"appengine/taskqueue"
+	"github.com/OwenDurni/loltools/util/errwrap"
+	"net/url"
+)
+
+// The number of entities each batch of a season migration reads, so that its writes stay
+// well under the datastore's limit of 500 entities per call and per transaction.
+const seasonMigrationBatchSize = 200
+
+// A step of carrying a league's teams, matches and rosters into a new season. Each call
+// migrates the page of entities after cursor and returns the cursor of the next page, or
+// "" once the step is done. Runs within a transaction.
+type seasonMigrationStep func(
+	c appengine.Context,
+	seasonKey *datastore.Key,
+	season *Season,
+	cursor string) (string, error)
+
+// The league's first season adopts every existing team, match and roster.
+var adoptLeagueSteps = []seasonMigrationStep{
+	adoptTeams,
+	adoptMatches,
+	adoptMemberships,
+}
+
+// Later seasons carry the teams, divisions and rosters of the previous season forward.
+var rollOverSeasonSteps = []seasonMigrationStep{
+	rollOverDivisions,
+	rollOverSeasonTeams,
+	rollOverMemberships,
+}
+
+// Queues the next batch of the migration of a season. Called within a transaction, so
+// the batch only runs once the transaction commits.
+func queueSeasonMigration(c appengine.Context, seasonKey *datastore.Key) error {
+	args := &url.Values{}
+	args.Add("league", EncodeKeyShort(seasonKey.Parent()))
+	args.Add("season", SeasonId(seasonKey))
+	_, err := taskqueue.Add(c, taskqueue.NewPOSTTask("/task/seasons/migrate", *args), "")
+	return errwrap.Wrap(err)
+}
+
+// Runs the next batch of the migration of a season started by StartSeason and queues the
+// batch after it. The season records its progress in the same transaction, so a retried
+// batch never runs twice. Returns whether batches remain.
+func MigrateSeasonBatch(c appengine.Context, seasonKey *datastore.Key) (bool, error) {
+	more := false
+	err := datastore.RunInTransaction(c, func(c appengine.Context) error {
+		season := new(Season)
+		if err := datastore.Get(c, seasonKey, season); err != nil {
+			return errwrap.Wrap(err)
+		}
+		if !season.Migrating {
+			return nil
+		}
+		steps := adoptLeagueSteps
+		if season.Previous != nil {
+			steps = rollOverSeasonSteps
+		}
+
+		next, err := steps[season.MigrationStep](c, seasonKey, season, season.MigrationCursor)
+		if err != nil {
+			return err
+		}
+		season.MigrationCursor = next
+		if next == "" {
+			season.MigrationStep++
+		}
+		more = season.MigrationStep < len(steps)
+		if more {
+			if err := queueSeasonMigration(c, seasonKey); err != nil {
+				return err
+			}
+		} else {
+			season.Migrating = false
+			season.MigrationStep = 0
+		}
+		_, err = datastore.Put(c, seasonKey, season)
+		return errwrap.Wrap(err)
+	}, nil)
+	return more, err
+}
+
+// Calls next for each entity of the page of q after cursor. next loads the entity with
+// it.Next. Returns the cursor of the following page, or "" if there is none.
+func migrationPage(
+	c appengine.Context,
+	q *datastore.Query,
+	cursor string,
+	next func(it *datastore.Iterator) error) (string, error) {
+	if cursor != "" {
+		start, err := datastore.DecodeCursor(cursor)
+		if err != nil {
+			return "", errwrap.Wrap(err)
+		}
+		q = q.Start(start)
+	}
+	it := q.Run(c)
+	for i := 0; i < seasonMigrationBatchSize; i++ {
+		err := next(it)
+		if err == datastore.Done {
+			return "", nil
+		} else if err != nil {
+			return "", errwrap.Wrap(err)
+		}
+	}
+	end, err := it.Cursor()
+	if err != nil {
+		return "", errwrap.Wrap(err)
+	}
+	return end.String(), nil
+}
+
+func putMigrated(c appengine.Context, keys []*datastore.Key, src interface{}) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := datastore.PutMulti(c, keys, src)
+	return errwrap.Wrap(err)
+}
+
+func adoptTeams(
+	c appengine.Context,
+	seasonKey *datastore.Key,
+	season *Season,
+	cursor string) (string, error) {
+	var seasonTeams []*SeasonTeam
+	var keys []*datastore.Key
+	q := datastore.NewQuery("Team").Ancestor(seasonKey.Parent()).KeysOnly()
+	next, err := migrationPage(c, q, cursor, func(it *datastore.Iterator) error {
+		teamKey, err := it.Next(nil)
+		if err != nil {
+			return err
+		}
+		seasonTeams = append(seasonTeams, &SeasonTeam{Season: seasonKey, TeamKey: teamKey})
+		keys = append(keys, KeyForSeasonTeam(c, seasonKey, teamKey))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return next, putMigrated(c, keys, seasonTeams)
+}
+
+func adoptMatches(
+	c appengine.Context,
+	seasonKey *datastore.Key,
+	season *Season,
+	cursor string) (string, error) {
+	var matches []*ScheduledMatch
+	var keys []*datastore.Key
+	q := datastore.NewQuery("ScheduledMatch").Ancestor(seasonKey.Parent())
+	next, err := migrationPage(c, q, cursor, func(it *datastore.Iterator) error {
+		match := new(ScheduledMatch)
+		key, err := it.Next(match)
+		if err != nil {
+			return err
+		}
+		match.Season = seasonKey
+		matches = append(matches, match)
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return next, putMigrated(c, keys, matches)
+}
+
+func adoptMemberships(
+	c appengine.Context,
+	seasonKey *datastore.Key,
+	season *Season,
+	cursor string) (string, error) {
+	var memberships []*TeamMembership
+	var keys []*datastore.Key
+	q := datastore.NewQuery("TeamMembership").Ancestor(seasonKey.Parent())
+	next, err := migrationPage(c, q, cursor, func(it *datastore.Iterator) error {
+		m := new(TeamMembership)
+		key, err := it.Next(m)
+		if err != nil {
+			return err
+		}
+		m.Season = seasonKey
+		memberships = append(memberships, m)
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return next, putMigrated(c, keys, memberships)
+}
+
+// Copies the divisions of the previous season. Divisions created in the new season since
+// it started are kept instead of their namesakes.
+func rollOverDivisions(
+	c appengine.Context,
+	seasonKey *datastore.Key,
+	season *Season,
+	cursor string) (string, error) {
+	existing, _, err := getSeasonDivisions(c, seasonKey)
+	if err != nil {
+		return "", err
+	}
+	names := make(map[string]bool)
+	for _, d := range existing {
+		names[d.Name] = true
+	}
+
+	var divisions []*Division
+	var keys []*datastore.Key
+	q := datastore.NewQuery("Division").Ancestor(seasonKey.Parent()).
+		Filter("Season =", season.Previous).
+		Order("Name")
+	next, err := migrationPage(c, q, cursor, func(it *datastore.Iterator) error {
+		d := new(Division)
+		if _, err := it.Next(d); err != nil {
+			return err
+		}
+		if !names[d.Name] {
+			d.Season = seasonKey
+			divisions = append(divisions, d)
+			keys = append(keys, datastore.NewIncompleteKey(c, "Division", seasonKey.Parent()))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return next, putMigrated(c, keys, divisions)
+}
+
+// Copies the teams of the previous season into the new season's divisions of the same
+// name. Teams already placed in the new season are left alone.
+func rollOverSeasonTeams(
+	c appengine.Context,
+	seasonKey *datastore.Key,
+	season *Season,
+	cursor string) (string, error) {
+	oldDivisions, oldDivisionKeys, err := getSeasonDivisions(c, season.Previous)
+	if err != nil {
+		return "", err
+	}
+	newDivisions, newDivisionKeys, err := getSeasonDivisions(c, seasonKey)
+	if err != nil {
+		return "", err
+	}
+	byName := make(map[string]*datastore.Key)
+	for i, d := range newDivisions {
+		byName[d.Name] = newDivisionKeys[i]
+	}
+	// New divisions by the encoded key of the old division.
+	divisionMap := make(map[string]*datastore.Key)
+	for i, d := range oldDivisions {
+		divisionMap[oldDivisionKeys[i].Encode()] = byName[d.Name]
+	}
+
+	var seasonTeams []*SeasonTeam
+	var keys []*datastore.Key
+	q := datastore.NewQuery("SeasonTeam").Ancestor(seasonKey.Parent()).
+		Filter("Season =", season.Previous)
+	next, err := migrationPage(c, q, cursor, func(it *datastore.Iterator) error {
+		t := new(SeasonTeam)
+		if _, err := it.Next(t); err != nil {
+			return err
+		}
+		t.Season = seasonKey
+		if t.Division != nil {
+			t.Division = divisionMap[t.Division.Encode()]
+		}
+		seasonTeams = append(seasonTeams, t)
+		keys = append(keys, KeyForSeasonTeam(c, seasonKey, t.TeamKey))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	existing := make([]*SeasonTeam, len(keys))
+	for i := range existing {
+		existing[i] = new(SeasonTeam)
+	}
+	err = datastore.GetMulti(c, keys, existing)
+	me, _ := err.(appengine.MultiError)
+	if err != nil && me == nil {
+		return "", errwrap.Wrap(err)
+	}
+	var missing []*SeasonTeam
+	var missingKeys []*datastore.Key
+	for i := range keys {
+		if me == nil || me[i] == nil {
+			continue
+		} else if me[i] != datastore.ErrNoSuchEntity {
+			return "", errwrap.Wrap(me[i])
+		}
+		missing = append(missing, seasonTeams[i])
+		missingKeys = append(missingKeys, keys[i])
+	}
+	return next, putMigrated(c, missingKeys, missing)
+}
+
+// Copies the current members of the previous season's rosters. Players added to a team
+// in the new season since it started are not copied again.
+func rollOverMemberships(
+	c appengine.Context,
+	seasonKey *datastore.Key,
+	season *Season,
+	cursor string) (string, error) {
+	var existing []*TeamMembership
+	q := datastore.NewQuery("TeamMembership").Ancestor(seasonKey.Parent()).
+		Filter("Season =", seasonKey)
+	if _, err := q.GetAll(c, &existing); err != nil {
+		return "", errwrap.Wrap(err)
+	}
+	// By the encoded team and player keys.
+	onRoster := make(map[string]bool)
+	for _, m := range existing {
+		if m.IsCurrent() {
+			onRoster[m.TeamKey.Encode()+"/"+m.PlayerKey.Encode()] = true
+		}
+	}
+
+	var memberships []*TeamMembership
+	var keys []*datastore.Key
+	q = datastore.NewQuery("TeamMembership").Ancestor(seasonKey.Parent()).
+		Filter("Season =", season.Previous)
+	next, err := migrationPage(c, q, cursor, func(it *datastore.Iterator) error {
+		m := new(TeamMembership)
+		if _, err := it.Next(m); err != nil {
+			return err
+		}
+		// Only players still on a roster carry over.
+		if m.IsCurrent() && !onRoster[m.TeamKey.Encode()+"/"+m.PlayerKey.Encode()] {
+			m.Season = seasonKey
+			memberships = append(memberships, m)
+			keys = append(keys,
+				datastore.NewIncompleteKey(c, "TeamMembership", seasonKey.Parent()))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return next, putMigrated(c, keys, memberships)
+}
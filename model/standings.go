@@ -0,0 +1,195 @@
+package model
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"sort"
+)
+
+// A team's place in the standings of a league. Not directly stored in datastore.
+//
+// Only final match results count towards standings.
+type Standing struct {
+	TeamKey *datastore.Key
+
+	// 1 for first place. Teams still tied after every tiebreaker share a rank.
+	Rank int
+
+	Points int
+	Wins   int
+	Losses int
+	Ties   int
+
+	GamesWon  int
+	GamesLost int
+
+	// The points earned in matches against teams with the same number of Points. This is
+	// the first tiebreaker.
+	HeadToHeadPoints int
+
+	teamId string
+}
+
+// The second tiebreaker.
+func (s *Standing) GameDifferential() int {
+	return s.GamesWon - s.GamesLost
+}
+
+// The result of a match for one team, as used to compute standings.
+type standingResult struct {
+	matchId   string
+	teamId    string
+	points    int
+	gamesWon  int
+	gamesLost int
+}
+
+// Ordered by rank. Teams tied after every tiebreaker keep their relative order.
+type standingsByRank []*Standing
+
+func (a standingsByRank) Len() int      { return len(a) }
+func (a standingsByRank) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a standingsByRank) Less(i, j int) bool {
+	return a[i].compare(a[j]) > 0
+}
+
+// Returns a positive number if s places above o, a negative one if it places below and
+// zero if they are tied after every tiebreaker.
+func (s *Standing) compare(o *Standing) int {
+	if s.Points != o.Points {
+		return s.Points - o.Points
+	}
+	if s.HeadToHeadPoints != o.HeadToHeadPoints {
+		return s.HeadToHeadPoints - o.HeadToHeadPoints
+	}
+	return s.GameDifferential() - o.GameDifferential()
+}
+
+// Computes standings for the teams with teamIds, listed in the order to use for teams
+// still tied after every tiebreaker.
+func computeStandings(teamIds []string, results []*standingResult) []*Standing {
+	standings := make([]*Standing, len(teamIds))
+	byTeam := make(map[string]*Standing)
+	for i, teamId := range teamIds {
+		standings[i] = &Standing{teamId: teamId}
+		byTeam[teamId] = standings[i]
+	}
+
+	byMatch := make(map[string][]*standingResult)
+	for _, r := range results {
+		s := byTeam[r.teamId]
+		if s == nil {
+			continue
+		}
+		byMatch[r.matchId] = append(byMatch[r.matchId], r)
+		s.Points += r.points
+		s.GamesWon += r.gamesWon
+		s.GamesLost += r.gamesLost
+		switch {
+		case r.gamesWon > r.gamesLost:
+			s.Wins++
+		case r.gamesWon < r.gamesLost:
+			s.Losses++
+		default:
+			s.Ties++
+		}
+	}
+
+	for _, matchResults := range byMatch {
+		for _, r := range matchResults {
+			s := byTeam[r.teamId]
+			for _, opponent := range matchResults {
+				if opponent.teamId == r.teamId {
+					continue
+				}
+				if byTeam[opponent.teamId].Points == s.Points {
+					s.HeadToHeadPoints += r.points
+				}
+			}
+		}
+	}
+
+	sort.Stable(standingsByRank(standings))
+	for i, s := range standings {
+		if i > 0 && s.compare(standings[i-1]) == 0 {
+			s.Rank = standings[i-1].Rank
+		} else {
+			s.Rank = i + 1
+		}
+	}
+	return standings
+}
+
+// Computes the standings of a league from its final match results. teamKeys are listed in
+// the order to use for teams still tied after every tiebreaker.
+func ComputeStandings(teamKeys []*datastore.Key, results []*MatchResult) []*Standing {
+	teamIds := make([]string, len(teamKeys))
+	keysById := make(map[string]*datastore.Key)
+	for i, k := range teamKeys {
+		teamIds[i] = k.Encode()
+		keysById[teamIds[i]] = k
+	}
+	var standingResults []*standingResult
+	for _, r := range results {
+		if !r.IsFinal {
+			continue
+		}
+		standingResults = append(standingResults, &standingResult{
+			matchId:   r.ScheduledMatch.Encode(),
+			teamId:    r.Team.Encode(),
+			points:    r.Points,
+			gamesWon:  r.GamesWon,
+			gamesLost: r.GamesLost,
+		})
+	}
+
+	standings := computeStandings(teamIds, standingResults)
+	for _, s := range standings {
+		s.TeamKey = keysById[s.teamId]
+	}
+	return standings
+}
+
+// Returns the standings of every team in a league within scope, along with the teams in
+// the same order.
+func LeagueStandings(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	scope *LeagueScope) ([]*Standing, []*Team, error) {
+	teams, teamKeys, err := ScopeTeams(c, userAcls, league, leagueKey, scope)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Sort(TeamsByName{teams, teamKeys})
+
+	results, err := LeagueMatchResults(c, userAcls, league, leagueKey, scope)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	standings := ComputeStandings(teamKeys, results)
+	teamsById := make(map[string]*Team)
+	for i, k := range teamKeys {
+		teamsById[k.Encode()] = teams[i]
+	}
+	standingTeams := make([]*Team, len(standings))
+	for i, s := range standings {
+		standingTeams[i] = teamsById[s.teamId]
+	}
+	return standings, standingTeams, nil
+}
+
+// Orders teams and their keys by name.
+type TeamsByName struct {
+	Teams []*Team
+	Keys  []*datastore.Key
+}
+
+func (a TeamsByName) Len() int { return len(a.Teams) }
+func (a TeamsByName) Swap(i, j int) {
+	a.Teams[i], a.Teams[j] = a.Teams[j], a.Teams[i]
+	a.Keys[i], a.Keys[j] = a.Keys[j], a.Keys[i]
+}
+func (a TeamsByName) Less(i, j int) bool { return a.Teams[i].Name < a.Teams[j].Name }
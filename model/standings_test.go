@@ -0,0 +1,63 @@
+package model
+
+import (
+	"testing"
+)
+
+type standingRow struct {
+	teamId                           string
+	rank, points, wins, losses, ties int
+	gamesWon, gamesLost, headToHead  int
+}
+
+func checkStandings(t *testing.T, got []*Standing, want []standingRow) {
+	if len(got) != len(want) {
+		t.Fatalf("got %d standings, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		g := got[i]
+		row := standingRow{
+			g.teamId, g.Rank, g.Points, g.Wins, g.Losses, g.Ties,
+			g.GamesWon, g.GamesLost, g.HeadToHeadPoints,
+		}
+		if row != w {
+			t.Errorf("place %d: got %+v, want %+v", i+1, row, w)
+		}
+	}
+}
+
+func TestComputeStandingsHeadToHead(t *testing.T) {
+	results := []*standingResult{
+		{"m1", "A", 2, 1, 0}, {"m1", "B", 0, 0, 1},
+		{"m2", "C", 2, 2, 0}, {"m2", "A", 0, 0, 2},
+		{"m3", "B", 2, 2, 0}, {"m3", "D", 0, 0, 2},
+		{"m4", "C", 1, 1, 1}, {"m4", "D", 1, 1, 1},
+	}
+	// B has the better game differential but A won the match between them.
+	checkStandings(t, computeStandings([]string{"A", "B", "C", "D"}, results), []standingRow{
+		{"C", 1, 3, 1, 0, 1, 3, 1, 0},
+		{"A", 2, 2, 1, 1, 0, 1, 2, 2},
+		{"B", 3, 2, 1, 1, 0, 2, 1, 0},
+		{"D", 4, 1, 0, 1, 1, 1, 3, 0},
+	})
+}
+
+func TestComputeStandings(t *testing.T) {
+	results := []*standingResult{
+		{"m1", "A", 2, 2, 0}, {"m1", "C", 0, 0, 2},
+		{"m2", "B", 2, 1, 0}, {"m2", "C", 0, 0, 1},
+		// Results of teams that are not in the standings are ignored.
+		{"m3", "X", 2, 1, 0}, {"m3", "D", 0, 0, 1},
+	}
+	teamIds := []string{"C", "D", "F", "E", "B", "A"}
+	checkStandings(t, computeStandings(teamIds, results), []standingRow{
+		// Tied on points without a match between them, so game differential decides.
+		{"A", 1, 2, 1, 0, 0, 2, 0, 0},
+		{"B", 2, 2, 1, 0, 0, 1, 0, 0},
+		// Tied after every tiebreaker.
+		{"F", 3, 0, 0, 0, 0, 0, 0, 0},
+		{"E", 3, 0, 0, 0, 0, 0, 0, 0},
+		{"D", 5, 0, 0, 1, 0, 0, 1, 0},
+		{"C", 6, 0, 0, 2, 0, 0, 3, 0},
+	})
+}
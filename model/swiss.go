@@ -0,0 +1,659 @@
+package model
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"errors"
+	"fmt"
+	"github.com/OwenDurni/loltools/util/errwrap"
+	"sort"
+	"time"
+)
+
+// Points for a Swiss match. A bye counts as a win.
+const (
+	swissWinPoints = 3
+	swissTiePoints = 1
+)
+
+// The lowest match win percentage counted for an opponent.
+const swissMinWinPercentage = 1.0 / 3
+
+// A Swiss-system tournament between a league's teams. Each round pairs teams with similar
+// records that have not played each other yet, and is created once every result of the
+// previous round is final.
+//
+// Ancestor: League
+type SwissTournament struct {
+	Name string
+
+	// The teams of the tournament, ordered by seed. Seeds break ties in the first round.
+	Teams []*datastore.Key
+
+	NumRounds int
+
+	// The last round created, from 1.
+	Round int
+
+	// The season the tournament's matches are part of. Nil before the league's first
+	// season.
+	Season *datastore.Key
+
+	// Copied to the match of each pairing.
+	Summary  string
+	NumGames int
+	MapId    int
+	GameMode string
+
+	// How many days after its round is created a match may be played.
+	WindowDays int
+
+	Created time.Time
+}
+
+func SwissId(swissKey *datastore.Key) string {
+	return EncodeKeyShort(swissKey)
+}
+func LeagueSwissUri(leagueKey *datastore.Key, swissKey *datastore.Key) string {
+	return fmt.Sprintf("%s/swiss/%s", LeagueUri(leagueKey), SwissId(swissKey))
+}
+
+// A pairing in a round of a Swiss tournament. A pairing without an AwayTeam is a bye
+// and has no match.
+//
+// Ancestor: League
+type SwissPairing struct {
+	Tournament     *datastore.Key
+	Round          int
+	HomeTeam       *datastore.Key
+	AwayTeam       *datastore.Key
+	ScheduledMatch *datastore.Key
+}
+
+// A team's record in a Swiss tournament. Not directly stored in datastore.
+type SwissRecord struct {
+	TeamKey *datastore.Key
+
+	// 1 for first place. Teams still tied after every tiebreaker share a rank.
+	Rank int
+
+	Points int
+	Wins   int
+	Losses int
+	Ties   int
+	Byes   int
+
+	// The sum of the points of the team's opponents. This is the first tiebreaker.
+	Buchholz int
+
+	// The average match win percentage of the team's opponents, each at least one third.
+	// This is the second tiebreaker.
+	OpponentWinPercentage float64
+
+	teamId    string
+	seed      int
+	opponents []string
+	homes     int
+}
+
+func (r *SwissRecord) Matches() int {
+	return r.Wins + r.Losses + r.Ties
+}
+
+// Points per match played, from 0 to 1.
+func (r *SwissRecord) WinPercentage() float64 {
+	if r.Matches() == 0 {
+		return 0
+	}
+	return float64(r.Points) / float64(swissWinPoints*r.Matches())
+}
+
+// Returns a positive number if r places above o, a negative one if it places below and
+// zero if they are tied after every tiebreaker.
+func (r *SwissRecord) compare(o *SwissRecord) int {
+	if r.Points != o.Points {
+		return r.Points - o.Points
+	}
+	if r.Buchholz != o.Buchholz {
+		return r.Buchholz - o.Buchholz
+	}
+	switch {
+	case r.OpponentWinPercentage > o.OpponentWinPercentage:
+		return 1
+	case r.OpponentWinPercentage < o.OpponentWinPercentage:
+		return -1
+	}
+	return 0
+}
+
+func (r *SwissRecord) hasPlayed(teamId string) bool {
+	for _, opponent := range r.opponents {
+		if opponent == teamId {
+			return true
+		}
+	}
+	return false
+}
+
+// Ordered by rank, then seed.
+type swissRecordsByRank []*SwissRecord
+
+func (a swissRecordsByRank) Len() int      { return len(a) }
+func (a swissRecordsByRank) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a swissRecordsByRank) Less(i, j int) bool {
+	if c := a[i].compare(a[j]); c != 0 {
+		return c > 0
+	}
+	return a[i].seed < a[j].seed
+}
+
+type swissOutcome int
+
+const (
+	swissPending swissOutcome = iota
+	swissHomeWin
+	swissAwayWin
+	swissTie
+)
+
+// A pairing and its outcome, as used to compute records. A bye has an empty away team.
+type swissGame struct {
+	home    string
+	away    string
+	outcome swissOutcome
+}
+
+// Computes the records of the teams with teamIds, listed by seed, ordered by rank. Pending
+// games are ignored.
+func computeSwissRecords(teamIds []string, games []*swissGame) []*SwissRecord {
+	records := make([]*SwissRecord, len(teamIds))
+	byTeam := make(map[string]*SwissRecord)
+	for i, teamId := range teamIds {
+		records[i] = &SwissRecord{teamId: teamId, seed: i}
+		byTeam[teamId] = records[i]
+	}
+
+	for _, g := range games {
+		home := byTeam[g.home]
+		if home == nil {
+			continue
+		}
+		if g.away == "" {
+			home.Byes++
+			home.Wins++
+			home.Points += swissWinPoints
+			continue
+		}
+		away := byTeam[g.away]
+		if away == nil || g.outcome == swissPending {
+			continue
+		}
+		home.opponents = append(home.opponents, g.away)
+		away.opponents = append(away.opponents, g.home)
+		home.homes++
+		switch g.outcome {
+		case swissHomeWin:
+			home.Wins++
+			home.Points += swissWinPoints
+			away.Losses++
+		case swissAwayWin:
+			away.Wins++
+			away.Points += swissWinPoints
+			home.Losses++
+		case swissTie:
+			home.Ties++
+			away.Ties++
+			home.Points += swissTiePoints
+			away.Points += swissTiePoints
+		}
+	}
+
+	for _, r := range records {
+		var total float64
+		for _, opponentId := range r.opponents {
+			opponent := byTeam[opponentId]
+			r.Buchholz += opponent.Points
+			if p := opponent.WinPercentage(); p > swissMinWinPercentage {
+				total += p
+			} else {
+				total += swissMinWinPercentage
+			}
+		}
+		if len(r.opponents) > 0 {
+			r.OpponentWinPercentage = total / float64(len(r.opponents))
+		}
+	}
+
+	sort.Sort(swissRecordsByRank(records))
+	for i, r := range records {
+		if i > 0 && r.compare(records[i-1]) == 0 {
+			r.Rank = records[i-1].Rank
+		} else {
+			r.Rank = i + 1
+		}
+	}
+	return records
+}
+
+// The most pairings swissPairings tries while looking for one without rematches. Late
+// rounds of small fields often have none, and trying every pairing takes factorial time.
+const swissPairingSearchLimit = 10000
+
+// Pairs teams by their records, which are ordered by rank. Returns pairs of {home, away}
+// indices into records, and the index of the team with a bye or -1.
+//
+// The bye goes to the lowest ranked team that has not had one. Within a group of teams
+// with the same points, the top half plays the bottom half; teams without an opponent in
+// their group play down. Rematches are avoided unless no pairing without them is found
+// within swissPairingSearchLimit tries; then each team takes the first candidate it has
+// not played, if any, and otherwise a rematch.
+func swissPairings(records []*SwissRecord) ([][2]int, int) {
+	bye := -1
+	if len(records)%2 == 1 {
+		bye = len(records) - 1
+		for i := len(records) - 1; i >= 0; i-- {
+			if records[i].Byes == 0 {
+				bye = i
+				break
+			}
+		}
+	}
+
+	var unpaired []int
+	for i := range records {
+		if i != bye {
+			unpaired = append(unpaired, i)
+		}
+	}
+
+	tries := 0
+	var pair func(unpaired []int, allowRematches bool) ([][2]int, bool)
+	pair = func(unpaired []int, allowRematches bool) ([][2]int, bool) {
+		if len(unpaired) == 0 {
+			return nil, true
+		}
+		if !allowRematches {
+			if tries++; tries > swissPairingSearchLimit {
+				return nil, false
+			}
+		}
+		top := records[unpaired[0]]
+
+		// Candidates: the bottom half of top's group, then the rest of the top half
+		// closest first, then everyone below.
+		var group, below []int
+		for _, i := range unpaired[1:] {
+			if records[i].Points == top.Points {
+				group = append(group, i)
+			} else {
+				below = append(below, i)
+			}
+		}
+		half := (len(group) + 1) / 2
+		candidates := append([]int{}, group[len(group)-half:]...)
+		for i := len(group) - half - 1; i >= 0; i-- {
+			candidates = append(candidates, group[i])
+		}
+		candidates = append(candidates, below...)
+		if allowRematches {
+			// Any candidate pairs the rest, so the first one that is not a rematch is taken
+			// without backtracking.
+			var rematches []int
+			var fresh []int
+			for _, c := range candidates {
+				if top.hasPlayed(records[c].teamId) {
+					rematches = append(rematches, c)
+				} else {
+					fresh = append(fresh, c)
+				}
+			}
+			candidates = append(fresh, rematches...)
+		}
+
+		for _, c := range candidates {
+			if !allowRematches && top.hasPlayed(records[c].teamId) {
+				continue
+			}
+			var rest []int
+			for _, i := range unpaired[1:] {
+				if i != c {
+					rest = append(rest, i)
+				}
+			}
+			if pairs, ok := pair(rest, allowRematches); ok {
+				return append([][2]int{{unpaired[0], c}}, pairs...), true
+			}
+		}
+		return nil, false
+	}
+
+	pairs, ok := pair(unpaired, false)
+	if !ok {
+		pairs, _ = pair(unpaired, true)
+	}
+
+	// The team with fewer home matches is home; the higher ranked team if tied.
+	for i, p := range pairs {
+		if records[p[1]].homes < records[p[0]].homes {
+			pairs[i] = [2]int{p[1], p[0]}
+		}
+	}
+	return pairs, bye
+}
+
+func getSwissPairings(
+	c appengine.Context, swissKey *datastore.Key) ([]*SwissPairing, error) {
+	var pairings []*SwissPairing
+	q := datastore.NewQuery("SwissPairing").Ancestor(swissKey.Parent()).
+		Filter("Tournament =", swissKey)
+	if _, err := q.GetAll(c, &pairings); err != nil {
+		return nil, errwrap.Wrap(err)
+	}
+	sort.Sort(swissPairingsByRound(pairings))
+	return pairings, nil
+}
+
+type swissPairingsByRound []*SwissPairing
+
+func (a swissPairingsByRound) Len() int           { return len(a) }
+func (a swissPairingsByRound) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a swissPairingsByRound) Less(i, j int) bool { return a[i].Round < a[j].Round }
+
+// Returns the outcome of a pairing from its match results.
+func swissPairingOutcome(
+	c appengine.Context, pairing *SwissPairing) (swissOutcome, error) {
+	if pairing.AwayTeam == nil {
+		return swissHomeWin, nil
+	}
+	results := make([]*MatchResult, 2)
+	for i, teamKey := range []*datastore.Key{pairing.HomeTeam, pairing.AwayTeam} {
+		results[i] = new(MatchResult)
+		resultKey := KeyForMatchResult(c, pairing.ScheduledMatch, teamKey)
+		err := datastore.Get(c, resultKey, results[i])
+		if err == datastore.ErrNoSuchEntity {
+			return swissPending, nil
+		} else if err != nil {
+			return swissPending, errwrap.Wrap(err)
+		}
+		if !results[i].IsFinal {
+			return swissPending, nil
+		}
+	}
+	switch {
+	case results[0].GamesWon > results[1].GamesWon:
+		return swissHomeWin, nil
+	case results[1].GamesWon > results[0].GamesWon:
+		return swissAwayWin, nil
+	}
+	return swissTie, nil
+}
+
+// Returns the records of a tournament's teams ordered by rank, its pairings ordered by
+// round, and whether every pairing has a final outcome.
+func swissState(
+	c appengine.Context,
+	swiss *SwissTournament,
+	swissKey *datastore.Key) ([]*SwissRecord, []*SwissPairing, bool, error) {
+	pairings, err := getSwissPairings(c, swissKey)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	final := true
+	games := make([]*swissGame, len(pairings))
+	for i, p := range pairings {
+		outcome, err := swissPairingOutcome(c, p)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		final = final && outcome != swissPending
+		games[i] = &swissGame{home: p.HomeTeam.Encode(), outcome: outcome}
+		if p.AwayTeam != nil {
+			games[i].away = p.AwayTeam.Encode()
+		}
+	}
+
+	teamIds := make([]string, len(swiss.Teams))
+	keysById := make(map[string]*datastore.Key)
+	for i, k := range swiss.Teams {
+		teamIds[i] = k.Encode()
+		keysById[teamIds[i]] = k
+	}
+	records := computeSwissRecords(teamIds, games)
+	for _, r := range records {
+		r.TeamKey = keysById[r.teamId]
+	}
+	return records, pairings, final, nil
+}
+
+// Creates the pairings and matches of the next round, within a transaction.
+func putNextSwissRound(
+	c appengine.Context,
+	swiss *SwissTournament,
+	swissKey *datastore.Key,
+	records []*SwissRecord) error {
+	swiss.Round++
+	now := time.Now()
+
+	pairs, bye := swissPairings(records)
+	var pairings []*SwissPairing
+	if bye >= 0 {
+		pairings = append(pairings, &SwissPairing{
+			Tournament: swissKey,
+			Round:      swiss.Round,
+			HomeTeam:   records[bye].TeamKey,
+		})
+	}
+	for _, p := range pairs {
+		home, away := records[p[0]].TeamKey, records[p[1]].TeamKey
+		match := &ScheduledMatch{
+			Summary:          swiss.Summary,
+			Description:      fmt.Sprintf("%s, round %d", swiss.Name, swiss.Round),
+			PrimaryTag:       fmt.Sprintf("%s Round %d", swiss.Name, swiss.Round),
+			Season:           swiss.Season,
+			TeamKeys:         []*datastore.Key{home, away},
+			NumGames:         swiss.NumGames,
+			MapId:            swiss.MapId,
+			GameMode:         swiss.GameMode,
+			OfficialDatetime: now,
+			DateEarliest:     now,
+			DateLatest:       now.AddDate(0, 0, swiss.WindowDays),
+		}
+		matchKey, err := datastore.Put(
+			c, datastore.NewIncompleteKey(c, "ScheduledMatch", swissKey.Parent()), match)
+		if err != nil {
+			return errwrap.Wrap(err)
+		}
+		pairings = append(pairings, &SwissPairing{
+			Tournament:     swissKey,
+			Round:          swiss.Round,
+			HomeTeam:       home,
+			AwayTeam:       away,
+			ScheduledMatch: matchKey,
+		})
+	}
+
+	keys := make([]*datastore.Key, len(pairings))
+	for i := range keys {
+		keys[i] = datastore.NewIncompleteKey(c, "SwissPairing", swissKey.Parent())
+	}
+	if _, err := datastore.PutMulti(c, keys, pairings); err != nil {
+		return errwrap.Wrap(err)
+	}
+	_, err := datastore.Put(c, swissKey, swiss)
+	return errwrap.Wrap(err)
+}
+
+// Creates a Swiss tournament between the top numTeams teams of the league's standings
+// within scope, and the matches of its first round. numRounds defaults to enough rounds
+// to leave a single undefeated team.
+func CreateSwissTournament(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	scope *LeagueScope,
+	swiss *SwissTournament,
+	numTeams int) (*datastore.Key, error) {
+	if err := canEditLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, err
+	}
+	if swiss.Name == "" {
+		return nil, errors.New("A tournament needs a name")
+	}
+
+	standings, _, err := LeagueStandings(c, userAcls, league, leagueKey, scope)
+	if err != nil {
+		return nil, err
+	}
+	swiss.Season = league.CurrentSeason
+	if scope != nil {
+		swiss.Season = scope.SeasonKey
+	}
+	if numTeams <= 0 || numTeams > len(standings) {
+		numTeams = len(standings)
+	}
+	if numTeams < 2 {
+		return nil, errors.New("A tournament needs at least two teams")
+	}
+	swiss.Teams = make([]*datastore.Key, numTeams)
+	for i := range swiss.Teams {
+		swiss.Teams[i] = standings[i].TeamKey
+	}
+	if swiss.NumRounds <= 0 {
+		for n := 1; n < numTeams; n *= 2 {
+			swiss.NumRounds++
+		}
+	}
+	if swiss.NumRounds >= numTeams {
+		return nil, errors.New(fmt.Sprintf(
+			"%d teams can play at most %d rounds without a rematch", numTeams, numTeams-1))
+	}
+	swiss.Round = 0
+	swiss.Created = time.Now()
+
+	var swissKey *datastore.Key
+	err = datastore.RunInTransaction(c, func(c appengine.Context) error {
+		var err error
+		swissKey, err = datastore.Put(
+			c, datastore.NewIncompleteKey(c, "SwissTournament", leagueKey), swiss)
+		if err != nil {
+			return errwrap.Wrap(err)
+		}
+		records, _, _, err := swissState(c, swiss, swissKey)
+		if err != nil {
+			return err
+		}
+		return putNextSwissRound(c, swiss, swissKey, records)
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return swissKey, nil
+}
+
+// Creates the next round of a tournament if every result of the current round is final.
+// Returns whether a round was created.
+func advanceSwiss(c appengine.Context, swissKey *datastore.Key) (bool, error) {
+	created := false
+	err := datastore.RunInTransaction(c, func(c appengine.Context) error {
+		swiss := new(SwissTournament)
+		if err := datastore.Get(c, swissKey, swiss); err != nil {
+			return errwrap.Wrap(err)
+		}
+		if swiss.Round >= swiss.NumRounds {
+			return nil
+		}
+		records, _, final, err := swissState(c, swiss, swissKey)
+		if err != nil || !final {
+			return err
+		}
+		created = true
+		return putNextSwissRound(c, swiss, swissKey, records)
+	}, nil)
+	return created, err
+}
+
+// Creates the next round of the Swiss tournament the match belongs to, if any, once
+// every result of the match's round is final.
+func AdvanceSwissForMatch(c appengine.Context, matchKey *datastore.Key) error {
+	var pairings []*SwissPairing
+	q := datastore.NewQuery("SwissPairing").Ancestor(matchKey.Parent()).
+		Filter("ScheduledMatch =", matchKey)
+	if _, err := q.GetAll(c, &pairings); err != nil {
+		return errwrap.Wrap(err)
+	}
+	for _, p := range pairings {
+		if _, err := advanceSwiss(c, p.Tournament); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Creates the next round of a tournament as a league editor. Fails unless every result
+// of the current round is final.
+func StartNextSwissRound(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	swissKey *datastore.Key) error {
+	if err := canEditLeague(c, userAcls, league, leagueKey); err != nil {
+		return err
+	}
+	created, err := advanceSwiss(c, swissKey)
+	if err != nil {
+		return err
+	}
+	if !created {
+		return errors.New(
+			"Every round has been created or the current round's results are not final")
+	}
+	return nil
+}
+
+// Returns a tournament, the records of its teams ordered by rank and its pairings
+// ordered by round.
+func SwissById(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	swissId string) (*SwissTournament, *datastore.Key, []*SwissRecord, []*SwissPairing, error) {
+	swissKey, err := DecodeKeyShort(c, "SwissTournament", swissId, leagueKey)
+	if err != nil {
+		return nil, nil, nil, nil, errwrap.Wrap(err)
+	}
+	if err := canViewLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	swiss := new(SwissTournament)
+	if err := datastore.Get(c, swissKey, swiss); err != nil {
+		return nil, nil, nil, nil, errwrap.Wrap(err)
+	}
+	records, pairings, _, err := swissState(c, swiss, swissKey)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return swiss, swissKey, records, pairings, nil
+}
+
+// Returns every Swiss tournament in a league.
+func LeagueSwissTournaments(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key) ([]*SwissTournament, []*datastore.Key, error) {
+	if err := canViewLeague(c, userAcls, league, leagueKey); err != nil {
+		return nil, nil, err
+	}
+
+	var tournaments []*SwissTournament
+	keys, err := datastore.NewQuery("SwissTournament").Ancestor(leagueKey).Order("Created").
+		GetAll(c, &tournaments)
+	if err != nil {
+		return nil, nil, errwrap.Wrap(err)
+	}
+	return tournaments, keys, nil
+}
@@ -0,0 +1,172 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestComputeSwissRecords(t *testing.T) {
+	games := []*swissGame{
+		{home: "a", away: "b", outcome: swissHomeWin},
+		{home: "c", away: "d", outcome: swissHomeWin},
+		{home: "e"},
+		{home: "a", away: "c", outcome: swissHomeWin},
+		{home: "d", away: "b", outcome: swissHomeWin},
+		{home: "e", away: "b", outcome: swissTie},
+		{home: "a", away: "e", outcome: swissPending},
+	}
+	records := computeSwissRecords([]string{"a", "b", "c", "d", "e"}, games)
+
+	var order []string
+	for _, r := range records {
+		order = append(order, r.teamId)
+	}
+	// c and d both have 3 points; c's opponents have more.
+	if want := []string{"a", "e", "c", "d", "b"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("got order %v, want %v", order, want)
+	}
+
+	a := records[0]
+	if a.Points != 6 || a.Wins != 2 || a.Losses != 0 || a.Matches() != 2 {
+		t.Errorf("a: got %+v", a)
+	}
+	if a.Buchholz != 1+3 {
+		t.Errorf("a: got Buchholz %d, want 4", a.Buchholz)
+	}
+	// b's 1/9 counts as 1/3, c won half its points.
+	if want := (1.0/3 + 0.5) / 2; math.Abs(a.OpponentWinPercentage-want) > 1e-9 {
+		t.Errorf("a: got opponent win percentage %v, want %v", a.OpponentWinPercentage, want)
+	}
+
+	e := records[1]
+	if e.Points != 4 || e.Byes != 1 || e.Wins != 1 || e.Ties != 1 || e.Buchholz != 1 {
+		t.Errorf("e: got %+v", e)
+	}
+	if c, d := records[2], records[3]; c.Buchholz != 9 || d.Buchholz != 4 {
+		t.Errorf("got Buchholz c=%d d=%d, want c=9 d=4", c.Buchholz, d.Buchholz)
+	}
+}
+
+func TestComputeSwissRecordsSharedRank(t *testing.T) {
+	records := computeSwissRecords([]string{"a", "b", "c"}, nil)
+	for i, r := range records {
+		if r.Rank != 1 || r.seed != i {
+			t.Errorf("got rank %d seed %d, want rank 1 seed %d", r.Rank, r.seed, i)
+		}
+	}
+}
+
+func TestSwissPairingsFirstRound(t *testing.T) {
+	teamIds := []string{"1", "2", "3", "4", "5", "6", "7", "8"}
+	pairs, bye := swissPairings(computeSwissRecords(teamIds, nil))
+	if bye != -1 {
+		t.Errorf("got bye %d, want none", bye)
+	}
+	if want := [][2]int{{0, 4}, {1, 5}, {2, 6}, {3, 7}}; !reflect.DeepEqual(pairs, want) {
+		t.Errorf("got %v, want %v", pairs, want)
+	}
+}
+
+// Plays rounds of a tournament in which the better seed always wins, checking that no
+// pair of teams meets twice and that no team has two byes.
+func TestSwissPairingsRematchesAndByes(t *testing.T) {
+	for numTeams := 2; numTeams <= 9; numTeams++ {
+		teamIds := make([]string, numTeams)
+		for i := range teamIds {
+			teamIds[i] = fmt.Sprintf("%d", i)
+		}
+		var games []*swissGame
+		met := make(map[[2]string]bool)
+		byes := make(map[string]bool)
+		numRounds := 0
+		for n := 1; n < numTeams; n *= 2 {
+			numRounds++
+		}
+		for round := 1; round <= numRounds; round++ {
+			records := computeSwissRecords(teamIds, games)
+			pairs, bye := swissPairings(records)
+			if (bye >= 0) != (numTeams%2 == 1) {
+				t.Fatalf("%d teams, round %d: got bye %d", numTeams, round, bye)
+			}
+			if bye >= 0 {
+				if byes[records[bye].teamId] {
+					t.Errorf("%d teams, round %d: second bye for %s",
+						numTeams, round, records[bye].teamId)
+				}
+				byes[records[bye].teamId] = true
+				games = append(games, &swissGame{home: records[bye].teamId})
+			}
+			if len(pairs) != numTeams/2 {
+				t.Fatalf("%d teams, round %d: got %d pairs", numTeams, round, len(pairs))
+			}
+			for _, p := range pairs {
+				home, away := records[p[0]], records[p[1]]
+				if met[[2]string{home.teamId, away.teamId}] {
+					t.Errorf("%d teams, round %d: rematch between %s and %s",
+						numTeams, round, home.teamId, away.teamId)
+				}
+				met[[2]string{home.teamId, away.teamId}] = true
+				met[[2]string{away.teamId, home.teamId}] = true
+				g := &swissGame{home: home.teamId, away: away.teamId, outcome: swissAwayWin}
+				if home.seed < away.seed {
+					g.outcome = swissHomeWin
+				}
+				games = append(games, g)
+			}
+		}
+	}
+}
+
+func TestSwissPairingsAlternateHome(t *testing.T) {
+	games := []*swissGame{
+		{home: "a", away: "b", outcome: swissHomeWin},
+		{home: "c", away: "d", outcome: swissHomeWin},
+	}
+	records := computeSwissRecords([]string{"a", "b", "c", "d"}, games)
+	pairs, _ := swissPairings(records)
+	for _, p := range pairs {
+		if records[p[0]].homes > records[p[1]].homes {
+			t.Errorf("%s is home again against %s", records[p[0]].teamId, records[p[1]].teamId)
+		}
+	}
+}
+
+// The last team has played everyone else, so no pairing avoids a rematch. Finding that
+// out means trying every pairing of the others, which must not take factorial time.
+func TestSwissPairingsNoRematchFreePairing(t *testing.T) {
+	const numTeams = 20
+	records := make([]*SwissRecord, numTeams)
+	for i := range records {
+		records[i] = &SwissRecord{teamId: fmt.Sprintf("%d", i), seed: i}
+	}
+	last := records[numTeams-1]
+	for _, r := range records[:numTeams-1] {
+		last.opponents = append(last.opponents, r.teamId)
+		r.opponents = append(r.opponents, last.teamId)
+	}
+
+	pairs, bye := swissPairings(records)
+	if bye != -1 {
+		t.Errorf("got bye %d, want none", bye)
+	}
+	if len(pairs) != numTeams/2 {
+		t.Fatalf("got %d pairs, want %d", len(pairs), numTeams/2)
+	}
+	paired := make(map[int]bool)
+	rematches := 0
+	for _, p := range pairs {
+		paired[p[0]] = true
+		paired[p[1]] = true
+		if records[p[0]].hasPlayed(records[p[1]].teamId) {
+			rematches++
+		}
+	}
+	if len(paired) != numTeams {
+		t.Errorf("got %d paired teams, want %d", len(paired), numTeams)
+	}
+	if rematches != 1 {
+		t.Errorf("got %d rematches, want 1", rematches)
+	}
+}
@@ -21,6 +21,13 @@ type GameTag struct {
 	Game   *datastore.Key
 	Tag    string
 	Reason string
+
+	// How confident the system is in the tag, from 0 to 100.
+	Confidence int
+
+	// Set once a league editor has reviewed the tag. Reviewed tags are not changed by the
+	// system.
+	Reviewed bool
 }
 
 func GetUserGameTags(
@@ -163,6 +170,41 @@ func AddGameTag(
 	}, nil)
 }
 
+// Creates the tag of gameTag.Game, or replaces its reason and confidence if it exists.
+func SetGameTag(
+	c appengine.Context,
+	userAcls *RequestorAclCache,
+	league *League,
+	leagueKey *datastore.Key,
+	gameTag *GameTag) error {
+	if userAcls != nil {
+		if *userAcls.UserKey != *league.Owner {
+			if err := userAcls.Can(c, PermissionEdit, leagueKey); err != nil {
+				return err
+			}
+		}
+	}
+
+	return datastore.RunInTransaction(c, func(c appengine.Context) error {
+		q := datastore.NewQuery("GameTag").
+			Filter("Game =", gameTag.Game).
+			Filter("Tag =", gameTag.Tag).
+			Ancestor(leagueKey).
+			Limit(1).
+			KeysOnly()
+		keys, err := q.GetAll(c, nil)
+		if err != nil {
+			return err
+		}
+		key := datastore.NewIncompleteKey(c, "GameTag", leagueKey)
+		if len(keys) > 0 {
+			key = keys[0]
+		}
+		_, err = datastore.Put(c, key, gameTag)
+		return err
+	}, nil)
+}
+
 func DelGameTag(
 	c appengine.Context,
 	userAcls *RequestorAclCache,
@@ -192,3 +234,40 @@ func DelGameTag(
 		return nil
 	}, nil)
 }
+
+// Returns the keys of the games in a league with the system tag.
+func GameKeysWithTag(
+	c appengine.Context,
+	leagueKey *datastore.Key,
+	tag string) ([]*datastore.Key, error) {
+	q := datastore.NewQuery("GameTag").
+		Ancestor(leagueKey).
+		Filter("Tag =", tag).
+		Project("Game")
+
+	var tags []*GameTag
+	if _, err := q.GetAll(c, &tags); err != nil {
+		return nil, err
+	}
+	gameKeys := make([]*datastore.Key, len(tags))
+	for i := range tags {
+		gameKeys[i] = tags[i].Game
+	}
+	return gameKeys, nil
+}
+
+// Returns the system tags in a league with the given tag, for every game.
+func GameTagsWithTag(
+	c appengine.Context,
+	leagueKey *datastore.Key,
+	tag string) ([]*GameTag, error) {
+	q := datastore.NewQuery("GameTag").
+		Ancestor(leagueKey).
+		Filter("Tag =", tag)
+
+	var tags []*GameTag
+	if _, err := q.GetAll(c, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
@@ -10,6 +10,6 @@ func AutomaticallyDetectedMatchResultFor(matchKey *datastore.Key) string {
 	return fmt.Sprintf("auto-result:%s", model.MatchId(matchKey))
 }
 
-func ReasonNotApplicable() string {
-	return "n/a"
+func NeedsReviewFor(matchKey *datastore.Key) string {
+	return fmt.Sprintf("review:%s", model.MatchId(matchKey))
 }
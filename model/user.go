@@ -6,8 +6,8 @@ import (
 	"appengine/user"
 	"errors"
 	"fmt"
-	"github.com/OwenDurni/loltools/riot"
 	"github.com/OwenDurni/loltools/util/errwrap"
+	"golang.org/x/net/context"
 	"math/rand"
 	"time"
 )
@@ -135,6 +135,7 @@ func GetSummonerDatas(c appengine.Context, userKey *datastore.Key) ([]*SummonerD
 
 func VerifySummoner(
 	c appengine.Context,
+	ctx context.Context,
 	userKey *datastore.Key,
 	playerKey *datastore.Key,
 	player *Player) error {
@@ -148,14 +149,7 @@ func VerifySummoner(
 	}
 
 	// Lookup rune pages for player.
-	riotApiKey, err := GetRiotApiKey(c)
-	if err != nil {
-		return err
-	}
-	if err = RiotApiRateLimiter.Consume(c, 1); err != nil {
-		return err
-	}
-	runePagesDto, err := riot.RunesBySummonerId(c, riotApiKey.Key, player.Region, player.RiotId)
+	runePagesDto, err := RiotClient(c, player.Region).RunesBySummonerId(ctx, player.RiotId)
 	if err != nil {
 		return err
 	}
@@ -193,10 +187,11 @@ func VerifySummoner(
 
 func AddUnverifiedSummoner(
 	c appengine.Context,
+	ctx context.Context,
 	userKey *datastore.Key,
 	region string,
 	summoner string) error {
-	_, playerKey, err := GetOrCreatePlayerBySummoner(c, region, summoner)
+	_, playerKey, err := GetOrCreatePlayerBySummoner(c, ctx, region, summoner)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,128 @@
+package riot
+
+import (
+	"container/list"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// A Cache stores Riot API response bodies so that a Client can skip requests for
+// resources it has already fetched. Implementations must be safe for concurrent use.
+//
+// Caches are best effort: a backend that fails to read or write should behave as if
+// the entry was missing.
+type Cache interface {
+	// Returns the data stored under key if it has not expired.
+	Get(key string) ([]byte, bool)
+
+	// Stores data under key for ttl, or forever if ttl is CacheForever.
+	Set(key string, data []byte, ttl time.Duration)
+}
+
+// A TTL meaning the entry never expires.
+const CacheForever time.Duration = 1<<63 - 1
+
+// Maps endpoint names (ex: "match-v2.2") to how long their responses are cached.
+// Responses from endpoints that are not listed are never cached.
+type CachePolicy map[string]time.Duration
+
+// Used when ClientOptions.Cache is set and ClientOptions.CachePolicy is nil.
+var DefaultCachePolicy = CachePolicy{
+	// Matches never change once they are over.
+	"match-v2.2": CacheForever,
+
+	"summoner-v1.4":   10 * time.Minute,
+	"matchlist-v2.2":  10 * time.Minute,
+	"league-v2.5":     15 * time.Minute,
+	"stats-v1.3":      time.Hour,
+	"championmastery": time.Hour,
+
+	// Not cached: game-v1.3 is polled for new games, current-game-v1.0 and
+	// featured-games-v1.0 describe games in progress, and summoner-v1.4-runes is polled
+	// to verify summoners.
+}
+
+// Returns the key a response is cached under.
+func cacheKey(region string, endpoint string, path string, args *url.Values) string {
+	key := region + "/" + endpoint + path
+	if len(*args) > 0 {
+		key += "?" + args.Encode()
+	}
+	return key
+}
+
+// An in-memory Cache that holds at most a fixed number of entries, evicting the least
+// recently used entry first.
+type LRUCache struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	ll      *list.List
+	entries map[string]*list.Element
+
+	// Overridden in tests.
+	now func() time.Time
+}
+
+type lruEntry struct {
+	key     string
+	data    []byte
+	expires time.Time // Zero if the entry never expires.
+}
+
+func NewLRUCache(maxEntries int) *LRUCache {
+	return &LRUCache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		entries:    make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+func (lru *LRUCache) Get(key string) ([]byte, bool) {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+	elem, ok := lru.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*lruEntry)
+	if !e.expires.IsZero() && !lru.now().Before(e.expires) {
+		lru.ll.Remove(elem)
+		delete(lru.entries, key)
+		return nil, false
+	}
+	lru.ll.MoveToFront(elem)
+	return e.data, true
+}
+
+func (lru *LRUCache) Set(key string, data []byte, ttl time.Duration) {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+	var expires time.Time
+	if ttl != CacheForever {
+		expires = lru.now().Add(ttl)
+	}
+	if elem, ok := lru.entries[key]; ok {
+		lru.ll.MoveToFront(elem)
+		e := elem.Value.(*lruEntry)
+		e.data = data
+		e.expires = expires
+		return
+	}
+	lru.entries[key] = lru.ll.PushFront(&lruEntry{key, data, expires})
+	for lru.maxEntries > 0 && lru.ll.Len() > lru.maxEntries {
+		oldest := lru.ll.Back()
+		lru.ll.Remove(oldest)
+		delete(lru.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// The number of entries in the cache, including expired entries that have not been
+// evicted yet.
+func (lru *LRUCache) Len() int {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+	return lru.ll.Len()
+}
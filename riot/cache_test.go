@@ -0,0 +1,181 @@
+package riot
+
+import (
+	"golang.org/x/net/context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLRUCache(t *testing.T) {
+	lru := NewLRUCache(2)
+	now := time.Unix(0, 0)
+	lru.now = func() time.Time { return now }
+
+	lru.Set("a", []byte("1"), CacheForever)
+	lru.Set("b", []byte("2"), time.Minute)
+	if data, ok := lru.Get("a"); !ok || string(data) != "1" {
+		t.Errorf("Get(a): got %q, %v", data, ok)
+	}
+
+	// "b" is now the least recently used entry.
+	lru.Set("c", []byte("3"), time.Minute)
+	if _, ok := lru.Get("b"); ok {
+		t.Errorf("Get(b): expected b to be evicted")
+	}
+	if lru.Len() != 2 {
+		t.Errorf("got Len() %d, want 2", lru.Len())
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := lru.Get("c"); ok {
+		t.Errorf("Get(c): expected c to expire")
+	}
+	if data, ok := lru.Get("a"); !ok || string(data) != "1" {
+		t.Errorf("Get(a): got %q, %v after a minute", data, ok)
+	}
+}
+
+func TestFileCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "riotcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Unix(1000, 0)
+	fc := &FileCache{Dir: dir, now: func() time.Time { return now }}
+	fc.Set("na/match-v2.2/api/lol/na/v2.2/match/1", []byte(`{"matchId": 1}`), CacheForever)
+	fc.Set("na/league-v2.5/x", []byte("line one\nline two"), time.Minute)
+
+	if data, ok := fc.Get("na/match-v2.2/api/lol/na/v2.2/match/1"); !ok ||
+		string(data) != `{"matchId": 1}` {
+		t.Errorf("got %q, %v", data, ok)
+	}
+	if data, ok := fc.Get("na/league-v2.5/x"); !ok || string(data) != "line one\nline two" {
+		t.Errorf("got %q, %v", data, ok)
+	}
+	if _, ok := fc.Get("missing"); ok {
+		t.Errorf("got a hit for a missing key")
+	}
+
+	now = now.Add(time.Hour)
+	if _, ok := fc.Get("na/league-v2.5/x"); ok {
+		t.Errorf("expected the league entry to expire")
+	}
+	if _, ok := fc.Get("na/match-v2.2/api/lol/na/v2.2/match/1"); !ok {
+		t.Errorf("expected the match entry to never expire")
+	}
+}
+
+func TestClientCachesByEndpoint(t *testing.T) {
+	server := newScriptedServer(scriptedResponse{status: 200, body: matchJson})
+	defer server.Close()
+
+	lru := NewLRUCache(10)
+	now := time.Unix(0, 0)
+	lru.now = func() time.Time { return now }
+
+	c := NewClient(ClientOptions{
+		ApiKey:  StaticApiKey("test-key"),
+		Retry:   &NoRetryPolicy,
+		Region:  "na",
+		Regions: Regions.WithBaseUrl(server.URL),
+		Cache:   lru,
+		CachePolicy: CachePolicy{
+			"match-v2.2":  CacheForever,
+			"league-v2.5": time.Minute,
+		},
+	})
+
+	for i := 0; i < 3; i++ {
+		match, err := c.LookupMatch(context.Background(), 42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if match.MatchId != 42 {
+			t.Errorf("got MatchId %d, want 42", match.MatchId)
+		}
+		now = now.Add(24 * time.Hour)
+	}
+	if n := server.numRequests(); n != 1 {
+		t.Errorf("got %d requests for a cached match, want 1", n)
+	}
+
+	// Not in the policy so never cached.
+	c.RankedStatsBySummonerId(context.Background(), 1)
+	c.RankedStatsBySummonerId(context.Background(), 1)
+	if n := server.numRequests(); n != 3 {
+		t.Errorf("got %d requests, want 3", n)
+	}
+
+	// Cached until the TTL passes.
+	leagueServer := newScriptedServer(scriptedResponse{status: 200, body: `{"1": []}`})
+	defer leagueServer.Close()
+	c.regions = Regions.WithBaseUrl(leagueServer.URL)
+	for i := 0; i < 2; i++ {
+		if _, err := c.LeagueInfoBySummonerId(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now = now.Add(time.Minute)
+	if _, err := c.LeagueInfoBySummonerId(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if n := leagueServer.numRequests(); n != 2 {
+		t.Errorf("got %d league requests, want 2", n)
+	}
+}
+
+func TestClientDoesNotCacheErrors(t *testing.T) {
+	server := newScriptedServer(
+		scriptedResponse{status: 404},
+		scriptedResponse{status: 200, body: matchJson})
+	defer server.Close()
+
+	c := NewClient(ClientOptions{
+		ApiKey:  StaticApiKey("test-key"),
+		Retry:   &NoRetryPolicy,
+		Region:  "na",
+		Regions: Regions.WithBaseUrl(server.URL),
+		Cache:   NewLRUCache(10),
+	})
+	if _, err := c.LookupMatch(context.Background(), 42); !IsNotFound(err) {
+		t.Errorf("got %v, want a 404", err)
+	}
+	if _, err := c.LookupMatch(context.Background(), 42); err != nil {
+		t.Errorf("got %v after the 404", err)
+	}
+	if _, err := c.LookupMatch(context.Background(), 42); err != nil {
+		t.Errorf("got %v from the cache", err)
+	}
+	if n := server.numRequests(); n != 2 {
+		t.Errorf("got %d requests, want 2", n)
+	}
+}
+
+// Summoners are verified by renaming a rune page, so a rename must be seen right away.
+func TestDefaultCachePolicySeesFreshRunePages(t *testing.T) {
+	server := newScriptedServer(
+		scriptedResponse{status: 200, body: `{"1": {"pages": [{"name": "old"}]}}`},
+		scriptedResponse{status: 200, body: `{"1": {"pages": [{"name": "token"}]}}`})
+	defer server.Close()
+
+	c := NewClient(ClientOptions{
+		ApiKey:  StaticApiKey("test-key"),
+		Retry:   &NoRetryPolicy,
+		Region:  "na",
+		Regions: Regions.WithBaseUrl(server.URL),
+		Cache:   NewLRUCache(10),
+	})
+	for _, want := range []string{"old", "token"} {
+		runes, err := c.RunesBySummonerId(context.Background(), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := runes.Pages[0].Name; got != want {
+			t.Errorf("got rune page %q, want %q", got, want)
+		}
+	}
+}
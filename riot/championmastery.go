@@ -0,0 +1,95 @@
+package riot
+
+import (
+	"fmt"
+	"golang.org/x/net/context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// championmastery: https://developer.riotgames.com/api/methods#!/1091
+type ChampionMasteryDto struct {
+	ChampionId                   int      `json:"championId"`
+	ChampionLevel                int      `json:"championLevel"`
+	ChampionPoints               int      `json:"championPoints"`
+	ChampionPointsSinceLastLevel int64    `json:"championPointsSinceLastLevel"`
+	ChampionPointsUntilNextLevel int64    `json:"championPointsUntilNextLevel"`
+	ChestGranted                 bool     `json:"chestGranted"`
+	HighestGrade                 string   `json:"highestGrade"`
+	LastPlayTime                 RiotTime `json:"lastPlayTime"`
+	PlayerId                     int64    `json:"playerId"`
+}
+
+// Champion mastery paths are keyed by platform rather than region.
+func (c *Client) championMasteryPath(summonerId int64, suffix string) (string, error) {
+	region, err := c.lookupRegion()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("/championmastery/location/%s/player/%d/%s",
+		region.PlatformId, summonerId, suffix), nil
+}
+
+// Returns the summoner's mastery of every champion they have played, highest first.
+func (c *Client) ChampionMasteries(
+	ctx context.Context, summonerId int64) ([]*ChampionMasteryDto, error) {
+	path, err := c.championMasteryPath(summonerId, "champions")
+	if err != nil {
+		return nil, err
+	}
+	var dtos []*ChampionMasteryDto
+	if err := c.fetch(ctx, "championmastery", path, &url.Values{}, &dtos); err != nil {
+		return nil, err
+	}
+	return dtos, nil
+}
+
+// Returns the summoner's mastery of one champion. A champion the summoner has never
+// played has zero points.
+func (c *Client) ChampionMastery(
+	ctx context.Context, summonerId int64, championId int) (*ChampionMasteryDto, error) {
+	path, err := c.championMasteryPath(summonerId, fmt.Sprintf("champion/%d", championId))
+	if err != nil {
+		return nil, err
+	}
+	dto := new(ChampionMasteryDto)
+	err = c.fetch(ctx, "championmastery", path, &url.Values{}, dto)
+	if e, ok := asAPIError(err); ok && e.StatusCode == http.StatusNoContent {
+		// Riot responds with no content for champions that were never played.
+		return &ChampionMasteryDto{ChampionId: championId, PlayerId: summonerId}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return dto, nil
+}
+
+// Returns the summoner's count highest mastery champions.
+func (c *Client) TopChampionMasteries(
+	ctx context.Context, summonerId int64, count int) ([]*ChampionMasteryDto, error) {
+	path, err := c.championMasteryPath(summonerId, "topchampions")
+	if err != nil {
+		return nil, err
+	}
+	var dtos []*ChampionMasteryDto
+	err = c.fetch(ctx, "championmastery", path,
+		&url.Values{"count": []string{strconv.Itoa(count)}}, &dtos)
+	if err != nil {
+		return nil, err
+	}
+	return dtos, nil
+}
+
+// Returns the sum of the summoner's champion mastery levels.
+func (c *Client) ChampionMasteryScore(
+	ctx context.Context, summonerId int64) (int, error) {
+	path, err := c.championMasteryPath(summonerId, "score")
+	if err != nil {
+		return 0, err
+	}
+	var score int
+	if err := c.fetch(ctx, "championmastery", path, &url.Values{}, &score); err != nil {
+		return 0, err
+	}
+	return score, nil
+}
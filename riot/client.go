@@ -0,0 +1,240 @@
+package riot
+
+import (
+	"encoding/json"
+	"errors"
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Options used to construct a Client.
+type ClientOptions struct {
+	// The transport used to send requests. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// Returns the Riot API key to send with each request. Required.
+	ApiKey func() (string, error)
+
+	// Called once before each request is sent. It should block until the request is
+	// permitted, or return an error if the request should not be sent. It should give
+	// up early if ctx is done. May be nil.
+	RateLimit func(ctx context.Context) error
+
+	// Called when Riot responds with a 429 so that the caller's rate limiter can stop
+	// issuing requests for at least retryAfter (zero if Riot did not say). May be nil.
+	OnRateLimited func(retryAfter time.Duration)
+
+	// How 429 and 5XX responses are retried. Defaults to DefaultRetryPolicy.
+	Retry *RetryPolicy
+
+	// The longest a single attempt of a request may take. Zero means attempts are only
+	// bounded by the context passed to each call.
+	RequestTimeout time.Duration
+
+	// The region (ex: "na") that requests are made against.
+	Region string
+
+	// Where regions are looked up. Defaults to Regions.
+	Regions RegionRegistry
+
+	// Where responses are cached. May be nil to disable caching.
+	Cache Cache
+
+	// How long responses from each endpoint are cached. Defaults to
+	// DefaultCachePolicy.
+	CachePolicy CachePolicy
+}
+
+// A Client makes requests against the Riot API for a single region.
+type Client struct {
+	transport http.RoundTripper
+	apiKey    func() (string, error)
+	rateLimit func(context.Context) error
+	onLimited func(time.Duration)
+	retry     RetryPolicy
+	timeout   time.Duration
+	region    string
+	regions   RegionRegistry
+	cache     Cache
+	cacheTtls CachePolicy
+
+	// Overridden in tests.
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+func NewClient(opts ClientOptions) *Client {
+	c := new(Client)
+	c.transport = opts.Transport
+	if c.transport == nil {
+		c.transport = http.DefaultTransport
+	}
+	c.apiKey = opts.ApiKey
+	c.rateLimit = opts.RateLimit
+	c.onLimited = opts.OnRateLimited
+	c.retry = DefaultRetryPolicy
+	if opts.Retry != nil {
+		c.retry = *opts.Retry
+	}
+	c.timeout = opts.RequestTimeout
+	c.region = opts.Region
+	c.regions = opts.Regions
+	c.cache = opts.Cache
+	c.cacheTtls = DefaultCachePolicy
+	if opts.CachePolicy != nil {
+		c.cacheTtls = opts.CachePolicy
+	}
+	c.now = time.Now
+	c.sleep = sleepContext
+	return c
+}
+
+// Waits for d to pass. Returns ctx.Err() if ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Returns a function suitable for ClientOptions.ApiKey that always returns key.
+func StaticApiKey(key string) func() (string, error) {
+	return func() (string, error) {
+		return key, nil
+	}
+}
+
+func (c *Client) Region() string {
+	return c.region
+}
+
+func (c *Client) lookupRegion() (*Region, error) {
+	regions := c.regions
+	if regions == nil {
+		regions = Regions
+	}
+	return regions.Lookup(c.region)
+}
+
+// Fetches the resource at path and decodes its JSON body into v.
+//
+// endpoint names the API being called (ex: "match-v2.2"). It describes errors and
+// selects how long the response is cached. Any non-200 response results in an
+// *APIError. 429 and 5XX responses are retried according to the client's RetryPolicy.
+//
+// Once ctx is done the request in flight is abandoned and ctx.Err() is returned. A retry
+// that could not be sent before ctx's deadline is not waited for.
+func (c *Client) fetch(
+	ctx context.Context, endpoint string, path string, args *url.Values, v interface{}) error {
+	var key string
+	ttl, cacheable := c.cacheTtls[endpoint]
+	if c.cache != nil && cacheable {
+		key = cacheKey(c.region, endpoint, path, args)
+		if jsonData, ok := c.cache.Get(key); ok {
+			if err := json.Unmarshal(jsonData, v); err == nil {
+				return nil
+			}
+			// Fall through and replace the corrupt entry.
+		}
+	}
+
+	start := c.now()
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		jsonData, err := c.fetchOnce(ctx, endpoint, path, args)
+		if err == nil {
+			if err := json.Unmarshal(jsonData, v); err != nil {
+				return err
+			}
+			if key != "" {
+				c.cache.Set(key, jsonData, ttl)
+			}
+			return nil
+		}
+		if IsRateLimited(err) && c.onLimited != nil {
+			e, _ := asAPIError(err)
+			c.onLimited(e.RetryAfter)
+		}
+		if !isRetryable(err) || attempt >= c.retry.MaxAttempts {
+			return err
+		}
+		wait := c.retry.backoff(attempt, err)
+		if c.retry.Deadline > 0 && c.now().Add(wait).Sub(start) > c.retry.Deadline {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok && c.now().Add(wait).After(deadline) {
+			return err
+		}
+		if sleepErr := c.sleep(ctx, wait); sleepErr != nil {
+			return sleepErr
+		}
+	}
+}
+
+// Makes a single request for the resource at path and returns the response body.
+func (c *Client) fetchOnce(
+	ctx context.Context, endpoint string, path string, args *url.Values) ([]byte, error) {
+	if c.apiKey == nil {
+		return nil, errors.New("riot.Client: no ApiKey provided")
+	}
+	region, err := c.lookupRegion()
+	if err != nil {
+		return nil, err
+	}
+	riotApiKey, err := c.apiKey()
+	if err != nil {
+		return nil, err
+	}
+	if c.rateLimit != nil {
+		if err := c.rateLimit(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// Copy args so that retries do not see the api_key added by a previous attempt.
+	argsCopy := url.Values{}
+	for k, vs := range *args {
+		argsCopy[k] = append([]string(nil), vs...)
+	}
+	loc, err := ComposeUrl(region, riotApiKey, path, &argsCopy)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("GET", loc, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	res, err := ctxhttp.Do(ctx, &http.Client{Transport: c.transport}, req)
+	if e, ok := err.(*url.Error); ok {
+		// Return the transport's error as is, like transport.RoundTrip would.
+		err = e.Err
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	jsonData, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, newAPIError(endpoint, c.region, req.URL, res)
+	}
+	return jsonData, nil
+}
@@ -0,0 +1,431 @@
+package riot
+
+import (
+	"flag"
+	"fmt"
+	"github.com/OwenDurni/loltools/riot/riottest"
+	"golang.org/x/net/context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Re-record fixtures against the live API with:
+//
+//	RIOT_API_KEY=... go test ./riot -record
+//
+// The summoners and matches referenced by the tests below must exist for the recorded
+// responses to be useful, so expect to adjust the tests when re-recording.
+var record = flag.Bool("record", false, "record fixtures from the live Riot API")
+
+const fixtureDir = "testdata/fixtures"
+
+func newFixtureClient(t *testing.T) (*Client, func()) {
+	if !*record {
+		replayer := &riottest.Replayer{Dir: fixtureDir}
+		c := NewClient(ClientOptions{
+			Transport: replayer,
+			ApiKey:    StaticApiKey("fixture-key"),
+			Retry:     &NoRetryPolicy,
+			Region:    "na",
+		})
+		return c, func() {
+			for _, f := range replayer.Missing() {
+				t.Errorf("missing fixture: %s", f)
+			}
+		}
+	}
+
+	riotApiKey := os.Getenv("RIOT_API_KEY")
+	if riotApiKey == "" {
+		contents, err := ioutil.ReadFile("../riot-api-key")
+		if err != nil {
+			t.Fatalf("-record requires RIOT_API_KEY or a riot-api-key file: %v", err)
+		}
+		riotApiKey = strings.TrimSpace(string(contents))
+	}
+	c := NewClient(ClientOptions{
+		Transport: &riottest.Recorder{Dir: fixtureDir},
+		ApiKey:    StaticApiKey(riotApiKey),
+		RateLimit: func(context.Context) error {
+			// Stay well under the development key limits.
+			time.Sleep(1200 * time.Millisecond)
+			return nil
+		},
+		Retry:  &NoRetryPolicy,
+		Region: "na",
+	})
+	return c, func() {}
+}
+
+func TestSummonerByName(t *testing.T) {
+	c, done := newFixtureClient(t)
+	defer done()
+
+	tests := []struct {
+		name      string
+		wantId    int64
+		wantLevel int
+		wantErrFn func(error) bool
+	}{
+		{name: "LolTools Tester", wantId: 1001, wantLevel: 30},
+		{name: "loltoolstester", wantId: 1001, wantLevel: 30},
+		{name: "Nobody Home", wantErrFn: IsNotFound},
+	}
+	for _, test := range tests {
+		dto, err := c.SummonerByName(context.Background(), test.name)
+		if test.wantErrFn != nil {
+			if !test.wantErrFn(err) {
+				t.Errorf("SummonerByName(%q): got error %v", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("SummonerByName(%q): %v", test.name, err)
+			continue
+		}
+		if dto.Id != test.wantId || dto.SummonerLevel != test.wantLevel {
+			t.Errorf("SummonerByName(%q): got %+v", test.name, dto)
+		}
+	}
+}
+
+func TestSummonersById(t *testing.T) {
+	c, done := newFixtureClient(t)
+	defer done()
+
+	dtos, err := c.SummonersById(context.Background(), 1001, 1002, 9999)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dtos) != 3 {
+		t.Fatalf("got %d summoners, want 3", len(dtos))
+	}
+	if dtos[0] == nil || dtos[0].Name != "LolTools Tester" {
+		t.Errorf("got summoner[0] %+v", dtos[0])
+	}
+	if dtos[1] == nil || dtos[1].Name != "Second Tester" {
+		t.Errorf("got summoner[1] %+v", dtos[1])
+	}
+	if dtos[2] != nil {
+		t.Errorf("got summoner[2] %+v, want nil", dtos[2])
+	}
+}
+
+func TestRunesBySummonerId(t *testing.T) {
+	c, done := newFixtureClient(t)
+	defer done()
+
+	dto, err := c.RunesBySummonerId(context.Background(), 1001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dto.Pages) != 2 || dto.Pages[1].Name != "483920175" || !dto.Pages[1].Current {
+		t.Errorf("got %+v", dto)
+	}
+}
+
+func TestSoloQueueRankBySummonerId(t *testing.T) {
+	c, done := newFixtureClient(t)
+	defer done()
+
+	tests := []struct {
+		summonerId int64
+		want       string
+	}{
+		// Ranked in solo queue and on a ranked team.
+		{1001, "GOLD II 45LP"},
+		// No league entries at all (404).
+		{1002, "Unranked"},
+		// Only ranked on a team.
+		{1003, "Unranked"},
+	}
+	for _, test := range tests {
+		rank, err := c.SoloQueueRankBySummonerId(context.Background(), test.summonerId)
+		if err != nil {
+			t.Errorf("SoloQueueRankBySummonerId(%d): %v", test.summonerId, err)
+			continue
+		}
+		if got := rank.String(); got != test.want {
+			t.Errorf("SoloQueueRankBySummonerId(%d): got %q, want %q",
+				test.summonerId, got, test.want)
+		}
+	}
+}
+
+func TestRankedStatsBySummonerId(t *testing.T) {
+	c, done := newFixtureClient(t)
+	defer done()
+
+	dto, err := c.RankedStatsBySummonerId(context.Background(), 1001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := -1
+	for _, champion := range dto.Champions {
+		if champion.ChampionId == ChampionStatsDto_AllChampions {
+			total = champion.Stats.TotalSessionsPlayed
+		}
+	}
+	if total != 116 {
+		t.Errorf("got %d total ranked games, want 116", total)
+	}
+}
+
+func TestRankedGameHistoryBySummonerIdSince(t *testing.T) {
+	c, done := newFixtureClient(t)
+	defer done()
+
+	since := RiotTime(time.Date(2016, 6, 1, 0, 0, 0, 0, time.UTC))
+	mlist, err := c.RankedGameHistoryBySummonerIdSince(context.Background(), 1001, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mlist.TotalGames != 2 || mlist.StartIndex != 0 || mlist.EndIndex != 2 {
+		t.Errorf("got %+v", mlist)
+	}
+	if len(mlist.Matches) != 2 || mlist.Matches[0].MatchId != 2002 ||
+		mlist.Matches[1].MatchId != 2001 {
+		t.Errorf("got matches %+v", mlist.Matches)
+	}
+}
+
+func TestMatchListQueryValues(t *testing.T) {
+	begin := RiotTime(time.Date(2016, 6, 1, 0, 0, 0, 0, time.UTC))
+	end := RiotTime(time.Date(2016, 7, 1, 0, 0, 0, 0, time.UTC))
+	tests := []struct {
+		query MatchListQuery
+		want  string
+	}{
+		{MatchListQuery{}, ""},
+		{MatchListQuery{BeginTime: begin}, "beginTime=1464739200000"},
+		{
+			MatchListQuery{
+				ChampionIds:  []int{103, 7},
+				RankedQueues: []string{"RANKED_SOLO_5x5", "TEAM_BUILDER_DRAFT_RANKED_5x5"},
+				Seasons:      []string{"SEASON2016"},
+				BeginTime:    begin,
+				EndTime:      end,
+				BeginIndex:   20,
+				EndIndex:     40,
+			},
+			"beginIndex=20&beginTime=1464739200000&championIds=103%2C7&endIndex=40" +
+				"&endTime=1467331200000" +
+				"&rankedQueues=RANKED_SOLO_5x5%2CTEAM_BUILDER_DRAFT_RANKED_5x5" +
+				"&seasons=SEASON2016",
+		},
+	}
+	for _, test := range tests {
+		if got := test.query.values().Encode(); got != test.want {
+			t.Errorf("%+v: got %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+func TestMatchListIterator(t *testing.T) {
+	c, done := newFixtureClient(t)
+	defer done()
+
+	defer func(pageSize int) { matchListPageSize = pageSize }(matchListPageSize)
+	matchListPageSize = 2
+
+	it := c.MatchListIterator(1001, &MatchListQuery{ChampionIds: []int{103}})
+	var got []int64
+	for {
+		ref, err := it.Next(context.Background())
+		if err == Done {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, ref.MatchId)
+	}
+	if fmt.Sprint(got) != "[2003 2002 2001]" {
+		t.Errorf("got matches %v", got)
+	}
+	if it.TotalGames() != 3 {
+		t.Errorf("got TotalGames %d, want 3", it.TotalGames())
+	}
+	if _, err := it.Next(context.Background()); err != Done {
+		t.Errorf("got %v after the last match, want Done", err)
+	}
+}
+
+func TestGameStatsForPlayer(t *testing.T) {
+	c, done := newFixtureClient(t)
+	defer done()
+
+	dto, err := c.GameStatsForPlayer(context.Background(), 1001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dto.Games) != 1 {
+		t.Fatalf("got %d games, want 1", len(dto.Games))
+	}
+	game := dto.Games[0]
+	if game.GameId != 2002 || len(game.FellowPlayers) != 2 || !game.Stats.Win {
+		t.Errorf("got %+v", game)
+	}
+	// Fields copied into the stats during post-processing.
+	if game.Stats.ChampionId != 103 || game.Stats.SummonerSpell1 != 4 ||
+		game.Stats.SummonerSpell2 != 14 {
+		t.Errorf("got stats %+v", game.Stats)
+	}
+}
+
+func TestLookupMatch(t *testing.T) {
+	c, done := newFixtureClient(t)
+	defer done()
+
+	tests := []struct {
+		matchId   int64
+		wantErrFn func(error) bool
+	}{
+		{matchId: 2001},
+		{matchId: 2999, wantErrFn: IsNotFound},
+	}
+	for _, test := range tests {
+		match, err := c.LookupMatch(context.Background(), test.matchId)
+		if test.wantErrFn != nil {
+			if !test.wantErrFn(err) {
+				t.Errorf("LookupMatch(%d): got error %v", test.matchId, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("LookupMatch(%d): %v", test.matchId, err)
+			continue
+		}
+		if match.MatchId != test.matchId || match.MatchType != "CUSTOM_GAME" {
+			t.Errorf("LookupMatch(%d): got %+v", test.matchId, match)
+		}
+		if len(match.Participants) != 10 || len(match.ParticipantIdentities) != 10 {
+			t.Errorf("LookupMatch(%d): got %d participants", test.matchId, len(match.Participants))
+		}
+		if p := match.ParticipantIdentities[0].Player; p.SummonerId != 1001 {
+			t.Errorf("LookupMatch(%d): got participant 1 %+v", test.matchId, p)
+		}
+		if len(match.Teams) != 2 || !match.Teams[0].Winner || len(match.Teams[1].Bans) != 3 {
+			t.Errorf("LookupMatch(%d): got teams %+v", test.matchId, match.Teams)
+		}
+		if match.Timeline == nil || len(match.Timeline.Frames) != 3 {
+			t.Fatalf("LookupMatch(%d): got timeline %+v", test.matchId, match.Timeline)
+		}
+		frame := match.Timeline.Frames[2]
+		if len(frame.ParticipantFrames) != 10 || len(frame.Events) != 1 ||
+			frame.Events[0].EventType != "CHAMPION_KILL" {
+			t.Errorf("LookupMatch(%d): got frame %+v", test.matchId, frame)
+		}
+	}
+}
+
+func TestReplayerFailsOnUnrecordedRequest(t *testing.T) {
+	replayer := &riottest.Replayer{Dir: fixtureDir}
+	c := NewClient(ClientOptions{
+		Transport: replayer,
+		ApiKey:    StaticApiKey("fixture-key"),
+		Retry:     &NoRetryPolicy,
+		Region:    "na",
+	})
+	_, err := c.LookupMatch(context.Background(), 1)
+	if _, ok := err.(riottest.ErrNoFixture); !ok {
+		t.Errorf("got error %v, want riottest.ErrNoFixture", err)
+	}
+	if len(replayer.Missing()) != 1 {
+		t.Errorf("got missing fixtures %v", replayer.Missing())
+	}
+}
+
+func TestChampionMastery(t *testing.T) {
+	c, done := newFixtureClient(t)
+	defer done()
+
+	all, err := c.ChampionMasteries(context.Background(), 1001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 || all[0].ChampionId != 103 || all[0].ChampionPoints != 84210 {
+		t.Errorf("ChampionMasteries: got %+v", all)
+	}
+
+	top, err := c.TopChampionMasteries(context.Background(), 1001, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 3 || top[2].ChampionId != 7 || top[2].ChampionLevel != 4 {
+		t.Errorf("TopChampionMasteries: got %+v", top)
+	}
+
+	tests := []struct {
+		championId int
+		wantLevel  int
+		wantPoints int
+	}{
+		{103, 5, 84210},
+		// Never played.
+		{12, 0, 0},
+	}
+	for _, test := range tests {
+		dto, err := c.ChampionMastery(context.Background(), 1001, test.championId)
+		if err != nil {
+			t.Errorf("ChampionMastery(%d): %v", test.championId, err)
+			continue
+		}
+		if dto.ChampionId != test.championId || dto.ChampionLevel != test.wantLevel ||
+			dto.ChampionPoints != test.wantPoints {
+			t.Errorf("ChampionMastery(%d): got %+v", test.championId, dto)
+		}
+	}
+
+	score, err := c.ChampionMasteryScore(context.Background(), 1001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score != 16 {
+		t.Errorf("ChampionMasteryScore: got %d, want 16", score)
+	}
+}
+
+func TestCurrentGameBySummonerId(t *testing.T) {
+	c, done := newFixtureClient(t)
+	defer done()
+
+	game, err := c.CurrentGameBySummonerId(context.Background(), 1001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game == nil || game.GameId != 2100 || len(game.Participants) != 10 ||
+		len(game.BannedChampions) != 6 {
+		t.Fatalf("got %+v", game)
+	}
+	if p := game.Participants[0]; p.SummonerId != 1001 || p.ChampionId != 103 {
+		t.Errorf("got participant %+v", p)
+	}
+	if b := game.BannedChampions[1]; b.TeamId != PurpleTeamId || b.PickTurn != 2 {
+		t.Errorf("got ban %+v", b)
+	}
+
+	// Not in a game.
+	game, err = c.CurrentGameBySummonerId(context.Background(), 1002)
+	if game != nil || err != nil {
+		t.Errorf("got %+v, %v; want nil, nil", game, err)
+	}
+}
+
+func TestFeaturedGames(t *testing.T) {
+	c, done := newFixtureClient(t)
+	defer done()
+
+	featured, err := c.FeaturedGames(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if featured.ClientRefreshInterval != 300 || len(featured.GameList) != 1 {
+		t.Fatalf("got %+v", featured)
+	}
+	if game := featured.GameList[0]; game.GameId != 2200 || len(game.Participants) != 10 {
+		t.Errorf("got game %+v", game)
+	}
+}
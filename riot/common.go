@@ -9,28 +9,15 @@ const (
 	PurpleTeamId = 200
 )
 
-const (
-	baseUrl = "https://na.api.pvp.net"
-)
-
-type NotFound struct{}
-
-func (e NotFound) Error() string {
-	return "Riot API 404"
-}
-
-func BaseUrl() (url *url.URL) {
-	url, err := url.Parse(baseUrl)
+// Returns the full url for an API path in the given region.
+func ComposeUrl(
+	region *Region, riotApiKey string, path string, args *url.Values) (string, error) {
+	u, err := region.baseUrl()
 	if err != nil {
-		panic(err)
+		return "", err
 	}
-	return
-}
-
-func ComposeUrl(riotApiKey string, path string, args *url.Values) string {
-	u := BaseUrl()
 	u.Path += path
 	args.Add("api_key", riotApiKey)
 	u.RawQuery = args.Encode()
-	return u.String()
+	return u.String(), nil
 }
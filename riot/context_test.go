@@ -0,0 +1,108 @@
+package riot
+
+import (
+	"golang.org/x/net/context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestFetchStopsWhenCanceled(t *testing.T) {
+	server := newScriptedServer(scriptedResponse{status: 200, body: matchJson})
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var sleeps []time.Duration
+	_, err := server.client(testPolicy, &sleeps).LookupMatch(ctx, 42)
+	if err != context.Canceled || !IsCanceled(err) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if n := server.numRequests(); n != 0 {
+		t.Errorf("got %d requests, want 0", n)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c := NewClient(ClientOptions{
+		ApiKey:         StaticApiKey("test-key"),
+		Retry:          &NoRetryPolicy,
+		RequestTimeout: 50 * time.Millisecond,
+		Region:         "na",
+		Regions:        Regions.WithBaseUrl(server.URL),
+	})
+	start := time.Now()
+	_, err := c.LookupMatch(context.Background(), 42)
+	if err != context.DeadlineExceeded {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("request took %v", elapsed)
+	}
+}
+
+func TestRetryNotWaitedPastDeadline(t *testing.T) {
+	server := newScriptedServer(
+		scriptedResponse{status: 429, headers: map[string]string{"Retry-After": "60"}},
+		scriptedResponse{status: 200, body: matchJson})
+	defer server.Close()
+
+	var sleeps []time.Duration
+	c := server.client(testPolicy, &sleeps)
+	c.now = time.Now
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := c.LookupMatch(ctx, 42); !IsRateLimited(err) {
+		t.Errorf("got %v, want a 429", err)
+	}
+	if len(sleeps) != 0 {
+		t.Errorf("got sleeps %v, want none", sleeps)
+	}
+}
+
+func TestSummonersByIdStopsBetweenBatches(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var paths []string
+	c := NewClient(ClientOptions{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			paths = append(paths, req.URL.Path)
+			// Cancel once the first batch has been sent.
+			cancel()
+			rec := httptest.NewRecorder()
+			rec.WriteHeader(http.StatusNotFound)
+			return rec.Result(), nil
+		}),
+		ApiKey: StaticApiKey("test-key"),
+		Retry:  &NoRetryPolicy,
+		Region: "na",
+	})
+
+	ids := make([]int64, 50)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+	_, err := c.SummonersById(ctx, ids...)
+	if err != context.Canceled {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if len(paths) != 1 {
+		t.Errorf("got requests %v, want only the first batch", paths)
+	}
+}
@@ -0,0 +1,113 @@
+package riot
+
+import (
+	"fmt"
+	"golang.org/x/net/context"
+	"net/url"
+)
+
+// current-game-v1.0: https://developer.riotgames.com/api/methods#!/976
+type CurrentGameInfo struct {
+	BannedChampions   []*CurrentGameBannedChampion `json:"bannedChampions"`
+	GameId            int64                        `json:"gameId"`
+	GameLength        int64                        `json:"gameLength"`
+	GameMode          string                       `json:"gameMode"`
+	GameQueueConfigId int64                        `json:"gameQueueConfigId"`
+	GameStartTime     RiotTime                     `json:"gameStartTime"`
+	GameType          string                       `json:"gameType"`
+	MapId             int                          `json:"mapId"`
+	Observers         *Observer                    `json:"observers"`
+	Participants      []*CurrentGameParticipant    `json:"participants"`
+	PlatformId        string                       `json:"platformId"`
+}
+
+// current-game-v1.0: https://developer.riotgames.com/api/methods#!/976
+type CurrentGameBannedChampion struct {
+	ChampionId int `json:"championId"`
+	PickTurn   int `json:"pickTurn"`
+	TeamId     int `json:"teamId"`
+}
+
+// current-game-v1.0: https://developer.riotgames.com/api/methods#!/976
+type Observer struct {
+	EncryptionKey string `json:"encryptionKey"`
+}
+
+// current-game-v1.0: https://developer.riotgames.com/api/methods#!/976
+type CurrentGameParticipant struct {
+	Bot           bool   `json:"bot"`
+	ChampionId    int    `json:"championId"`
+	ProfileIconId int    `json:"profileIconId"`
+	Spell1Id      int    `json:"spell1Id"`
+	Spell2Id      int    `json:"spell2Id"`
+	SummonerId    int64  `json:"summonerId"`
+	SummonerName  string `json:"summonerName"`
+	TeamId        int    `json:"teamId"`
+}
+
+// featured-games-v1.0: https://developer.riotgames.com/api/methods#!/977
+type FeaturedGames struct {
+	ClientRefreshInterval int64               `json:"clientRefreshInterval"`
+	GameList              []*FeaturedGameInfo `json:"gameList"`
+}
+
+// featured-games-v1.0: https://developer.riotgames.com/api/methods#!/977
+type FeaturedGameInfo struct {
+	BannedChampions   []*CurrentGameBannedChampion `json:"bannedChampions"`
+	GameId            int64                        `json:"gameId"`
+	GameLength        int64                        `json:"gameLength"`
+	GameMode          string                       `json:"gameMode"`
+	GameQueueConfigId int64                        `json:"gameQueueConfigId"`
+	GameStartTime     RiotTime                     `json:"gameStartTime"`
+	GameType          string                       `json:"gameType"`
+	MapId             int                          `json:"mapId"`
+	Observers         *Observer                    `json:"observers"`
+	Participants      []*FeaturedGameParticipant   `json:"participants"`
+	PlatformId        string                       `json:"platformId"`
+}
+
+// featured-games-v1.0: https://developer.riotgames.com/api/methods#!/977
+//
+// Unlike CurrentGameParticipant there is no summoner id.
+type FeaturedGameParticipant struct {
+	Bot           bool   `json:"bot"`
+	ChampionId    int    `json:"championId"`
+	ProfileIconId int    `json:"profileIconId"`
+	Spell1Id      int    `json:"spell1Id"`
+	Spell2Id      int    `json:"spell2Id"`
+	SummonerName  string `json:"summonerName"`
+	TeamId        int    `json:"teamId"`
+}
+
+// Returns the game the summoner is playing right now, or nil if they are not in one.
+func (c *Client) CurrentGameBySummonerId(
+	ctx context.Context, summonerId int64) (*CurrentGameInfo, error) {
+	region, err := c.lookupRegion()
+	if err != nil {
+		return nil, err
+	}
+	game := new(CurrentGameInfo)
+	err = c.fetch(
+		ctx, "current-game-v1.0",
+		fmt.Sprintf("/observer-mode/rest/consumer/getSpectatorGameInfo/%s/%d",
+			region.PlatformId, summonerId),
+		&url.Values{}, game)
+	if IsNotFound(err) {
+		// Not in a game.
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+// Returns the games currently featured in the client for the region.
+func (c *Client) FeaturedGames(ctx context.Context) (*FeaturedGames, error) {
+	games := new(FeaturedGames)
+	err := c.fetch(
+		ctx, "featured-games-v1.0", "/observer-mode/rest/featured", &url.Values{}, games)
+	if err != nil {
+		return nil, err
+	}
+	return games, nil
+}
@@ -0,0 +1,152 @@
+package riot
+
+import (
+	"fmt"
+	"golang.org/x/net/context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Identifies who imposed a rate limit that caused a 429 response.
+type RateLimitType int
+
+const (
+	// The response was not caused by a rate limit.
+	RateLimitNone RateLimitType = iota
+
+	// The limit is on our API key (X-Rate-Limit-Type: application or method). Requests
+	// are rejected until the window in Retry-After passes.
+	RateLimitApplication
+
+	// The limit is on the underlying Riot service and is shared by all API keys. The
+	// response has no Retry-After so callers should back off on their own.
+	RateLimitService
+)
+
+func (t RateLimitType) String() string {
+	switch t {
+	case RateLimitApplication:
+		return "application"
+	case RateLimitService:
+		return "service"
+	}
+	return "none"
+}
+
+// An APIError is returned for any non-200 response from the Riot API.
+type APIError struct {
+	// The HTTP status code of the response.
+	StatusCode int
+
+	// The API the request was made to (ex: "match-v2.2").
+	Endpoint string
+
+	// The region the request was made against.
+	Region string
+
+	// The request path and query with the api_key parameter removed.
+	Path string
+
+	// How long Riot asked us to wait before retrying. Zero if not specified.
+	RetryAfter time.Duration
+
+	// Only meaningful if StatusCode is 429.
+	RateLimitType RateLimitType
+}
+
+func (e *APIError) Error() string {
+	s := fmt.Sprintf("Riot API %d (%s %s): %s", e.StatusCode, e.Region, e.Endpoint, e.Path)
+	if e.StatusCode == 429 {
+		s += fmt.Sprintf(" [%s rate limit", e.RateLimitType)
+		if e.RetryAfter > 0 {
+			s += fmt.Sprintf(", retry after %v", e.RetryAfter)
+		}
+		s += "]"
+	}
+	return s
+}
+
+func newAPIError(endpoint string, region string, u *url.URL, res *http.Response) *APIError {
+	e := new(APIError)
+	e.StatusCode = res.StatusCode
+	e.Endpoint = endpoint
+	e.Region = region
+	e.Path = redactedPath(u)
+	e.RetryAfter = parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+	if e.StatusCode == 429 {
+		switch res.Header.Get("X-Rate-Limit-Type") {
+		case "application", "method", "user":
+			e.RateLimitType = RateLimitApplication
+		default:
+			e.RateLimitType = RateLimitService
+		}
+	}
+	return e
+}
+
+// Returns the path and query of u without the api_key parameter.
+func redactedPath(u *url.URL) string {
+	args := u.Query()
+	args.Del("api_key")
+	if len(args) == 0 {
+		return u.Path
+	}
+	return fmt.Sprintf("%s?%s", u.Path, args.Encode())
+}
+
+// Parses a Retry-After header, which is either a number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+func asAPIError(err error) (*APIError, bool) {
+	e, ok := err.(*APIError)
+	return e, ok
+}
+
+// True if err is a 429 from the Riot API.
+func IsRateLimited(err error) bool {
+	e, ok := asAPIError(err)
+	return ok && e.StatusCode == 429
+}
+
+// True if err is a 404 from the Riot API.
+func IsNotFound(err error) bool {
+	e, ok := asAPIError(err)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+// True if err is a 401 or 403 from the Riot API, which usually means the API key is
+// missing, invalid or blacklisted.
+func IsUnauthorized(err error) bool {
+	e, ok := asAPIError(err)
+	return ok && (e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden)
+}
+
+// True if err is a 5XX from the Riot API.
+func IsServerError(err error) bool {
+	e, ok := asAPIError(err)
+	return ok && 500 <= e.StatusCode && e.StatusCode < 600
+}
+
+// True if err means a request was abandoned because its context was cancelled or its
+// deadline passed.
+func IsCanceled(err error) bool {
+	return err == context.Canceled || err == context.DeadlineExceeded
+}
@@ -0,0 +1,87 @@
+package riot
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// A Cache that keeps each entry in its own file under Dir. Meant for tools that are
+// run repeatedly against the same summoners and matches.
+//
+// Each file holds the expiry time in Unix nanoseconds (0 for never) on the first line,
+// the key on the second, and the data after that.
+type FileCache struct {
+	Dir string
+
+	// Overridden in tests.
+	now func() time.Time
+}
+
+func (fc *FileCache) clock() time.Time {
+	if fc.now != nil {
+		return fc.now()
+	}
+	return time.Now()
+}
+
+func (fc *FileCache) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(fc.Dir, hex.EncodeToString(sum[:]))
+}
+
+func (fc *FileCache) Get(key string) ([]byte, bool) {
+	contents, err := ioutil.ReadFile(fc.path(key))
+	if err != nil {
+		return nil, false
+	}
+	parts := bytes.SplitN(contents, []byte("\n"), 3)
+	if len(parts) != 3 || string(parts[1]) != key {
+		return nil, false
+	}
+	expires, err := strconv.ParseInt(string(parts[0]), 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	if expires != 0 && fc.clock().UnixNano() >= expires {
+		os.Remove(fc.path(key))
+		return nil, false
+	}
+	return parts[2], true
+}
+
+func (fc *FileCache) Set(key string, data []byte, ttl time.Duration) {
+	var expires int64
+	if ttl != CacheForever {
+		expires = fc.clock().Add(ttl).UnixNano()
+	}
+	if err := os.MkdirAll(fc.Dir, 0755); err != nil {
+		return
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d\n%s\n", expires, key)
+	buf.Write(data)
+
+	// Write to a temporary file first so that readers never see a partial entry.
+	tmp, err := ioutil.TempFile(fc.Dir, "tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), fc.path(key)); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
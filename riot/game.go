@@ -1,8 +1,8 @@
 package riot
 
 import (
-	"encoding/json"
 	"fmt"
+	"golang.org/x/net/context"
 	"net/url"
 )
 
@@ -154,26 +154,16 @@ type RawStatsDto struct {
 	//TeamObjective        int `json:"teamObjective"`
 }
 
-func GameStatsForPlayer(
-	urlFetcher func(string) ([]byte, error),
-	rateLimiter func(),
-	riotApiKey string,
-	region string,
-	riotSummonerId int64) (*RecentGamesDto, error) {
-	loc := ComposeUrl(
-		riotApiKey,
-		fmt.Sprintf("/api/lol/%s/v1.3/game/by-summoner/%d/recent", region, riotSummonerId),
-		&url.Values{})
+func (c *Client) GameStatsForPlayer(
+	ctx context.Context, riotSummonerId int64) (*RecentGamesDto, error) {
 	g := new(RecentGamesDto)
 	g.SummonerId = riotSummonerId
 
-	rateLimiter()
-	jsonData, err := urlFetcher(loc)
-	if err != nil {
-		return nil, err
-	}
-
-	if err = json.Unmarshal(jsonData, g); err == nil {
+	err := c.fetch(
+		ctx, "game-v1.3",
+		fmt.Sprintf("/api/lol/%s/v1.3/game/by-summoner/%d/recent", c.region, riotSummonerId),
+		&url.Values{}, g)
+	if err == nil {
 		// Do some post-processing.
 		for i, _ := range g.Games {
 			var gameDto *GameDto = &g.Games[i]
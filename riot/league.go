@@ -1,9 +1,9 @@
 package riot
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
+	"golang.org/x/net/context"
 	"net/url"
 	"strconv"
 )
@@ -35,27 +35,14 @@ func (r *Rank) String() string {
 	return fmt.Sprintf("%s %s %dLP", r.Tier, r.Division, r.LeaguePoints)
 }
 
-func LeagueInfoBySummonerId(
-	urlFetcher func(string) ([]byte, int, error),
-	rateLimiter func(),
-	riotApiKey string,
-	region string,
-	summonerId int64) ([]*LeagueDto, error) {
-	loc := ComposeUrl(
-		riotApiKey,
-		fmt.Sprintf("/api/lol/%s/v2.5/league/by-summoner/%d/entry",
-			region, summonerId),
-		&url.Values{})
-	rateLimiter()
-	jsonData, httpStatus, err := urlFetcher(loc)
-	if err != nil {
-		return nil, err
-	}
-	if httpStatus == 404 {
-		return nil, NotFound{}
-	}
+func (c *Client) LeagueInfoBySummonerId(
+	ctx context.Context, summonerId int64) ([]*LeagueDto, error) {
 	data := make(map[string][]*LeagueDto)
-	err = json.Unmarshal(jsonData, &data)
+	err := c.fetch(
+		ctx, "league-v2.5",
+		fmt.Sprintf("/api/lol/%s/v2.5/league/by-summoner/%d/entry",
+			c.region, summonerId),
+		&url.Values{}, &data)
 	if err != nil {
 		return nil, err
 	}
@@ -65,16 +52,11 @@ func LeagueInfoBySummonerId(
 	return nil, errors.New("Riot data did not contain info for this summoner")
 }
 
-func SoloQueueRankBySummonerId(
-	urlFetcher func(string) ([]byte, int, error),
-	rateLimiter func(),
-	riotApiKey string,
-	region string,
-	summonerId int64) (*Rank, error) {
-	leagueDtos, err := LeagueInfoBySummonerId(
-		urlFetcher, rateLimiter, riotApiKey, region, summonerId)
+func (c *Client) SoloQueueRankBySummonerId(
+	ctx context.Context, summonerId int64) (*Rank, error) {
+	leagueDtos, err := c.LeagueInfoBySummonerId(ctx, summonerId)
 	if err != nil {
-		if _, ok := err.(NotFound); ok {
+		if IsNotFound(err) {
 			// No league exists for this summoner id.
 			return &Rank{"Unranked", "", 0}, nil
 		}
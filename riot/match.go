@@ -1,8 +1,8 @@
 package riot
 
 import (
-	"encoding/json"
 	"fmt"
+	"golang.org/x/net/context"
 	"net/url"
 )
 
@@ -255,26 +255,15 @@ type Position struct {
 	Y int `json:"y"`
 }
 
-func LookupMatch(
-	urlFetcher func(string) ([]byte, int, error),
-	rateLimiter func(),
-	riotApiKey string,
-	region string,
-	matchId int64) (*MatchDetail, error) {
-	loc := ComposeUrl(
-		riotApiKey,
-		fmt.Sprintf("/api/lol/%s/v2.2/match/%d", region, matchId),
-		&url.Values{})
-	rateLimiter()
-	jsonData, httpStatus, err := urlFetcher(loc)
-	if err != nil {
-		return nil, err
-	}
-	if httpStatus == 404 {
-		return nil, NotFound{}
-	}
+// Returns the details of a match, including its timeline.
+func (c *Client) LookupMatch(ctx context.Context, matchId int64) (*MatchDetail, error) {
 	match := new(MatchDetail)
-	err = json.Unmarshal(jsonData, &match)
+	args := &url.Values{}
+	args.Add("includeTimeline", "true")
+	err := c.fetch(
+		ctx, "match-v2.2",
+		fmt.Sprintf("/api/lol/%s/v2.2/match/%d", c.region, matchId),
+		args, match)
 	if err != nil {
 		return nil, err
 	}
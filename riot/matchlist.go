@@ -1,15 +1,19 @@
 package riot
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"golang.org/x/net/context"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // matchlist-v2.2: https://developer.riotgames.com/api/methods#!/1069
 type MatchList struct {
 	EndIndex   int               `json:"endIndex"`
-	Matches    []*MatchReference `json:"matches'`
+	Matches    []*MatchReference `json:"matches"`
 	StartIndex int               `json:"startIndex"`
 	TotalGames int               `json:"totalGames"`
 }
@@ -27,26 +31,153 @@ type MatchReference struct {
 	Timestamp  RiotTime `json:"timestamp"`
 }
 
-func RankedGameHistoryBySummonerIdSince(
-	urlFetcher func(string) ([]byte, int, error),
-	rateLimiter func(),
-	riotApiKey string,
-	region string,
-	summonerId int64,
-	startDateTime RiotTime) (*MatchList, error) {
-	loc := ComposeUrl(
-		riotApiKey,
-		fmt.Sprintf("/api/lol/%s/v2.2/matchlist/by-summoner/%d",
-			region, summonerId),
-		&url.Values{
-			"beginTime": []string{startDateTime.UnixMillisString()},
-		})
-	rateLimiter()
-	jsonData, _, err := urlFetcher(loc)
-	if err != nil {
-		return nil, err
+// Filters for a matchlist request. Zero values are left out of the request.
+//
+// matchlist-v2.2: https://developer.riotgames.com/api/methods#!/1069
+type MatchListQuery struct {
+	ChampionIds []int
+	// Ex: "RANKED_SOLO_5x5", "TEAM_BUILDER_DRAFT_RANKED_5x5".
+	RankedQueues []string
+	// Ex: "SEASON2016".
+	Seasons []string
+
+	BeginTime RiotTime
+	EndTime   RiotTime
+
+	// The range [BeginIndex, EndIndex) of the summoner's matches, newest first.
+	BeginIndex int
+	EndIndex   int
+}
+
+func (q *MatchListQuery) values() *url.Values {
+	args := &url.Values{}
+	if len(q.ChampionIds) > 0 {
+		ids := make([]string, len(q.ChampionIds))
+		for i, id := range q.ChampionIds {
+			ids[i] = strconv.Itoa(id)
+		}
+		args.Set("championIds", strings.Join(ids, ","))
+	}
+	if len(q.RankedQueues) > 0 {
+		args.Set("rankedQueues", strings.Join(q.RankedQueues, ","))
+	}
+	if len(q.Seasons) > 0 {
+		args.Set("seasons", strings.Join(q.Seasons, ","))
+	}
+	if !time.Time(q.BeginTime).IsZero() {
+		args.Set("beginTime", q.BeginTime.UnixMillisString())
 	}
+	if !time.Time(q.EndTime).IsZero() {
+		args.Set("endTime", q.EndTime.UnixMillisString())
+	}
+	if q.BeginIndex > 0 {
+		args.Set("beginIndex", strconv.Itoa(q.BeginIndex))
+	}
+	if q.EndIndex > 0 {
+		args.Set("endIndex", strconv.Itoa(q.EndIndex))
+	}
+	return args
+}
+
+// Returns one page of the summoner's ranked matches. Use MatchListIterator to walk
+// every page.
+func (c *Client) MatchList(
+	ctx context.Context, summonerId int64, q *MatchListQuery) (*MatchList, error) {
 	mlist := new(MatchList)
-	err = json.Unmarshal(jsonData, &mlist)
+	err := c.fetch(
+		ctx, "matchlist-v2.2",
+		fmt.Sprintf("/api/lol/%s/v2.2/matchlist/by-summoner/%d",
+			c.region, summonerId),
+		q.values(), mlist)
 	return mlist, err
 }
+
+func (c *Client) RankedGameHistoryBySummonerIdSince(
+	ctx context.Context,
+	summonerId int64,
+	startDateTime RiotTime) (*MatchList, error) {
+	return c.MatchList(ctx, summonerId, &MatchListQuery{BeginTime: startDateTime})
+}
+
+// The number of matches requested per page by a MatchListIterator.
+var matchListPageSize = 20
+
+// Returned by MatchListIterator.Next when there are no more matches.
+var Done = errors.New("riot: no more items in iterator")
+
+// Walks every match of a summoner that matches a query, one page at a time.
+type MatchListIterator struct {
+	c          *Client
+	summonerId int64
+	query      MatchListQuery
+
+	// The index of the next page to fetch, and the end of the requested range (or 0
+	// for everything).
+	next int
+	end  int
+
+	page       []*MatchReference
+	totalGames int
+	done       bool
+}
+
+// Returns an iterator over the summoner's matches. The BeginIndex and EndIndex of q,
+// when set, limit the range that is walked.
+func (c *Client) MatchListIterator(summonerId int64, q *MatchListQuery) *MatchListIterator {
+	it := &MatchListIterator{
+		c:          c,
+		summonerId: summonerId,
+		query:      *q,
+		next:       q.BeginIndex,
+		end:        q.EndIndex,
+	}
+	return it
+}
+
+// Returns the next match, fetching the next page if needed. Returns Done when every
+// match has been returned.
+func (it *MatchListIterator) Next(ctx context.Context) (*MatchReference, error) {
+	for len(it.page) == 0 {
+		if it.done {
+			return nil, Done
+		}
+		if err := it.fetchPage(ctx); err != nil {
+			return nil, err
+		}
+	}
+	ref := it.page[0]
+	it.page = it.page[1:]
+	return ref, nil
+}
+
+// The total number of matches for the query, as reported by the last page fetched.
+func (it *MatchListIterator) TotalGames() int {
+	return it.totalGames
+}
+
+func (it *MatchListIterator) fetchPage(ctx context.Context) error {
+	q := it.query
+	q.BeginIndex = it.next
+	q.EndIndex = it.next + matchListPageSize
+	if it.end > 0 && q.EndIndex > it.end {
+		q.EndIndex = it.end
+	}
+
+	mlist, err := it.c.MatchList(ctx, it.summonerId, &q)
+	if IsNotFound(err) {
+		// The summoner has no matches.
+		it.done = true
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	it.page = mlist.Matches
+	it.totalGames = mlist.TotalGames
+	it.next += len(mlist.Matches)
+	if len(mlist.Matches) == 0 || it.next >= mlist.TotalGames ||
+		(it.end > 0 && it.next >= it.end) {
+		it.done = true
+	}
+	return nil
+}
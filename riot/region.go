@@ -0,0 +1,91 @@
+package riot
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Describes how to reach the Riot API and related sites for one region.
+type Region struct {
+	// The region code used in API paths and by the rest of the app (ex: "euw").
+	Code string
+
+	// The scheme and host of the regional API endpoint (ex: "https://euw.api.pvp.net").
+	BaseUrl string
+
+	// The platform id used by match history and newer endpoints (ex: "EUW1").
+	PlatformId string
+
+	// The subdomain of the match history site (ex: "euw" for
+	// matchhistory.euw.leagueoflegends.com).
+	MatchHistorySlug string
+}
+
+func (r *Region) baseUrl() (*url.URL, error) {
+	return url.Parse(r.BaseUrl)
+}
+
+// Returns a link to the official match history page for a game in this region.
+func (r *Region) MatchHistoryUrl(language string, gameId int64) string {
+	return fmt.Sprintf(
+		"http://matchhistory.%s.leagueoflegends.com/%s/#match-details/%s/%d",
+		r.MatchHistorySlug, language, r.PlatformId, gameId)
+}
+
+// An ordered list of known regions.
+type RegionRegistry []*Region
+
+// The regions supported by the app. Tests and local development may replace this
+// (see WithBaseUrl) to send requests somewhere other than the live API.
+var Regions = RegionRegistry{
+	{"na", "https://na.api.pvp.net", "NA1", "na"},
+	{"euw", "https://euw.api.pvp.net", "EUW1", "euw"},
+	{"eune", "https://eune.api.pvp.net", "EUN1", "eune"},
+	{"kr", "https://kr.api.pvp.net", "KR", "kr"},
+	{"br", "https://br.api.pvp.net", "BR1", "br"},
+	{"lan", "https://lan.api.pvp.net", "LA1", "lan"},
+	{"las", "https://las.api.pvp.net", "LA2", "las"},
+	{"oce", "https://oce.api.pvp.net", "OC1", "oce"},
+	{"tr", "https://tr.api.pvp.net", "TR1", "tr"},
+	{"ru", "https://ru.api.pvp.net", "RU", "ru"},
+	{"jp", "https://jp.api.pvp.net", "JP1", "jp"},
+}
+
+type ErrUnknownRegion struct {
+	Code string
+}
+
+func (e ErrUnknownRegion) Error() string {
+	return fmt.Sprintf("Unknown region: %s", e.Code)
+}
+
+// Returns the region with the given code.
+func (rr RegionRegistry) Lookup(code string) (*Region, error) {
+	for _, r := range rr {
+		if r.Code == code {
+			return r, nil
+		}
+	}
+	return nil, ErrUnknownRegion{code}
+}
+
+// Returns the codes of all regions in order.
+func (rr RegionRegistry) Codes() []string {
+	codes := make([]string, len(rr))
+	for i, r := range rr {
+		codes[i] = r.Code
+	}
+	return codes
+}
+
+// Returns a copy of the registry with every region's BaseUrl set to baseUrl. This is
+// used to point clients at a local server (ex: "http://localhost:8081").
+func (rr RegionRegistry) WithBaseUrl(baseUrl string) RegionRegistry {
+	ret := make(RegionRegistry, len(rr))
+	for i, r := range rr {
+		region := *r
+		region.BaseUrl = baseUrl
+		ret[i] = &region
+	}
+	return ret
+}
@@ -0,0 +1,64 @@
+package riot
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Controls how a Client retries requests that fail with a 429 or 5XX response.
+type RetryPolicy struct {
+	// The maximum number of times a request is sent, including the first attempt.
+	// Values less than 2 disable retries.
+	MaxAttempts int
+
+	// The wait before the first retry. Each following retry waits twice as long as the
+	// one before it, up to MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	// The fraction (0.0 to 1.0) of each backoff that is randomized so that concurrent
+	// callers do not retry in lockstep.
+	Jitter float64
+
+	// Retrying stops once this much time has passed since the first attempt, or if the
+	// next wait would end after it. Zero means no deadline.
+	Deadline time.Duration
+}
+
+// Used when ClientOptions.Retry is nil.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseBackoff: 1 * time.Second,
+	MaxBackoff:  10 * time.Second,
+	Jitter:      0.2,
+	Deadline:    30 * time.Second,
+}
+
+// Disables retries.
+var NoRetryPolicy = RetryPolicy{MaxAttempts: 1}
+
+// Returns true if a request that failed with err should be retried.
+func isRetryable(err error) bool {
+	return IsRateLimited(err) || IsServerError(err)
+}
+
+// Returns how long to wait before sending attempt number `attempt` (the first retry is
+// attempt 1) after a request failed with err.
+func (p *RetryPolicy) backoff(attempt int, err error) time.Duration {
+	d := p.BaseBackoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+
+	// Never retry sooner than Riot asked us to.
+	if e, ok := asAPIError(err); ok && e.RetryAfter > d {
+		d = e.RetryAfter
+	}
+	return d
+}
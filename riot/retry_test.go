@@ -0,0 +1,240 @@
+package riot
+
+import (
+	"fmt"
+	"golang.org/x/net/context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A scripted response from the test server.
+type scriptedResponse struct {
+	status  int
+	headers map[string]string
+	body    string
+}
+
+// Serves responses from script in order, repeating the last one once it runs out.
+type scriptedServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	script   []scriptedResponse
+	requests []*http.Request
+}
+
+func newScriptedServer(script ...scriptedResponse) *scriptedServer {
+	s := &scriptedServer{script: script}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		i := len(s.requests)
+		if i >= len(s.script) {
+			i = len(s.script) - 1
+		}
+		s.requests = append(s.requests, r)
+		res := s.script[i]
+		s.mu.Unlock()
+
+		for k, v := range res.headers {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(res.status)
+		fmt.Fprint(w, res.body)
+	}))
+	return s
+}
+
+func (s *scriptedServer) numRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+// Returns a client for s that records sleeps instead of sleeping.
+func (s *scriptedServer) client(policy RetryPolicy, sleeps *[]time.Duration) *Client {
+	c := NewClient(ClientOptions{
+		ApiKey:  StaticApiKey("test-key"),
+		Retry:   &policy,
+		Region:  "na",
+		Regions: Regions.WithBaseUrl(s.URL),
+	})
+	now := time.Unix(0, 0)
+	c.now = func() time.Time { return now }
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		*sleeps = append(*sleeps, d)
+		now = now.Add(d)
+		return nil
+	}
+	return c
+}
+
+const matchJson = `{"matchId": 42, "region": "NA"}`
+
+var testPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseBackoff: 1 * time.Second,
+	MaxBackoff:  4 * time.Second,
+	Deadline:    time.Minute,
+}
+
+func TestFetchRetries(t *testing.T) {
+	ok := scriptedResponse{status: 200, body: matchJson}
+	serviceLimited := scriptedResponse{status: 429}
+	appLimited := scriptedResponse{
+		status:  429,
+		headers: map[string]string{"Retry-After": "7", "X-Rate-Limit-Type": "application"},
+	}
+	unavailable := scriptedResponse{status: 503}
+	internal := scriptedResponse{status: 500}
+	notFound := scriptedResponse{status: 404}
+
+	tests := []struct {
+		name         string
+		script       []scriptedResponse
+		policy       RetryPolicy
+		wantErr      func(error) bool
+		wantRequests int
+		wantSleeps   []time.Duration
+	}{
+		{
+			name:         "success",
+			script:       []scriptedResponse{ok},
+			policy:       testPolicy,
+			wantRequests: 1,
+		},
+		{
+			name:         "service 429 then success",
+			script:       []scriptedResponse{serviceLimited, serviceLimited, ok},
+			policy:       testPolicy,
+			wantRequests: 3,
+			wantSleeps:   []time.Duration{1 * time.Second, 2 * time.Second},
+		},
+		{
+			name:         "retry after is honoured",
+			script:       []scriptedResponse{appLimited, ok},
+			policy:       testPolicy,
+			wantRequests: 2,
+			wantSleeps:   []time.Duration{7 * time.Second},
+		},
+		{
+			name:         "5xx then success",
+			script:       []scriptedResponse{unavailable, internal, ok},
+			policy:       testPolicy,
+			wantRequests: 3,
+			wantSleeps:   []time.Duration{1 * time.Second, 2 * time.Second},
+		},
+		{
+			name:         "backoff is capped",
+			script:       []scriptedResponse{internal},
+			policy:       testPolicy,
+			wantErr:      IsServerError,
+			wantRequests: 5,
+			wantSleeps: []time.Duration{
+				1 * time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second},
+		},
+		{
+			name:         "404 is not retried",
+			script:       []scriptedResponse{notFound},
+			policy:       testPolicy,
+			wantErr:      IsNotFound,
+			wantRequests: 1,
+		},
+		{
+			name:         "no retry policy",
+			script:       []scriptedResponse{unavailable, ok},
+			policy:       NoRetryPolicy,
+			wantErr:      IsServerError,
+			wantRequests: 1,
+		},
+		{
+			name:   "deadline stops retries",
+			script: []scriptedResponse{appLimited, appLimited, ok},
+			policy: RetryPolicy{
+				MaxAttempts: 5,
+				BaseBackoff: 1 * time.Second,
+				Deadline:    10 * time.Second,
+			},
+			wantErr:      IsRateLimited,
+			wantRequests: 2,
+			wantSleeps:   []time.Duration{7 * time.Second},
+		},
+	}
+
+	for _, test := range tests {
+		server := newScriptedServer(test.script...)
+		var sleeps []time.Duration
+		match, err := server.client(test.policy, &sleeps).LookupMatch(context.Background(), 42)
+		server.Close()
+
+		if test.wantErr == nil {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", test.name, err)
+			} else if match.MatchId != 42 {
+				t.Errorf("%s: got MatchId %d, want 42", test.name, match.MatchId)
+			}
+		} else if !test.wantErr(err) {
+			t.Errorf("%s: got error %v", test.name, err)
+		}
+		if n := server.numRequests(); n != test.wantRequests {
+			t.Errorf("%s: got %d requests, want %d", test.name, n, test.wantRequests)
+		}
+		if fmt.Sprint(sleeps) != fmt.Sprint(test.wantSleeps) {
+			t.Errorf("%s: got sleeps %v, want %v", test.name, sleeps, test.wantSleeps)
+		}
+	}
+}
+
+func TestFetchReportsRateLimits(t *testing.T) {
+	server := newScriptedServer(
+		scriptedResponse{
+			status:  429,
+			headers: map[string]string{"Retry-After": "3", "X-Rate-Limit-Type": "application"},
+		},
+		scriptedResponse{status: 429},
+		scriptedResponse{status: 200, body: matchJson})
+	defer server.Close()
+
+	var sleeps []time.Duration
+	c := server.client(testPolicy, &sleeps)
+	var limited []time.Duration
+	c.onLimited = func(retryAfter time.Duration) {
+		limited = append(limited, retryAfter)
+	}
+	if _, err := c.LookupMatch(context.Background(), 42); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []time.Duration{3 * time.Second, 0}
+	if fmt.Sprint(limited) != fmt.Sprint(want) {
+		t.Errorf("got OnRateLimited calls %v, want %v", limited, want)
+	}
+}
+
+func TestAPIErrorRedactsKey(t *testing.T) {
+	server := newScriptedServer(scriptedResponse{
+		status:  429,
+		headers: map[string]string{"Retry-After": "2", "X-Rate-Limit-Type": "service"},
+	})
+	defer server.Close()
+
+	var sleeps []time.Duration
+	_, err := server.client(NoRetryPolicy, &sleeps).LookupMatch(context.Background(), 42)
+	e, ok := err.(*APIError)
+	if !ok {
+		t.Fatalf("got %T, want *APIError", err)
+	}
+	if e.Path != "/api/lol/na/v2.2/match/42?includeTimeline=true" {
+		t.Errorf("got Path %q", e.Path)
+	}
+	if e.Endpoint != "match-v2.2" || e.Region != "na" {
+		t.Errorf("got Endpoint %q, Region %q", e.Endpoint, e.Region)
+	}
+	if e.RetryAfter != 2*time.Second || e.RateLimitType != RateLimitService {
+		t.Errorf("got RetryAfter %v, RateLimitType %v", e.RetryAfter, e.RateLimitType)
+	}
+	if got := server.requests[0].URL.Query().Get("api_key"); got != "test-key" {
+		t.Errorf("server got api_key %q", got)
+	}
+}
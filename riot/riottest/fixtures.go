@@ -0,0 +1,203 @@
+// Package riottest records Riot API responses to fixture files and replays them so
+// that code using package riot can be tested offline.
+//
+// Use a Recorder as the riot.ClientOptions.Transport once against the live API to
+// capture fixtures, check them in, and then use a Replayer in tests.
+package riottest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A recorded request and response. Stored as one JSON file per request.
+type Fixture struct {
+	Method string
+	// The request path and query with the api_key parameter removed.
+	Path       string
+	StatusCode int
+	Header     map[string]string `json:",omitempty"`
+
+	// The response body. Riot responds with JSON so it is stored inline to keep
+	// fixtures readable. Bodies that are not valid JSON are stored as a JSON string and
+	// BodyIsText is set.
+	Body       json.RawMessage
+	BodyIsText bool `json:",omitempty"`
+}
+
+// Response headers worth keeping in fixtures. Everything else is dropped so that
+// re-recording does not produce noisy diffs.
+var recordedHeaders = []string{"Content-Type", "Retry-After", "X-Rate-Limit-Type"}
+
+// Returns the path and query of u in a canonical form without the api_key parameter.
+func requestPath(u *url.URL) string {
+	args := u.Query()
+	args.Del("api_key")
+	if len(args) == 0 {
+		return u.Path
+	}
+	// url.Values.Encode() sorts by key.
+	return fmt.Sprintf("%s?%s", u.Path, args.Encode())
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._=-]+`)
+
+// Returns the name of the fixture file for a request.
+func FixtureName(method string, u *url.URL) string {
+	name := fmt.Sprintf("%s_%s", method, strings.Trim(requestPath(u), "/"))
+	return unsafeFilenameChars.ReplaceAllString(name, "_") + ".json"
+}
+
+// A Recorder is an http.RoundTripper that sends requests with Transport and writes
+// each response to a fixture in Dir.
+type Recorder struct {
+	// Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+	Dir       string
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fixture{
+		Method:     req.Method,
+		Path:       requestPath(req.URL),
+		StatusCode: res.StatusCode,
+		Header:     make(map[string]string),
+	}
+	for _, h := range recordedHeaders {
+		if v := res.Header.Get(h); v != "" {
+			f.Header[h] = v
+		}
+	}
+	if json.Valid(body) {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, body, "", "  "); err != nil {
+			return nil, err
+		}
+		f.Body = indented.Bytes()
+	} else {
+		f.Body, _ = json.Marshal(string(body))
+		f.BodyIsText = true
+	}
+	if err := f.write(filepath.Join(r.Dir, FixtureName(req.Method, req.URL))); err != nil {
+		return nil, err
+	}
+
+	// Hand the caller a fresh copy of the body we consumed.
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return res, nil
+}
+
+func (f *Fixture) write(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func readFixture(path string) (*Fixture, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := new(Fixture)
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, errors.New(fmt.Sprintf("riottest: malformed fixture %s: %v", path, err))
+	}
+	return f, nil
+}
+
+// An ErrNoFixture is returned by a Replayer for a request that was never recorded.
+type ErrNoFixture struct {
+	Method string
+	Path   string
+	File   string
+}
+
+func (e ErrNoFixture) Error() string {
+	return fmt.Sprintf("riottest: no fixture recorded for %s %s (expected %s)",
+		e.Method, e.Path, e.File)
+}
+
+// A Replayer is an http.RoundTripper that serves responses from the fixtures in Dir.
+// It never touches the network.
+type Replayer struct {
+	Dir string
+
+	mu      sync.Mutex
+	missing []string
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	file := filepath.Join(r.Dir, FixtureName(req.Method, req.URL))
+	f, err := readFixture(file)
+	if os.IsNotExist(err) {
+		r.mu.Lock()
+		r.missing = append(r.missing, file)
+		r.mu.Unlock()
+		return nil, ErrNoFixture{req.Method, requestPath(req.URL), file}
+	} else if err != nil {
+		return nil, err
+	}
+
+	body := []byte(f.Body)
+	if f.BodyIsText {
+		var text string
+		if err := json.Unmarshal(f.Body, &text); err != nil {
+			return nil, err
+		}
+		body = []byte(text)
+	}
+	res := &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.StatusCode, http.StatusText(f.StatusCode)),
+		StatusCode:    f.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	for k, v := range f.Header {
+		res.Header.Set(k, v)
+	}
+	return res, nil
+}
+
+// Returns the fixture files that requests were made for but that did not exist.
+func (r *Replayer) Missing() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	missing := append([]string(nil), r.missing...)
+	sort.Strings(missing)
+	return missing
+}
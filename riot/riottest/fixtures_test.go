@@ -0,0 +1,74 @@
+package riottest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "5")
+		w.Header().Set("X-Unrecorded", "noise")
+		w.WriteHeader(429)
+		fmt.Fprint(w, `{"status": {"status_code": 429}}`)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "riottest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := server.URL + "/api/lol/na/v2.2/match/7?includeTimeline=true&api_key=SECRET"
+
+	recorder := &Recorder{Dir: dir}
+	req, _ := http.NewRequest("GET", loc, nil)
+	res, err := recorder.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	if !strings.Contains(string(body), "429") {
+		t.Errorf("recorder returned body %q", body)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("got fixtures %v", files)
+	}
+	if filepath.Base(files[0]) != "GET_api_lol_na_v2.2_match_7_includeTimeline=true.json" {
+		t.Errorf("got fixture name %s", files[0])
+	}
+	contents, _ := ioutil.ReadFile(files[0])
+	if strings.Contains(string(contents), "SECRET") {
+		t.Errorf("fixture contains the api key:\n%s", contents)
+	}
+	if strings.Contains(string(contents), "X-Unrecorded") {
+		t.Errorf("fixture contains unrecorded header:\n%s", contents)
+	}
+
+	// Replay against a different host and key.
+	replayer := &Replayer{Dir: dir}
+	req, _ = http.NewRequest(
+		"GET", "https://na.example.com/api/lol/na/v2.2/match/7?api_key=OTHER&includeTimeline=true", nil)
+	res, err = replayer.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != 429 || res.Header.Get("Retry-After") != "5" {
+		t.Errorf("got status %d, headers %v", res.StatusCode, res.Header)
+	}
+	if !strings.Contains(string(replayed), `"status_code": 429`) {
+		t.Errorf("got body %s", replayed)
+	}
+
+	req, _ = http.NewRequest("GET", "https://na.example.com/api/lol/na/v2.2/match/8", nil)
+	if _, err := replayer.RoundTrip(req); err == nil {
+		t.Errorf("expected an error for an unrecorded request")
+	}
+}
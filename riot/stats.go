@@ -1,8 +1,8 @@
 package riot
 
 import (
-	"encoding/json"
 	"fmt"
+	"golang.org/x/net/context"
 	"net/url"
 )
 
@@ -32,23 +32,13 @@ type AggregatedStatsDto struct {
 	TotalSessionsPlayed int `json:"totalSessionsPlayed"`
 }
 
-func RankedStatsBySummonerId(
-	urlFetcher func(string) ([]byte, int, error),
-	rateLimiter func(),
-	riotApiKey string,
-	region string,
-	summonerId int64) (*RankedStatsDto, error) {
-	loc := ComposeUrl(
-		riotApiKey,
-		fmt.Sprintf("/api/lol/%s/v1.3/stats/by-summoner/%d/ranked",
-			region, summonerId),
-		&url.Values{})
-	rateLimiter()
-	jsonData, _, err := urlFetcher(loc)
-	if err != nil {
-		return nil, err
-	}
+func (c *Client) RankedStatsBySummonerId(
+	ctx context.Context, summonerId int64) (*RankedStatsDto, error) {
 	dto := new(RankedStatsDto)
-	err = json.Unmarshal(jsonData, &dto)
+	err := c.fetch(
+		ctx, "stats-v1.3",
+		fmt.Sprintf("/api/lol/%s/v1.3/stats/by-summoner/%d/ranked",
+			c.region, summonerId),
+		&url.Values{}, dto)
 	return dto, err
 }
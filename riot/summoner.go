@@ -1,9 +1,9 @@
 package riot
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
+	"golang.org/x/net/context"
 	"net/url"
 	"strconv"
 	"strings"
@@ -46,25 +46,14 @@ func CanonicalizeSummoner(name string) string {
 	return strings.Replace(strings.ToLower(name), " ", "", -1)
 }
 
-func SummonerByName(
-	urlFetcher func(string) ([]byte, int, error),
-	rateLimiter func(),
-	riotApiKey string,
-	region string,
-	name string) (*SummonerDto, error) {
+func (c *Client) SummonerByName(ctx context.Context, name string) (*SummonerDto, error) {
 	name = CanonicalizeSummoner(name)
 
-	loc := ComposeUrl(
-		riotApiKey,
-		fmt.Sprintf("/api/lol/%s/v1.4/summoner/by-name/%s", region, name),
-		&url.Values{})
-	rateLimiter()
-	jsonData, _, err := urlFetcher(loc)
-	if err != nil {
-		return nil, err
-	}
 	data := make(map[string]*SummonerDto)
-	err = json.Unmarshal(jsonData, &data)
+	err := c.fetch(
+		ctx, "summoner-v1.4",
+		fmt.Sprintf("/api/lol/%s/v1.4/summoner/by-name/%s", c.region, name),
+		&url.Values{}, &data)
 	if err != nil {
 		return nil, err
 	}
@@ -77,11 +66,11 @@ func SummonerByName(
 }
 
 // Note that if the summoner id is not found nil gets populated into the output slice.
-func SummonersById(
-	urlFetcher func(string) ([]byte, int, error),
-	riotApiKey string,
-	region string,
-	ids ...int64) ([]*SummonerDto, error) {
+//
+// Summoners are requested 40 at a time. Once ctx is done the batch in flight is
+// abandoned, no more batches are requested and ctx.Err() is returned.
+func (c *Client) SummonersById(
+	ctx context.Context, ids ...int64) ([]*SummonerDto, error) {
 	ret := make([]*SummonerDto, len(ids))
 
 	// API supports up to 40 summoners at a time.
@@ -96,17 +85,14 @@ func SummonersById(
 	for ; ; begin, end = begin+40, min(len(ret), end+40) {
 		batch := idStrings[begin:end]
 
-		loc := ComposeUrl(
-			riotApiKey,
-			fmt.Sprintf("/api/lol/%s/v1.4/summoner/%s", region, strings.Join(batch, ",")),
-			&url.Values{})
-		jsonData, _, err := urlFetcher(loc)
 		data := make(map[string]*SummonerDto)
-		if err != nil {
-			return nil, err
-		}
-		err = json.Unmarshal(jsonData, &data)
-		if err != nil {
+		err := c.fetch(
+			ctx, "summoner-v1.4",
+			fmt.Sprintf("/api/lol/%s/v1.4/summoner/%s", c.region, strings.Join(batch, ",")),
+			&url.Values{}, &data)
+		if IsNotFound(err) {
+			// None of the summoners in this batch exist.
+		} else if err != nil {
 			return nil, err
 		}
 
@@ -122,21 +108,15 @@ func SummonersById(
 	return ret, nil
 }
 
-func RunesBySummonerId(
-	urlFetcher func(string) ([]byte, int, error),
-	riotApiKey string,
-	region string,
-	riotId int64) (*RunePagesDto, error) {
-	loc := ComposeUrl(
-		riotApiKey,
-		fmt.Sprintf("/api/lol/%s/v1.4/summoner/%d/runes", region, riotId),
-		&url.Values{})
-	jsonData, _, err := urlFetcher(loc)
-	if err != nil {
-		return nil, err
-	}
+// Rune pages are never cached: summoners are verified by renaming a rune page, and the
+// rename must be seen as soon as it is made.
+func (c *Client) RunesBySummonerId(
+	ctx context.Context, riotId int64) (*RunePagesDto, error) {
 	data := make(map[string]*RunePagesDto)
-	err = json.Unmarshal(jsonData, &data)
+	err := c.fetch(
+		ctx, "summoner-v1.4-runes",
+		fmt.Sprintf("/api/lol/%s/v1.4/summoner/%d/runes", c.region, riotId),
+		&url.Values{}, &data)
 	if err != nil {
 		return nil, err
 	}
@@ -144,6 +124,6 @@ func RunesBySummonerId(
 	if dto, ok := data[fmt.Sprintf("%d", riotId)]; ok {
 		return dto, nil
 	} else {
-		return nil, errors.New(fmt.Sprintf("Summoner does not exist: %s-%d", region, riotId))
+		return nil, errors.New(fmt.Sprintf("Summoner does not exist: %s-%d", c.region, riotId))
 	}
 }
@@ -20,8 +20,9 @@ func (rt *RiotTime) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// Marshals to epoch milliseconds, matching what the Riot API sends.
 func (rt RiotTime) MarshalJSON() ([]byte, error) {
-	return json.Marshal((*time.Time)(&rt).Unix())
+	return json.Marshal(time.Time(rt).UnixNano() / int64(time.Millisecond))
 }
 
 func (rt RiotTime) UnixMillisString() string {
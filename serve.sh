@@ -1,3 +1,6 @@
+# Set RIOT_BASE_URL to send Riot API requests somewhere other than the live API,
+# for example a local fake started with `go run tools/fakeriot/*.go`.
 dev_appserver.py \
   --storage_path=./localdata \
+  ${RIOT_BASE_URL:+--env_var RIOT_BASE_URL=$RIOT_BASE_URL} \
   app/app.yaml
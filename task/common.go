@@ -4,6 +4,7 @@ import (
 	"appengine"
 	"fmt"
 	"github.com/OwenDurni/loltools/model"
+	"github.com/OwenDurni/loltools/riot"
 	"github.com/OwenDurni/loltools/view"
 	"net/http"
 )
@@ -18,6 +19,13 @@ func ReportError(c appengine.Context, w http.ResponseWriter, err error) bool {
 	if _, ok := err.(model.ErrRateLimitExceeded); ok {
 		shouldRetry = true
 	}
+	if riot.IsRateLimited(err) || riot.IsServerError(err) {
+		shouldRetry = true
+	}
+	if riot.IsCanceled(err) {
+		// The task ran out of time before Riot responded.
+		shouldRetry = true
+	}
 
 	if shouldRetry {
 		// We write a non-2XX response so that the task is retried.
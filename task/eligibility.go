@@ -0,0 +1,67 @@
+package task
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"appengine/taskqueue"
+	"fmt"
+	"github.com/OwenDurni/loltools/model"
+	"net/http"
+	"net/url"
+)
+
+func AllLeagueEligibility(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+
+	q := datastore.NewQuery("League").KeysOnly()
+	leagueKeys, err := q.GetAll(c, nil)
+	if ReportError(c, w, err) {
+		return
+	}
+
+	for _, leagueKey := range leagueKeys {
+		args := &url.Values{}
+		args.Add("league", model.EncodeKeyShort(leagueKey))
+		task := taskqueue.NewPOSTTask("/task/leagues/eligibility", *args)
+		task.RetryOptions = new(taskqueue.RetryOptions)
+		task.RetryOptions.RetryLimit = 1
+		taskqueue.Add(c, task, "")
+	}
+
+	fmt.Fprintf(w, "<html><body><pre>")
+	fmt.Fprintf(w, "Queueing /task/leagues/eligibility for %d league(s)\n", len(leagueKeys))
+	fmt.Fprintf(w, "</pre></body></html>")
+}
+
+// Re-checks the rosters of a league against its eligibility rules and flags the teams
+// that no longer meet them.
+func LeagueEligibilityHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	fmt.Fprintf(w, "<html><body><pre>")
+	c := appengine.NewContext(r)
+	reqCtx, cancel := model.NewRequestContext(r)
+	defer cancel()
+
+	league, leagueKey, err := model.LeagueById(c, r.FormValue("league"))
+	if ReportError(c, w, err) {
+		return
+	}
+
+	results, err := model.CheckLeagueEligibility(c, reqCtx, league, leagueKey)
+	if ReportError(c, w, err) {
+		return
+	}
+
+	flagged := 0
+	for _, result := range results {
+		if len(result.Violations) == 0 {
+			continue
+		}
+		flagged++
+		fmt.Fprintf(w, "Team %s:\n", model.EncodeKeyShort(result.TeamKey))
+		for _, v := range result.Violations {
+			fmt.Fprintf(w, "  %s\n", v)
+		}
+	}
+	fmt.Fprintf(w, "Checked %d team(s), %d not eligible\n", len(results), flagged)
+	fmt.Fprintf(w, "</pre></body></html>")
+}
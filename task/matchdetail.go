@@ -0,0 +1,108 @@
+package task
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"appengine/taskqueue"
+	"fmt"
+	"github.com/OwenDurni/loltools/model"
+	"net/http"
+	"net/url"
+)
+
+// The number of GameByTeam entities checked by each AllMatchDetails task.
+const matchDetailPageSize = 50
+
+func QueueFetchMatchDetail(c appengine.Context, gameKey *datastore.Key) {
+	args := &url.Values{}
+	args.Add("game", gameKey.StringID())
+	task := taskqueue.NewPOSTTask("/task/riot/get/match-detail", *args)
+	task.RetryOptions = new(taskqueue.RetryOptions)
+	task.RetryOptions.RetryLimit = 3
+	taskqueue.Add(c, task, "")
+}
+
+// Walks every GameByTeam and queues /task/riot/get/match-detail for games whose
+// details have not been archived. Each task checks one page and then queues itself
+// with a cursor for the next page.
+func AllMatchDetails(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	fmt.Fprintf(w, "<html><body><pre>")
+	c := appengine.NewContext(r)
+
+	q := datastore.NewQuery("GameByTeam").Project("GameKey")
+	if cursorStr := r.FormValue("cursor"); cursorStr != "" {
+		cursor, err := datastore.DecodeCursor(cursorStr)
+		if ReportError(c, w, err) {
+			return
+		}
+		q = q.Start(cursor)
+	}
+
+	var gameKeys []*datastore.Key
+	seen := make(map[string]bool)
+	it := q.Run(c)
+	count := 0
+	for ; count < matchDetailPageSize; count++ {
+		gameByTeam := new(model.GameByTeam)
+		_, err := it.Next(gameByTeam)
+		if err == datastore.Done {
+			break
+		} else if ReportError(c, w, err) {
+			return
+		}
+		// A game played between two league teams has a GameByTeam for each.
+		if !seen[gameByTeam.GameKey.StringID()] {
+			seen[gameByTeam.GameKey.StringID()] = true
+			gameKeys = append(gameKeys, gameByTeam.GameKey)
+		}
+	}
+
+	has, err := model.HasGameMatchDetails(c, gameKeys)
+	if ReportError(c, w, err) {
+		return
+	}
+	queued := 0
+	for i, gameKey := range gameKeys {
+		if !has[i] {
+			QueueFetchMatchDetail(c, gameKey)
+			queued++
+		}
+	}
+	fmt.Fprintf(w, "Queueing /task/riot/get/match-detail for %d of %d game(s)\n",
+		queued, len(gameKeys))
+
+	if count == matchDetailPageSize {
+		cursor, err := it.Cursor()
+		if ReportError(c, w, err) {
+			return
+		}
+		args := &url.Values{}
+		args.Add("cursor", cursor.String())
+		taskqueue.Add(c, taskqueue.NewPOSTTask("/task/cron/all-match-details", *args), "")
+		fmt.Fprintf(w, "Queueing the next page\n")
+	}
+	fmt.Fprintf(w, "</pre></body></html>")
+}
+
+// Archives the details of a single game.
+func FetchMatchDetailHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	fmt.Fprintf(w, "<html><body><pre>")
+	c := appengine.NewContext(r)
+	reqCtx, cancel := model.NewRequestContext(r)
+	defer cancel()
+
+	game, gameKey, err := model.GameById(c, r.FormValue("game"))
+	if ReportError(c, w, err) {
+		return
+	}
+	match, err := model.GetOrFetchGameMatchDetail(c, reqCtx, game, gameKey)
+	if ReportError(c, w, err) {
+		return
+	}
+	if match == nil {
+		fmt.Fprintf(w, "Riot has no details for %s\n", game.Id())
+	} else {
+		fmt.Fprintf(w, "Archived details for %s (match %d)\n", game.Id(), match.MatchId)
+	}
+	fmt.Fprintf(w, "</pre></body></html>")
+}
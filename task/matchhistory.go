@@ -16,11 +16,8 @@ import (
 func MissingGameStats(w http.ResponseWriter, r *http.Request, args map[string]string) {
 	fmt.Fprintf(w, "<html><body><pre>")
 	c := appengine.NewContext(r)
-
-	riotApiKey, err := model.GetRiotApiKey(c)
-	if ReportError(c, w, errwrap.Wrap(err)) {
-		return
-	}
+	reqCtx, cancel := model.NewRequestContext(r)
+	defer cancel()
 
 	limit := 20
 	q := datastore.NewQuery("PlayerGameStats").
@@ -53,7 +50,7 @@ func MissingGameStats(w http.ResponseWriter, r *http.Request, args map[string]st
 		if ReportError(c, w, errwrap.Wrap(err)) {
 			return
 		}
-		_, _, err = model.GetOrCreatePlayerByRiotId(c, region, riotSummonerId)
+		_, _, err = model.GetOrCreatePlayerByRiotId(c, reqCtx, region, riotSummonerId)
 		if ReportError(c, w, errwrap.Wrap(err)) {
 			return
 		}
@@ -85,18 +82,27 @@ func MissingGameStats(w http.ResponseWriter, r *http.Request, args map[string]st
 	}
 
 	collectiveGameStats := new(model.CollectiveGameStats)
+	fetchedPlayers := make(map[int64]bool)
+	var retryErr error
 	for _, player := range players {
-		if err := model.RiotApiRateLimiter.Consume(c, 1); err != nil {
-			// Hitting rate limit: break to finish storing what we have already fetched.
-			ReportError(c, w, errwrap.Wrap(err))
-			return
+		recentGamesDto, err := model.RiotClient(c, player.Region).GameStatsForPlayer(
+			reqCtx, player.RiotId)
+		if riot.IsNotFound(err) {
+			// The summoner no longer exists so their stats will never be available.
+			fetchedPlayers[player.RiotId] = true
+			continue
 		}
-		recentGamesDto, err := riot.GameStatsForPlayer(
-			c, riotApiKey.Key, player.Region, player.RiotId)
-		if ReportError(c, w, errwrap.Wrap(err)) {
+		if _, ok := err.(model.ErrRateLimitExceeded); ok || riot.IsRateLimited(err) ||
+			riot.IsServerError(err) || riot.IsCanceled(err) {
+			// Break to finish storing what we have already fetched, then retry the task.
+			retryErr = err
+			break
+		}
+		if ReportError(c, w, err) {
 			return
 		}
 
+		fetchedPlayers[player.RiotId] = true
 		for _, gameDto := range recentGamesDto.Games {
 			gameId := model.MakeGameId(player.Region, gameDto.GameId)
 			collectiveGameStats.Add(gameId, player.RiotId, &gameDto)
@@ -110,6 +116,10 @@ func MissingGameStats(w http.ResponseWriter, r *http.Request, args map[string]st
 		statKey := statKeys[i]
 		game := gameMap[stat.GameKey.Encode()]
 		player := playerMap[stat.PlayerKey.Encode()]
+		if !fetchedPlayers[player.RiotId] {
+			// We don't know whether these stats are still available yet.
+			continue
+		}
 
 		riotData := collectiveGameStats.Lookup(game.Id(), player.RiotId)
 
@@ -144,6 +154,9 @@ func MissingGameStats(w http.ResponseWriter, r *http.Request, args map[string]st
 
 	fmt.Fprintf(w, "  Found: %d\n", foundCount)
 	fmt.Fprintf(w, "  Not Available: %d\n", expiredCount)
+	if ReportError(c, w, retryErr) {
+		return
+	}
 	fmt.Fprintf(w, "</pre></body></html>")
 }
 
@@ -187,6 +200,8 @@ func AllTeamHistories(w http.ResponseWriter, r *http.Request, args map[string]st
 func FetchTeamMatchHistoryHandler(
 	w http.ResponseWriter, r *http.Request, args map[string]string) {
 	c := appengine.NewContext(r)
+	reqCtx, cancel := model.NewRequestContext(r)
+	defer cancel()
 	leagueId := r.FormValue("league")
 	teamId := r.FormValue("team")
 
@@ -208,20 +223,19 @@ func FetchTeamMatchHistoryHandler(
 		return
 	}
 
-	riotApiKey, err := model.GetRiotApiKey(c)
-	if ReportError(c, w, err) {
-		return
-	}
+	riotClient := model.RiotClient(c, region)
 
 	// First gather games from all players on the team.
 	collectiveGameStats := new(model.CollectiveGameStats)
 	for _, player := range players {
-		if err := model.RiotApiRateLimiter.Consume(c, 1); err != nil {
-			// Hitting rate limit: break to finish storing what we have already fetched.
+		recentGamesDto, err := riotClient.GameStatsForPlayer(reqCtx, player.RiotId)
+		if _, ok := err.(model.ErrRateLimitExceeded); ok || riot.IsRateLimited(err) ||
+			riot.IsCanceled(err) {
+			// Hitting rate limit or out of time: break to finish storing what we have
+			// already fetched.
 			ReportError(c, w, err)
 			break
 		}
-		recentGamesDto, err := riot.GameStatsForPlayer(c, riotApiKey.Key, region, player.RiotId)
 		if ReportError(c, w, err) {
 			return
 		}
@@ -55,17 +55,29 @@ func MatchSync(w http.ResponseWriter, r *http.Request, args map[string]string) {
 		return
 	}
 
+	league := new(model.League)
+	err = datastore.Get(c, matchKey.Parent(), league)
+	if ReportError(c, w, err) {
+		return
+	}
+
 	homeTeamKey := match.HomeTeam()
 	awayTeamKey := match.AwayTeam()
 
-	// Phase 1: Tag games that look like they could be for this match.
-	err = tagGamesInMatchWindow(c, w, homeTeamKey, awayTeamKey, match, matchKey)
+	// Phase 1: Tag games that are, or may be, part of this match.
+	err = tagGamesInMatchWindow(c, w, league, homeTeamKey, awayTeamKey, match, matchKey)
 	if ReportError(c, w, err) {
 		return
 	}
 
 	// Phase 2: Compute match results.
-	computeMatchResults(c, w, homeTeamKey, awayTeamKey, match, matchKey)
+	err = computeMatchResults(c, w, league, homeTeamKey, awayTeamKey, match, matchKey)
+	if ReportError(c, w, err) {
+		return
+	}
+
+	// Phase 3: Advance the bracket or Swiss tournament the match is part of.
+	err = model.AdvanceForMatch(c, matchKey)
 	if ReportError(c, w, err) {
 		return
 	}
@@ -76,96 +88,145 @@ func MatchSync(w http.ResponseWriter, r *http.Request, args map[string]string) {
 func tagGamesInMatchWindow(
 	c appengine.Context,
 	w io.Writer,
+	league *model.League,
 	homeTeamKey *datastore.Key,
 	awayTeamKey *datastore.Key,
 	match *model.ScheduledMatch,
 	matchKey *datastore.Key) error {
-	gameKeys, err := getGamesInMatchWindow(c, homeTeamKey, awayTeamKey, match)
+	gameKeys, home, away, err := getGamesInMatchWindow(c, homeTeamKey, awayTeamKey, match)
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(w, "Found %d possible match results:\n", len(gameKeys))
-	for _, gameKey := range gameKeys {
-		uri := model.GameUri(gameKey)
-		fmt.Fprintf(w, "  <a href=\"%s\">%s</a>\n", uri, uri)
+	games := make([]*model.Game, len(gameKeys))
+	for i := range games {
+		games[i] = new(model.Game)
+	}
+	if err := datastore.GetMulti(c, gameKeys, games); err != nil {
+		return err
 	}
-	fmt.Fprintf(w, "\n")
 
 	leagueKey := homeTeamKey.Parent()
-	for _, gameKey := range gameKeys {
-		err := model.AddGameTag(
-			c, nil, leagueKey, gameKey,
-			tags.AutomaticallyDetectedMatchResultFor(matchKey),
-			tags.ReasonNotApplicable())
+	resultTag := tags.AutomaticallyDetectedMatchResultFor(matchKey)
+	reviewTag := tags.NeedsReviewFor(matchKey)
+
+	// Games reviewed by a league editor keep their tags.
+	reviewed := make(map[string]bool)
+	for _, tag := range []string{resultTag, reviewTag} {
+		gameTags, err := model.GameTagsWithTag(c, leagueKey, tag)
 		if err != nil {
 			return err
 		}
+		for _, gameTag := range gameTags {
+			if gameTag.Reviewed {
+				reviewed[gameTag.Game.Encode()] = true
+			}
+		}
+	}
+
+	detections := model.DetectMatchGames(match, gameKeys, games, home, away)
+	fmt.Fprintf(w, "Found %d possible match game(s):\n", len(detections))
+	for _, d := range detections {
+		uri := model.GameUri(d.GameKey)
+		fmt.Fprintf(w, "  <a href=\"%s\">%s</a> %v (%d%%): %s\n",
+			uri, uri, d.Verdict, d.Confidence, d.Reason())
+		if reviewed[d.GameKey.Encode()] {
+			fmt.Fprintf(w, "    unchanged, reviewed by a league editor\n")
+			continue
+		}
+
+		// A game has at most one of the tags.
+		var setTag string
+		var delTags []string
+		switch d.Verdict {
+		case model.MatchGameAccepted:
+			setTag, delTags = resultTag, []string{reviewTag}
+		case model.MatchGameNeedsReview:
+			setTag, delTags = reviewTag, []string{resultTag}
+		default:
+			delTags = []string{resultTag, reviewTag}
+		}
+		if setTag != "" {
+			err := model.SetGameTag(c, nil, league, leagueKey, &model.GameTag{
+				Game:       d.GameKey,
+				Tag:        setTag,
+				Reason:     d.Reason(),
+				Confidence: d.Confidence,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		for _, tag := range delTags {
+			if err := model.DelGameTag(c, nil, leagueKey, d.GameKey, tag); err != nil {
+				return err
+			}
+		}
 	}
+	fmt.Fprintf(w, "\n")
 	return nil
 }
 
-// Gets a list of gameKeys played by the two specified teams within the bounds of
-// the specified match.
+// Gets the games played by the two specified teams within the bounds of the specified
+// match, along with the GameByTeam rows of each team for those games.
 func getGamesInMatchWindow(
 	c appengine.Context,
 	homeTeamKey *datastore.Key,
 	awayTeamKey *datastore.Key,
-	match *model.ScheduledMatch) ([]*datastore.Key, error) {
-	homeMatchPossibleGameKeys, err := getGamesInMatchWindowByTeam(c, homeTeamKey, match)
+	match *model.ScheduledMatch) (
+	[]*datastore.Key, []*model.GameByTeam, []*model.GameByTeam, error) {
+	homeGameByTeams, err := getGamesInMatchWindowByTeam(c, homeTeamKey, match)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
-	awayMatchPossibleGameKeys, err := getGamesInMatchWindowByTeam(c, awayTeamKey, match)
+	awayGameByTeams, err := getGamesInMatchWindowByTeam(c, awayTeamKey, match)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
-	ret := make([]*datastore.Key, 0, 8)
-	gameKeySet := make(map[string]struct{})
-	for _, k := range homeMatchPossibleGameKeys {
-		gameKeySet[k.Encode()] = struct{}{}
+	gameKeys := make([]*datastore.Key, 0, 8)
+	var home, away []*model.GameByTeam
+	homeByGame := make(map[string]*model.GameByTeam)
+	for _, g := range homeGameByTeams {
+		homeByGame[g.GameKey.Encode()] = g
 	}
-	for _, k := range awayMatchPossibleGameKeys {
-		if _, exists := gameKeySet[k.Encode()]; exists {
-			ret = append(ret, k)
+	for _, g := range awayGameByTeams {
+		if h, exists := homeByGame[g.GameKey.Encode()]; exists {
+			gameKeys = append(gameKeys, g.GameKey)
+			home = append(home, h)
+			away = append(away, g)
 		}
 	}
-	return ret, nil
+	return gameKeys, home, away, nil
 }
 
-// Gets a list of gameKeys played by the specified team within the bounds of
-// the specified match.
+// Gets the GameByTeam rows of the specified team within the bounds of the specified
+// match.
 func getGamesInMatchWindowByTeam(
 	c appengine.Context,
 	teamKey *datastore.Key,
-	match *model.ScheduledMatch) ([]*datastore.Key, error) {
+	match *model.ScheduledMatch) ([]*model.GameByTeam, error) {
 	q := datastore.NewQuery("GameByTeam").
 		Ancestor(teamKey.Parent()).
 		Filter("TeamKey =", teamKey).
 		Filter("DateTime >=", match.DateEarliest).
-		Filter("DateTime <=", match.DateLatest).
-		Project("GameKey")
+		Filter("DateTime <=", match.DateLatest)
 	var gameByTeams []*model.GameByTeam
 	_, err := q.GetAll(c, &gameByTeams)
 	if err != nil {
 		return nil, err
 	}
-	gameKeys := make([]*datastore.Key, len(gameByTeams))
-	for i := range gameByTeams {
-		gameKeys[i] = gameByTeams[i].GameKey
-	}
-	return gameKeys, nil
+	return gameByTeams, nil
 }
 
 // Computes match results for each team based on games identified as part of the match.
 func computeMatchResults(
 	c appengine.Context,
 	w io.Writer,
+	league *model.League,
 	homeTeamKey *datastore.Key,
 	awayTeamKey *datastore.Key,
 	match *model.ScheduledMatch,
 	matchKey *datastore.Key) error {
 	leagueKey := homeTeamKey.Parent()
-	// TODO: Manually reported results should take precedence over automatically reported ones.
 
 	q := datastore.NewQuery("GameTag").
 		Ancestor(leagueKey).
@@ -188,11 +249,18 @@ func computeMatchResults(
 	}
 	sort.Sort(model.GameByTime(games))
 
-	err = computeMatchResultForTeam(c, w, homeTeamKey, match, games)
+	scoringType := match.TeamScoringType(league)
+	scoring, err := model.TeamScoringFor(scoringType)
 	if err != nil {
 		return err
 	}
-	err = computeMatchResultForTeam(c, w, awayTeamKey, match, games)
+	fmt.Fprintf(w, "Scoring %d game(s) with %v:\n", len(games), scoringType)
+
+	err = computeMatchResultForTeam(c, w, league, scoring, homeTeamKey, match, matchKey, games)
+	if err != nil {
+		return err
+	}
+	err = computeMatchResultForTeam(c, w, league, scoring, awayTeamKey, match, matchKey, games)
 	if err != nil {
 		return err
 	}
@@ -200,11 +268,52 @@ func computeMatchResults(
 	return nil
 }
 
+// Scores games, which are in chronological order, for one team of the match and saves
+// the result.
 func computeMatchResultForTeam(
 	c appengine.Context,
 	w io.Writer,
+	league *model.League,
+	scoring model.TeamScoring,
 	teamKey *datastore.Key,
 	match *model.ScheduledMatch,
+	matchKey *datastore.Key,
 	games []*model.Game) error {
-	return nil
+	leagueKey := teamKey.Parent()
+	history, err := model.GetTeamRosterHistory(c, nil, league, leagueKey, teamKey)
+	if err != nil {
+		return err
+	}
+	playerCache := model.NewPlayerCache(c, league.Region)
+
+	// Games are scored with the team's roster when they were played.
+	infos := make([]*model.GameInfo, len(games))
+	for i, game := range games {
+		players := history.PlayersAt(game.StartDateTime)
+		for _, p := range players {
+			playerCache.Add(p)
+		}
+		info, errs := model.TeamGameInfo(c, playerCache, leagueKey, players, game)
+		if len(errs) > 0 {
+			return errs[0]
+		}
+		infos[i] = info
+	}
+
+	score := scoring.Score(match, infos)
+	result := &model.MatchResult{
+		ScheduledMatch: matchKey,
+		Team:           teamKey,
+		Points:         score.Points,
+		IsFinal:        score.IsFinal,
+	}
+	result.GamesWon, result.GamesLost = model.SeriesGames(match, infos)
+
+	fmt.Fprintf(w, "  %s: %d point(s), %d-%d in games",
+		model.EncodeKeyShort(teamKey), result.Points, result.GamesWon, result.GamesLost)
+	if score.IsFinal {
+		fmt.Fprintf(w, " (final)")
+	}
+	fmt.Fprintf(w, "\n")
+	return model.SaveMatchResult(c, result)
 }
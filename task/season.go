@@ -0,0 +1,39 @@
+package task
+
+import (
+	"appengine"
+	"fmt"
+	"github.com/OwenDurni/loltools/model"
+	"github.com/OwenDurni/loltools/view"
+	"net/http"
+)
+
+// Carries the next batch of a league's teams, matches and rosters into a season started
+// by model.StartSeason. Each batch queues the next one.
+func MigrateSeasonHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	fmt.Fprintf(w, "<html><body><pre>")
+	c := appengine.NewContext(r)
+
+	_, leagueKey, err := model.LeagueById(c, r.FormValue("league"))
+	if ReportError(c, w, err) {
+		return
+	}
+	seasonKey, err := model.DecodeKeyShort(c, "Season", r.FormValue("season"), leagueKey)
+	if ReportError(c, w, err) {
+		return
+	}
+
+	more, err := model.MigrateSeasonBatch(c, seasonKey)
+	if err != nil {
+		// The batch did not commit, so it is safe to retry.
+		c.Warningf("[Temporary Task Error] %v", err)
+		view.HttpReplyError(c, w, http.StatusInternalServerError, false, err)
+		return
+	}
+	if more {
+		fmt.Fprintf(w, "Migrated a batch; queueing the next\n")
+	} else {
+		fmt.Fprintf(w, "Season migration done\n")
+	}
+	fmt.Fprintf(w, "</pre></body></html>")
+}
@@ -0,0 +1,57 @@
+// Command fakeriot serves a fake Riot API for local development.
+//
+// It answers the summoner, game, stats, matchlist, match, league, champion mastery,
+// current-game and featured-games requests made by package riot from an in-memory
+// world of summoners and games, and can simulate rate limiting. Point clients at it with riot.Regions.WithBaseUrl:
+//
+//	go run tools/fakeriot/*.go -addr localhost:8081
+//	RIOT_BASE_URL=http://localhost:8081 ./serve.sh
+//	go run tools/ranked_data.go -riot_base_url http://localhost:8081 "Blue Ravens Top"
+//
+// Without -world a world of -teams generated teams is served. The world can be
+// changed while the server runs; see Server.serveFake for the /fake/ endpoints. For
+// example, to make the next 3 requests fail with an application rate limit:
+//
+//	curl -X POST 'localhost:8081/fake/ratelimit?count=3&retry_after=5&type=application'
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+)
+
+var (
+	addr       = flag.String("addr", "localhost:8081", "address to listen on")
+	worldFile  = flag.String("world", "", "JSON file describing the world to serve")
+	numTeams   = flag.Int("teams", 4, "number of teams to generate when -world is not set")
+	seed       = flag.Int64("seed", 1, "random seed for the generated world")
+	apiKey     = flag.String("api_key", "", "if set, the only api_key that is accepted")
+	rateLimit  = flag.Int("rate_limit", 10, "requests allowed per -rate_window; 0 disables")
+	rateWindow = flag.Duration("rate_window", 10*time.Second, "rate limit window")
+)
+
+func main() {
+	flag.Parse()
+
+	var world *World
+	if *worldFile != "" {
+		var err error
+		world, err = loadWorld(*worldFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		world = generateWorld(*numTeams, *seed, time.Now())
+	}
+
+	server := NewServer(world)
+	server.ApiKey = *apiKey
+	server.RateLimit = *rateLimit
+	server.RateWindow = *rateWindow
+
+	log.Printf("Serving %d summoners and %d games on http://%s",
+		len(world.Summoners), len(world.Games), *addr)
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
@@ -0,0 +1,599 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/OwenDurni/loltools/riot"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Serves the Riot API endpoints used by package riot from a World, along with /fake/
+// endpoints for changing the world and scripting failures.
+type Server struct {
+	// When set requests must use this api_key. Otherwise any non-empty key is accepted.
+	ApiKey string
+
+	// Requests beyond RateLimit within RateWindow get an application rate limit 429.
+	// Zero disables the limit.
+	RateLimit  int
+	RateWindow time.Duration
+
+	mu    sync.Mutex
+	world *World
+
+	windowStart time.Time
+	windowCount int
+
+	// Scripted 429s returned before anything else (see /fake/ratelimit).
+	forcedLimits     int
+	forcedRetryAfter int
+	forcedLimitType  string
+}
+
+func NewServer(w *World) *Server {
+	w.normalize(time.Now())
+	return &Server{world: w}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Printf("%s %s", r.Method, redactedUrl(r))
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/api/lol/"),
+		strings.HasPrefix(r.URL.Path, "/championmastery/location/"),
+		strings.HasPrefix(r.URL.Path, "/observer-mode/rest/"):
+		s.serveApi(w, r)
+	case strings.HasPrefix(r.URL.Path, "/fake/"):
+		s.serveFake(w, r)
+	default:
+		writeStatus(w, http.StatusNotFound)
+	}
+}
+
+func redactedUrl(r *http.Request) string {
+	args := r.URL.Query()
+	if args.Get("api_key") != "" {
+		args.Set("api_key", "REDACTED")
+	}
+	if len(args) == 0 {
+		return r.URL.Path
+	}
+	return r.URL.Path + "?" + args.Encode()
+}
+
+// Writes an error the way Riot does.
+func writeStatus(w http.ResponseWriter, code int) {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, `{"status": {"message": %q, "status_code": %d}}`,
+		http.StatusText(code), code)
+}
+
+func writeJson(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// Returns true if the request may proceed. Otherwise a 429 has been written.
+func (s *Server) checkRateLimit(w http.ResponseWriter, now time.Time) bool {
+	if s.forcedLimits > 0 {
+		s.forcedLimits--
+		if s.forcedRetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(s.forcedRetryAfter))
+		}
+		if s.forcedLimitType != "" {
+			w.Header().Set("X-Rate-Limit-Type", s.forcedLimitType)
+		}
+		writeStatus(w, 429)
+		return false
+	}
+
+	if s.RateLimit <= 0 || s.RateWindow <= 0 {
+		return true
+	}
+	if now.Sub(s.windowStart) >= s.RateWindow {
+		s.windowStart = now
+		s.windowCount = 0
+	}
+	s.windowCount++
+	w.Header().Set("X-Rate-Limit-Count",
+		fmt.Sprintf("%d:%d", s.windowCount, int(s.RateWindow/time.Second)))
+	if s.windowCount <= s.RateLimit {
+		return true
+	}
+	retryAfter := s.windowStart.Add(s.RateWindow).Sub(now)
+	w.Header().Set("Retry-After", strconv.Itoa(int((retryAfter+time.Second-1)/time.Second)))
+	w.Header().Set("X-Rate-Limit-Type", "application")
+	writeStatus(w, 429)
+	return false
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Riot API
+
+// Handles /api/lol/<region>/<version>/..., /championmastery/location/<platform>/...
+// and /observer-mode/rest/...
+func (s *Server) serveApi(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("api_key")
+	if key == "" {
+		writeStatus(w, http.StatusUnauthorized)
+		return
+	}
+	if s.ApiKey != "" && key != s.ApiKey {
+		writeStatus(w, http.StatusForbidden)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if !s.checkRateLimit(w, now) {
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/championmastery/location/") {
+		s.serveChampionMastery(w, r)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/observer-mode/rest/") {
+		s.serveObserverMode(w, r, now)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/lol/"), "/")
+	if len(parts) < 3 {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+	region, endpoint, args := parts[0], parts[1]+"/"+parts[2], parts[3:]
+	if _, err := riot.Regions.Lookup(region); err != nil {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case endpoint == "v1.4/summoner" && len(args) == 2 && args[0] == "by-name":
+		s.summonersByName(w, region, args[1], now)
+	case endpoint == "v1.4/summoner" && len(args) == 1:
+		s.summonersById(w, region, args[0], now)
+	case endpoint == "v1.4/summoner" && len(args) == 2 && args[1] == "runes":
+		s.runes(w, region, args[0])
+	case endpoint == "v1.3/game" && len(args) == 3 && args[0] == "by-summoner" &&
+		args[2] == "recent":
+		s.recentGames(w, region, args[1])
+	case endpoint == "v1.3/stats" && len(args) == 3 && args[0] == "by-summoner" &&
+		args[2] == "ranked":
+		s.rankedStats(w, region, args[1])
+	case endpoint == "v2.2/matchlist" && len(args) == 2 && args[0] == "by-summoner":
+		s.matchList(w, r, region, args[1])
+	case endpoint == "v2.2/match" && len(args) == 1:
+		s.match(w, r, region, args[0])
+	case endpoint == "v2.5/league" && len(args) == 3 && args[0] == "by-summoner" &&
+		args[2] == "entry":
+		s.leagueEntries(w, region, args[1])
+	default:
+		writeStatus(w, http.StatusNotFound)
+	}
+}
+
+// Parses a comma separated list of ids. Writes a 400 and returns false on failure.
+func parseIds(w http.ResponseWriter, list string, max int) ([]int64, bool) {
+	var ids []int64
+	for _, idString := range strings.Split(list, ",") {
+		id, err := strconv.ParseInt(idString, 10, 64)
+		if err != nil {
+			writeStatus(w, http.StatusBadRequest)
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) > max {
+		writeStatus(w, http.StatusBadRequest)
+		return nil, false
+	}
+	return ids, true
+}
+
+func parseId(w http.ResponseWriter, idString string) (int64, bool) {
+	ids, ok := parseIds(w, idString, 1)
+	if !ok {
+		return 0, false
+	}
+	return ids[0], true
+}
+
+func (s *Server) summonersByName(
+	w http.ResponseWriter, region string, names string, now time.Time) {
+	data := make(map[string]*riot.SummonerDto)
+	for _, name := range strings.Split(names, ",") {
+		if summoner := s.world.summonerByName(region, name); summoner != nil {
+			data[riot.CanonicalizeSummoner(name)] = summoner.summonerDto(now)
+		}
+	}
+	if len(data) == 0 {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+	writeJson(w, data)
+}
+
+func (s *Server) summonersById(
+	w http.ResponseWriter, region string, idList string, now time.Time) {
+	ids, ok := parseIds(w, idList, 40)
+	if !ok {
+		return
+	}
+	data := make(map[string]*riot.SummonerDto)
+	for _, id := range ids {
+		if summoner := s.world.summoner(region, id); summoner != nil {
+			data[strconv.FormatInt(id, 10)] = summoner.summonerDto(now)
+		}
+	}
+	if len(data) == 0 {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+	writeJson(w, data)
+}
+
+func (s *Server) runes(w http.ResponseWriter, region string, idList string) {
+	ids, ok := parseIds(w, idList, 40)
+	if !ok {
+		return
+	}
+	data := make(map[string]*riot.RunePagesDto)
+	for _, id := range ids {
+		if summoner := s.world.summoner(region, id); summoner != nil {
+			data[strconv.FormatInt(id, 10)] = summoner.runePagesDto()
+		}
+	}
+	if len(data) == 0 {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+	writeJson(w, data)
+}
+
+func (s *Server) leagueEntries(w http.ResponseWriter, region string, idList string) {
+	ids, ok := parseIds(w, idList, 10)
+	if !ok {
+		return
+	}
+	data := make(map[string][]*riot.LeagueDto)
+	for _, id := range ids {
+		if summoner := s.world.summoner(region, id); summoner != nil {
+			if leagues := summoner.leagueDtos(); leagues != nil {
+				data[strconv.FormatInt(id, 10)] = leagues
+			}
+		}
+	}
+	if len(data) == 0 {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+	writeJson(w, data)
+}
+
+// The 10 most recent games of the summoner.
+func (s *Server) recentGames(w http.ResponseWriter, region string, idString string) {
+	summonerId, ok := parseId(w, idString)
+	if !ok {
+		return
+	}
+	if s.world.summoner(region, summonerId) == nil {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+	dto := &riot.RecentGamesDto{SummonerId: summonerId, Games: []riot.GameDto{}}
+	for i, g := range s.world.gamesFor(region, summonerId) {
+		if i == 10 {
+			break
+		}
+		dto.Games = append(dto.Games, g.gameDto(s.world, summonerId))
+	}
+	writeJson(w, dto)
+}
+
+func (s *Server) rankedStats(w http.ResponseWriter, region string, idString string) {
+	summonerId, ok := parseId(w, idString)
+	if !ok {
+		return
+	}
+	if s.world.summoner(region, summonerId) == nil {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+	sessions := make(map[int]int)
+	for _, g := range s.world.gamesFor(region, summonerId) {
+		if g.isRanked() {
+			sessions[g.player(summonerId).ChampionId]++
+			sessions[riot.ChampionStatsDto_AllChampions]++
+		}
+	}
+	dto := &riot.RankedStatsDto{SummonerId: summonerId, Champions: []*riot.ChampionStatsDto{}}
+	for championId, n := range sessions {
+		dto.Champions = append(dto.Champions, &riot.ChampionStatsDto{
+			ChampionId: championId,
+			Stats:      &riot.AggregatedStatsDto{TotalSessionsPlayed: n},
+		})
+	}
+	writeJson(w, dto)
+}
+
+// Supports the championIds, rankedQueues, seasons, beginTime, endTime, beginIndex and
+// endIndex filters.
+func (s *Server) matchList(
+	w http.ResponseWriter, r *http.Request, region string, idString string) {
+	summonerId, ok := parseId(w, idString)
+	if !ok {
+		return
+	}
+	if s.world.summoner(region, summonerId) == nil {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+
+	args := r.URL.Query()
+	inList := func(name string, value string) bool {
+		list := args.Get(name)
+		if list == "" {
+			return true
+		}
+		for _, v := range strings.Split(list, ",") {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+	millisArg := func(name string) (int64, error) {
+		if args.Get(name) == "" {
+			return 0, nil
+		}
+		return strconv.ParseInt(args.Get(name), 10, 64)
+	}
+	beginTime, err1 := millisArg("beginTime")
+	endTime, err2 := millisArg("endTime")
+	beginIndex, err3 := millisArg("beginIndex")
+	endIndex, err4 := millisArg("endIndex")
+	for _, err := range []error{err1, err2, err3, err4} {
+		if err != nil {
+			writeStatus(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var matches []*riot.MatchReference
+	for _, g := range s.world.gamesFor(region, summonerId) {
+		created := g.Created.UnixNano() / int64(time.Millisecond)
+		if !g.isRanked() ||
+			!inList("championIds", strconv.Itoa(g.player(summonerId).ChampionId)) ||
+			!inList("rankedQueues", g.Queue) ||
+			!inList("seasons", g.Season) ||
+			(beginTime != 0 && created < beginTime) ||
+			(endTime != 0 && created > endTime) {
+			continue
+		}
+		matches = append(matches, g.matchReference(summonerId))
+	}
+
+	total := len(matches)
+	if endIndex == 0 || endIndex > int64(total) {
+		endIndex = int64(total)
+	}
+	if beginIndex > endIndex {
+		beginIndex = endIndex
+	}
+	writeJson(w, &riot.MatchList{
+		StartIndex: int(beginIndex),
+		EndIndex:   int(endIndex),
+		TotalGames: total,
+		Matches:    matches[beginIndex:endIndex],
+	})
+}
+
+// Like the live API, the timeline is only included with includeTimeline=true.
+func (s *Server) match(
+	w http.ResponseWriter, r *http.Request, region string, idString string) {
+	matchId, ok := parseId(w, idString)
+	if !ok {
+		return
+	}
+	g := s.world.game(region, matchId)
+	if g == nil {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+	m := g.matchDetail(s.world)
+	if r.URL.Query().Get("includeTimeline") != "true" {
+		m.Timeline = nil
+	}
+	writeJson(w, m)
+}
+
+// Handles /championmastery/location/<platform>/player/<id>/<champions|champion/<id>|
+// topchampions|score>.
+func (s *Server) serveChampionMastery(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(
+		strings.TrimPrefix(r.URL.Path, "/championmastery/location/"), "/")
+	if len(parts) < 4 || parts[1] != "player" {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+	region := regionForPlatform(parts[0])
+	summonerId, ok := parseId(w, parts[2])
+	if !ok {
+		return
+	}
+	if region == "" || s.world.summoner(region, summonerId) == nil {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+	masteries := s.world.championMasteries(region, summonerId)
+
+	switch {
+	case len(parts) == 4 && parts[3] == "champions":
+		writeJson(w, masteries)
+	case len(parts) == 5 && parts[3] == "champion":
+		championId, err := strconv.Atoi(parts[4])
+		if err != nil {
+			writeStatus(w, http.StatusBadRequest)
+			return
+		}
+		for _, m := range masteries {
+			if m.ChampionId == championId {
+				writeJson(w, m)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 4 && parts[3] == "topchampions":
+		count := 3
+		if n, err := strconv.Atoi(r.URL.Query().Get("count")); err == nil {
+			count = n
+		}
+		if count < len(masteries) {
+			masteries = masteries[:count]
+		}
+		writeJson(w, masteries)
+	case len(parts) == 4 && parts[3] == "score":
+		score := 0
+		for _, m := range masteries {
+			score += m.ChampionLevel
+		}
+		writeJson(w, score)
+	default:
+		writeStatus(w, http.StatusNotFound)
+	}
+}
+
+// Returns the code of the region with the given platform id, or "" if there is none.
+func regionForPlatform(platformId string) string {
+	for _, region := range riot.Regions {
+		if region.PlatformId == platformId {
+			return region.Code
+		}
+	}
+	return ""
+}
+
+// Handles /observer-mode/rest/consumer/getSpectatorGameInfo/<platform>/<id> and
+// /observer-mode/rest/featured.
+func (s *Server) serveObserverMode(w http.ResponseWriter, r *http.Request, now time.Time) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/observer-mode/rest/"), "/")
+	switch {
+	case len(parts) == 4 && parts[0] == "consumer" && parts[1] == "getSpectatorGameInfo":
+		summonerId, ok := parseId(w, parts[3])
+		if !ok {
+			return
+		}
+		g := s.world.liveGameFor(regionForPlatform(parts[2]), summonerId)
+		if g == nil {
+			writeStatus(w, http.StatusNotFound)
+			return
+		}
+		writeJson(w, g.currentGameInfo(s.world, now))
+	case len(parts) == 1 && parts[0] == "featured":
+		// Featured games are per host rather than per platform, and every region shares
+		// this host, so list the live games of every region.
+		featured := &riot.FeaturedGames{
+			ClientRefreshInterval: 300,
+			GameList:              []*riot.FeaturedGameInfo{},
+		}
+		for _, region := range riot.Regions {
+			for _, g := range s.world.liveGames(region.Code) {
+				featured.GameList = append(featured.GameList, g.featuredGameInfo(s.world, now))
+			}
+		}
+		writeJson(w, featured)
+	default:
+		writeStatus(w, http.StatusNotFound)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Scripting endpoints
+
+// Handles:
+//
+//	GET  /fake/world           Returns the world as JSON.
+//	PUT  /fake/world           Replaces the world with the JSON body.
+//	POST /fake/summoners       Adds or replaces the summoner in the JSON body.
+//	POST /fake/games           Adds or replaces the game in the JSON body.
+//	POST /fake/ratelimit?count=N&retry_after=S&type=T
+//	                           Answers the next N API requests with a 429.
+func (s *Server) serveFake(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	switch {
+	case r.URL.Path == "/fake/world" && r.Method == "GET":
+		w.Header().Set("Content-Type", "application/json;charset=utf-8")
+		data, _ := json.MarshalIndent(s.world, "", "  ")
+		w.Write(data)
+
+	case r.URL.Path == "/fake/world" && r.Method == "PUT":
+		world := new(World)
+		if err := json.NewDecoder(r.Body).Decode(world); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		world.normalize(now)
+		s.world = world
+
+	case r.URL.Path == "/fake/summoners" && r.Method == "POST":
+		summoner := new(Summoner)
+		if err := json.NewDecoder(r.Body).Decode(summoner); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		summoner.normalize()
+		summoners := s.world.Summoners[:0]
+		for _, existing := range s.world.Summoners {
+			if existing.Region != summoner.Region || existing.Id != summoner.Id {
+				summoners = append(summoners, existing)
+			}
+		}
+		s.world.Summoners = append(summoners, summoner)
+		writeJson(w, summoner)
+
+	case r.URL.Path == "/fake/games" && r.Method == "POST":
+		game := new(Game)
+		if err := json.NewDecoder(r.Body).Decode(game); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		games := s.world.Games[:0]
+		for _, existing := range s.world.Games {
+			if game.Id == 0 || existing.Region != game.Region || existing.Id != game.Id {
+				games = append(games, existing)
+			}
+		}
+		s.world.Games = append(games, game)
+		// Assigns an id if needed.
+		s.world.normalize(now)
+		writeJson(w, game)
+
+	case r.URL.Path == "/fake/ratelimit" && r.Method == "POST":
+		args := r.URL.Query()
+		count, err := strconv.Atoi(args.Get("count"))
+		if err != nil {
+			count = 1
+		}
+		retryAfter, _ := strconv.Atoi(args.Get("retry_after"))
+		s.forcedLimits = count
+		s.forcedRetryAfter = retryAfter
+		s.forcedLimitType = args.Get("type")
+
+	default:
+		http.Error(w, "unknown fake endpoint", http.StatusNotFound)
+	}
+}
@@ -0,0 +1,939 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/OwenDurni/loltools/riot"
+	"io/ioutil"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The summoners and games the fake API serves. Worlds are loaded from JSON (see
+// -world) or generated, and can be changed while the server runs through the /fake/
+// endpoints.
+type World struct {
+	Summoners []*Summoner
+	Games     []*Game
+}
+
+type Summoner struct {
+	// Defaults to "na".
+	Region        string
+	Id            int64
+	Name          string
+	Level         int
+	ProfileIconId int
+
+	// Solo queue rank. An empty Tier means unranked.
+	Tier         string
+	Division     string
+	LeaguePoints int
+
+	RunePages []*riot.RunePageDto
+}
+
+type Game struct {
+	// Defaults to "na".
+	Region string
+	// Used as both the game id and the match id. Assigned when 0.
+	Id int64
+	// Defaults to the time the game was added.
+	Created         time.Time
+	DurationSeconds int
+
+	// Default to Summoner's Rift custom games (MapId 11, "CLASSIC", "CUSTOM_GAME",
+	// "NONE" and "CUSTOM").
+	MapId   int
+	Mode    string
+	Type    string
+	SubType string
+	Queue   string
+	Season  string
+
+	// riot.BlueTeamId or riot.PurpleTeamId.
+	WinningTeam int
+	BlueBans    []int
+	PurpleBans  []int
+
+	Players    []*GamePlayer
+	Objectives []*Objective
+
+	// Live games are in progress. They are only visible through the current-game and
+	// featured-games endpoints, and Created is when the game started.
+	Live bool
+}
+
+type GamePlayer struct {
+	SummonerId int64
+	TeamId     int
+	ChampionId int
+	Spell1     int
+	Spell2     int
+
+	// Final stats. Timeline frames are interpolated from these.
+	Kills                int
+	Deaths               int
+	Assists              int
+	ChampLevel           int
+	GoldEarned           int
+	MinionsKilled        int
+	NeutralMinionsKilled int
+	WardsPlaced          int
+	// In purchase order. The first 7 are also the final inventory.
+	Items []int
+
+	// Ex: "TOP", "JUNGLE", "MIDDLE", "BOTTOM".
+	Lane string
+	// Ex: "SOLO", "NONE", "DUO_CARRY", "DUO_SUPPORT".
+	Role string
+}
+
+// A team objective taken during a game.
+type Objective struct {
+	// One of "DRAGON", "BARON_NASHOR", "RIFT_HERALD", "TOWER_BUILDING" or
+	// "INHIBITOR_BUILDING".
+	Type   string
+	TeamId int
+	Minute int
+}
+
+func (g *Game) isRanked() bool {
+	return strings.Contains(g.Queue, "RANKED")
+}
+
+func (g *Game) player(summonerId int64) *GamePlayer {
+	for _, p := range g.Players {
+		if p.SummonerId == summonerId {
+			return p
+		}
+	}
+	return nil
+}
+
+func (w *World) summoner(region string, id int64) *Summoner {
+	for _, s := range w.Summoners {
+		if s.Region == region && s.Id == id {
+			return s
+		}
+	}
+	return nil
+}
+
+func (w *World) summonerByName(region string, name string) *Summoner {
+	name = riot.CanonicalizeSummoner(name)
+	for _, s := range w.Summoners {
+		if s.Region == region && riot.CanonicalizeSummoner(s.Name) == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func (w *World) game(region string, id int64) *Game {
+	for _, g := range w.Games {
+		if g.Region == region && g.Id == id && !g.Live {
+			return g
+		}
+	}
+	return nil
+}
+
+// Returns the finished games in region that summonerId played in, newest first.
+func (w *World) gamesFor(region string, summonerId int64) []*Game {
+	var games []*Game
+	for _, g := range w.Games {
+		if g.Region == region && g.player(summonerId) != nil && !g.Live {
+			games = append(games, g)
+		}
+	}
+	sort.Sort(gamesNewestFirst(games))
+	return games
+}
+
+// Returns the live game summonerId is playing in region, if any.
+func (w *World) liveGameFor(region string, summonerId int64) *Game {
+	for _, g := range w.Games {
+		if g.Region == region && g.Live && g.player(summonerId) != nil {
+			return g
+		}
+	}
+	return nil
+}
+
+func (w *World) liveGames(region string) []*Game {
+	var games []*Game
+	for _, g := range w.Games {
+		if g.Region == region && g.Live {
+			games = append(games, g)
+		}
+	}
+	return games
+}
+
+type gamesNewestFirst []*Game
+
+func (a gamesNewestFirst) Len() int      { return len(a) }
+func (a gamesNewestFirst) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a gamesNewestFirst) Less(i, j int) bool {
+	return a[i].Created.After(a[j].Created)
+}
+
+// Fills in defaults for anything the world file left out.
+func (w *World) normalize(now time.Time) {
+	var maxGameId int64
+	for _, g := range w.Games {
+		if g.Id > maxGameId {
+			maxGameId = g.Id
+		}
+	}
+	for _, s := range w.Summoners {
+		s.normalize()
+	}
+	for _, g := range w.Games {
+		if g.Id == 0 {
+			maxGameId++
+			g.Id = maxGameId
+		}
+		g.normalize(now)
+	}
+}
+
+func (s *Summoner) normalize() {
+	if s.Region == "" {
+		s.Region = "na"
+	}
+	if s.Level == 0 {
+		s.Level = 30
+	}
+}
+
+func (g *Game) normalize(now time.Time) {
+	if g.Region == "" {
+		g.Region = "na"
+	}
+	if g.Created.IsZero() {
+		g.Created = now
+	}
+	if g.DurationSeconds == 0 {
+		g.DurationSeconds = 30 * 60
+	}
+	if g.MapId == 0 {
+		g.MapId = 11
+	}
+	if g.Mode == "" {
+		g.Mode = "CLASSIC"
+	}
+	if g.Type == "" {
+		g.Type = "CUSTOM_GAME"
+	}
+	if g.SubType == "" {
+		g.SubType = "NONE"
+	}
+	if g.Queue == "" {
+		g.Queue = "CUSTOM"
+	}
+	if g.Season == "" {
+		g.Season = "SEASON2016"
+	}
+	if g.WinningTeam == 0 {
+		g.WinningTeam = riot.BlueTeamId
+	}
+	for _, p := range g.Players {
+		if p.ChampLevel == 0 {
+			p.ChampLevel = 18 * g.DurationSeconds / (45 * 60)
+			if p.ChampLevel < 1 {
+				p.ChampLevel = 1
+			} else if p.ChampLevel > 18 {
+				p.ChampLevel = 18
+			}
+		}
+		if p.GoldEarned == 0 {
+			p.GoldEarned = 500 + 350*g.DurationSeconds/60
+		}
+	}
+}
+
+func loadWorld(path string) (*World, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	w := new(World)
+	if err := json.Unmarshal(data, w); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return w, nil
+}
+
+var generatedTeamNames = []string{
+	"Blue Ravens", "Crimson Foxes", "Golden Owls", "Silver Wolves",
+	"Iron Bears", "Jade Serpents", "Ember Hawks", "Frost Giants",
+}
+
+var generatedRoles = []struct {
+	Name string
+	Lane string
+	Role string
+}{
+	{"Top", "TOP", "SOLO"},
+	{"Jungle", "JUNGLE", "NONE"},
+	{"Mid", "MIDDLE", "SOLO"},
+	{"ADC", "BOTTOM", "DUO_CARRY"},
+	{"Support", "BOTTOM", "DUO_SUPPORT"},
+}
+
+var generatedTiers = []string{"BRONZE", "SILVER", "GOLD", "PLATINUM", "DIAMOND", ""}
+var generatedDivisions = []string{"I", "II", "III", "IV", "V"}
+
+// Returns a world of numTeams five player teams in region "na". Every pair of teams
+// has played one custom game against each other, the first two teams are in a live
+// game, and every summoner has a few ranked games. The same seed always generates the same world.
+func generateWorld(numTeams int, seed int64, now time.Time) *World {
+	rng := rand.New(rand.NewSource(seed))
+	w := new(World)
+
+	teams := make([][]*Summoner, numTeams)
+	for t := range teams {
+		teamName := fmt.Sprintf("Team %d", t+1)
+		if t < len(generatedTeamNames) {
+			teamName = generatedTeamNames[t]
+		}
+		for r, role := range generatedRoles {
+			s := &Summoner{
+				Region:        "na",
+				Id:            int64(10000 + 10*t + r),
+				Name:          fmt.Sprintf("%s %s", teamName, role.Name),
+				Level:         30,
+				ProfileIconId: rng.Intn(30),
+				Tier:          generatedTiers[rng.Intn(len(generatedTiers))],
+			}
+			if s.Tier != "" {
+				s.Division = generatedDivisions[rng.Intn(len(generatedDivisions))]
+				s.LeaguePoints = rng.Intn(100)
+			}
+			s.RunePages = []*riot.RunePageDto{
+				{Current: true, Id: s.Id*10 + 1, Name: "Generated"},
+			}
+			teams[t] = append(teams[t], s)
+			w.Summoners = append(w.Summoners, s)
+		}
+	}
+
+	nextGameId := int64(100000)
+	addGame := func(blue, purple []*Summoner, created time.Time, queue string) {
+		g := &Game{
+			Region:          "na",
+			Id:              nextGameId,
+			Created:         created,
+			DurationSeconds: 25*60 + rng.Intn(20*60),
+			Queue:           queue,
+			WinningTeam:     riot.BlueTeamId,
+		}
+		nextGameId++
+		if queue != "CUSTOM" {
+			g.Type = "MATCHED_GAME"
+			g.SubType = "RANKED_SOLO_5x5"
+		}
+		if rng.Intn(2) == 0 {
+			g.WinningTeam = riot.PurpleTeamId
+		}
+		g.normalize(now)
+		g.generateDetails(rng, blue, purple)
+		w.Games = append(w.Games, g)
+	}
+
+	// One custom game per pair of teams, a day apart, ending yesterday.
+	var pairs [][2]int
+	for a := 0; a < numTeams; a++ {
+		for b := a + 1; b < numTeams; b++ {
+			pairs = append(pairs, [2]int{a, b})
+		}
+	}
+	for i, pair := range pairs {
+		created := now.Add(-time.Duration(len(pairs)-i) * 24 * time.Hour)
+		addGame(teams[pair[0]], teams[pair[1]], created, "CUSTOM")
+	}
+
+	// The first two teams are playing each other right now.
+	if numTeams >= 2 {
+		addGame(teams[0], teams[1], now.Add(-10*time.Minute), "CUSTOM")
+		w.Games[len(w.Games)-1].Live = true
+	}
+
+	// Ranked games drawn from the whole pool.
+	if len(w.Summoners) >= 10 {
+		for i := 0; i < 3*numTeams; i++ {
+			perm := rng.Perm(len(w.Summoners))
+			var blue, purple []*Summoner
+			for j := 0; j < 5; j++ {
+				blue = append(blue, w.Summoners[perm[j]])
+				purple = append(purple, w.Summoners[perm[5+j]])
+			}
+			created := now.Add(-time.Duration(i+1) * 7 * time.Hour)
+			addGame(blue, purple, created, "TEAM_BUILDER_DRAFT_RANKED_5x5")
+		}
+	}
+	return w
+}
+
+// Fills in random players, bans and objectives for a generated game. blue and purple
+// are listed in generatedRoles order.
+func (g *Game) generateDetails(rng *rand.Rand, blue, purple []*Summoner) {
+	minutes := g.DurationSeconds / 60
+	champions := rng.Perm(130)
+	g.BlueBans = []int{champions[10] + 1, champions[11] + 1, champions[12] + 1}
+	g.PurpleBans = []int{champions[13] + 1, champions[14] + 1, champions[15] + 1}
+
+	for side, summoners := range [][]*Summoner{blue, purple} {
+		teamId := riot.BlueTeamId
+		if side == 1 {
+			teamId = riot.PurpleTeamId
+		}
+		won := teamId == g.WinningTeam
+		for r, s := range summoners {
+			role := generatedRoles[r%len(generatedRoles)]
+			p := &GamePlayer{
+				SummonerId:  s.Id,
+				TeamId:      teamId,
+				ChampionId:  champions[5*side+r] + 1,
+				Spell1:      4,
+				Spell2:      []int{12, 11, 14, 7, 3}[r%5],
+				Kills:       rng.Intn(8),
+				Deaths:      rng.Intn(8),
+				Assists:     rng.Intn(12),
+				GoldEarned:  6000 + rng.Intn(400*minutes),
+				WardsPlaced: 5 + rng.Intn(2*minutes),
+				Lane:        role.Lane,
+				Role:        role.Role,
+			}
+			if won {
+				p.Kills += 3
+			}
+			switch role.Lane {
+			case "JUNGLE":
+				p.MinionsKilled = rng.Intn(2 * minutes)
+				p.NeutralMinionsKilled = 3*minutes + rng.Intn(minutes)
+			default:
+				if role.Role != "DUO_SUPPORT" {
+					p.MinionsKilled = 5*minutes + rng.Intn(3*minutes)
+				} else {
+					p.MinionsKilled = rng.Intn(minutes)
+				}
+			}
+			p.Items = []int{1055, 2003, 3006}
+			for i := 0; i < 3+rng.Intn(3); i++ {
+				p.Items = append(p.Items, 3000+rng.Intn(200))
+			}
+			g.Players = append(g.Players, p)
+		}
+	}
+	g.normalize(g.Created)
+
+	for minute := 5; minute < minutes; minute += 5 + rng.Intn(4) {
+		types := []string{"DRAGON", "TOWER_BUILDING", "RIFT_HERALD", "TOWER_BUILDING"}
+		if minute >= 20 {
+			types = append(types, "BARON_NASHOR", "INHIBITOR_BUILDING")
+		}
+		teamId := g.WinningTeam
+		if rng.Intn(3) == 0 {
+			teamId = riot.BlueTeamId + riot.PurpleTeamId - g.WinningTeam
+		}
+		g.Objectives = append(g.Objectives, &Objective{
+			Type:   types[rng.Intn(len(types))],
+			TeamId: teamId,
+			Minute: minute,
+		})
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Conversion to Riot DTOs.
+
+func (s *Summoner) summonerDto(now time.Time) *riot.SummonerDto {
+	return &riot.SummonerDto{
+		Id:            s.Id,
+		Name:          s.Name,
+		ProfileIconId: s.ProfileIconId,
+		RevisionDate:  riot.RiotTime(now),
+		SummonerLevel: s.Level,
+	}
+}
+
+func (s *Summoner) runePagesDto() *riot.RunePagesDto {
+	pages := s.RunePages
+	if pages == nil {
+		pages = []*riot.RunePageDto{}
+	}
+	return &riot.RunePagesDto{Pages: pages, SummonerId: s.Id}
+}
+
+// Returns nil if the summoner is unranked.
+func (s *Summoner) leagueDtos() []*riot.LeagueDto {
+	if s.Tier == "" {
+		return nil
+	}
+	return []*riot.LeagueDto{{
+		Entries: []*riot.LeagueEntryDto{{
+			Division:     s.Division,
+			LeaguePoints: s.LeaguePoints,
+		}},
+		ParticipantId: strconv.FormatInt(s.Id, 10),
+		Queue:         "RANKED_SOLO_5x5",
+		Tier:          s.Tier,
+	}}
+}
+
+// Returns the game from the point of view of summonerId for game-v1.3.
+func (g *Game) gameDto(w *World, summonerId int64) riot.GameDto {
+	me := g.player(summonerId)
+	dto := riot.GameDto{
+		GameId:         g.Id,
+		MapId:          g.MapId,
+		CreateDate:     riot.RiotTime(g.Created),
+		GameMode:       g.Mode,
+		GameType:       g.Type,
+		SubType:        g.SubType,
+		TeamId:         me.TeamId,
+		ChampionId:     me.ChampionId,
+		SummonerSpell1: me.Spell1,
+		SummonerSpell2: me.Spell2,
+	}
+	if s := w.summoner(g.Region, summonerId); s != nil {
+		dto.Level = s.Level
+	}
+	for _, p := range g.Players {
+		if p != me {
+			dto.FellowPlayers = append(dto.FellowPlayers, riot.PlayerDto{
+				ChampionId: p.ChampionId,
+				SummonerId: p.SummonerId,
+				TeamId:     p.TeamId,
+			})
+		}
+	}
+	items := finalItems(me.Items)
+	dto.Stats = riot.RawStatsDto{
+		Win:                  me.TeamId == g.WinningTeam,
+		TimePlayed:           g.DurationSeconds,
+		ChampionsKilled:      me.Kills,
+		NumDeaths:            me.Deaths,
+		Assists:              me.Assists,
+		Level:                me.ChampLevel,
+		GoldEarned:           me.GoldEarned,
+		MinionsKilled:        me.MinionsKilled,
+		NeutralMinionsKilled: me.NeutralMinionsKilled,
+		Item0:                items[0],
+		Item1:                items[1],
+		Item2:                items[2],
+		Item3:                items[3],
+		Item4:                items[4],
+		Item5:                items[5],
+		Item6:                items[6],
+		WardPlaced:           me.WardsPlaced,
+	}
+	return dto
+}
+
+// Mastery points awarded per game played in the fake world.
+const masteryPointsPerGame = 1800
+
+// Returns the summoner's champion mastery, derived from the games they played,
+// highest first.
+func (w *World) championMasteries(region string, summonerId int64) []*riot.ChampionMasteryDto {
+	byChampion := make(map[int]*riot.ChampionMasteryDto)
+	var masteries []*riot.ChampionMasteryDto
+	for _, g := range w.gamesFor(region, summonerId) {
+		championId := g.player(summonerId).ChampionId
+		m, ok := byChampion[championId]
+		if !ok {
+			// Games are newest first so this is the last time the champion was played.
+			m = &riot.ChampionMasteryDto{
+				ChampionId:   championId,
+				LastPlayTime: riot.RiotTime(g.Created),
+				PlayerId:     summonerId,
+			}
+			byChampion[championId] = m
+			masteries = append(masteries, m)
+		}
+		m.ChampionPoints += masteryPointsPerGame
+	}
+	for _, m := range masteries {
+		m.ChampionLevel = 1 + m.ChampionPoints/(2*masteryPointsPerGame)
+		if m.ChampionLevel > 5 {
+			m.ChampionLevel = 5
+		}
+	}
+	sort.Stable(masteriesByPoints(masteries))
+	if masteries == nil {
+		masteries = []*riot.ChampionMasteryDto{}
+	}
+	return masteries
+}
+
+type masteriesByPoints []*riot.ChampionMasteryDto
+
+func (a masteriesByPoints) Len() int      { return len(a) }
+func (a masteriesByPoints) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a masteriesByPoints) Less(i, j int) bool {
+	return a[i].ChampionPoints > a[j].ChampionPoints
+}
+
+// Returns the game as current-game-v1.0 reports it at time now.
+func (g *Game) currentGameInfo(w *World, now time.Time) *riot.CurrentGameInfo {
+	info := &riot.CurrentGameInfo{
+		GameId:        g.Id,
+		GameLength:    int64(now.Sub(g.Created) / time.Second),
+		GameMode:      g.Mode,
+		GameStartTime: riot.RiotTime(g.Created),
+		GameType:      g.Type,
+		MapId:         g.MapId,
+		Observers:     &riot.Observer{EncryptionKey: fmt.Sprintf("fake%d", g.Id)},
+	}
+	if region, err := riot.Regions.Lookup(g.Region); err == nil {
+		info.PlatformId = region.PlatformId
+	}
+	// Bans alternate between the teams starting with blue.
+	for i, championId := range g.BlueBans {
+		info.BannedChampions = append(info.BannedChampions, &riot.CurrentGameBannedChampion{
+			ChampionId: championId, PickTurn: 2*i + 1, TeamId: riot.BlueTeamId})
+	}
+	for i, championId := range g.PurpleBans {
+		info.BannedChampions = append(info.BannedChampions, &riot.CurrentGameBannedChampion{
+			ChampionId: championId, PickTurn: 2*i + 2, TeamId: riot.PurpleTeamId})
+	}
+	for _, p := range g.Players {
+		participant := &riot.CurrentGameParticipant{
+			ChampionId: p.ChampionId,
+			Spell1Id:   p.Spell1,
+			Spell2Id:   p.Spell2,
+			SummonerId: p.SummonerId,
+			TeamId:     p.TeamId,
+		}
+		if s := w.summoner(g.Region, p.SummonerId); s != nil {
+			participant.SummonerName = s.Name
+			participant.ProfileIconId = s.ProfileIconId
+		}
+		info.Participants = append(info.Participants, participant)
+	}
+	return info
+}
+
+// Returns the game as featured-games-v1.0 reports it at time now.
+func (g *Game) featuredGameInfo(w *World, now time.Time) *riot.FeaturedGameInfo {
+	current := g.currentGameInfo(w, now)
+	info := &riot.FeaturedGameInfo{
+		BannedChampions: current.BannedChampions,
+		GameId:          current.GameId,
+		GameLength:      current.GameLength,
+		GameMode:        current.GameMode,
+		GameStartTime:   current.GameStartTime,
+		GameType:        current.GameType,
+		MapId:           current.MapId,
+		Observers:       current.Observers,
+		PlatformId:      current.PlatformId,
+	}
+	for _, p := range current.Participants {
+		info.Participants = append(info.Participants, &riot.FeaturedGameParticipant{
+			ChampionId:    p.ChampionId,
+			ProfileIconId: p.ProfileIconId,
+			Spell1Id:      p.Spell1Id,
+			Spell2Id:      p.Spell2Id,
+			SummonerName:  p.SummonerName,
+			TeamId:        p.TeamId,
+		})
+	}
+	return info
+}
+
+func finalItems(purchased []int) [7]int {
+	var items [7]int
+	copy(items[:], purchased)
+	return items
+}
+
+func (g *Game) matchReference(summonerId int64) *riot.MatchReference {
+	me := g.player(summonerId)
+	region, _ := riot.Regions.Lookup(g.Region)
+	ref := &riot.MatchReference{
+		Champion:  int64(me.ChampionId),
+		Lane:      me.Lane,
+		MatchId:   g.Id,
+		Queue:     g.Queue,
+		Region:    strings.ToUpper(g.Region),
+		Role:      me.Role,
+		Season:    g.Season,
+		Timestamp: riot.RiotTime(g.Created),
+	}
+	if region != nil {
+		ref.PlatformId = region.PlatformId
+	}
+	return ref
+}
+
+// Returns the match-v2.2 detail for the game. The timeline has a frame per minute
+// with stats interpolated linearly towards each player's final stats.
+func (g *Game) matchDetail(w *World) *riot.MatchDetail {
+	region, _ := riot.Regions.Lookup(g.Region)
+	m := &riot.MatchDetail{
+		MapId:         g.MapId,
+		MatchCreation: g.Created.UnixNano() / int64(time.Millisecond),
+		MatchDuration: int64(g.DurationSeconds),
+		MatchId:       g.Id,
+		MatchMode:     g.Mode,
+		MatchType:     g.Type,
+		MatchVersion:  "6.12.1",
+		QueueType:     g.Queue,
+		Region:        strings.ToUpper(g.Region),
+		Season:        g.Season,
+	}
+	if region != nil {
+		m.PlatformId = region.PlatformId
+	}
+
+	participantIds := make(map[int64]int)
+	for i, p := range g.Players {
+		participantId := i + 1
+		participantIds[p.SummonerId] = participantId
+
+		identity := &riot.ParticipantIdentity{
+			ParticipantId: participantId,
+			Player:        &riot.Player{SummonerId: p.SummonerId},
+		}
+		tier := "UNRANKED"
+		if s := w.summoner(g.Region, p.SummonerId); s != nil {
+			identity.Player.SummonerName = s.Name
+			identity.Player.ProfileIcon = s.ProfileIconId
+			if s.Tier != "" {
+				tier = s.Tier
+			}
+		}
+		m.ParticipantIdentities = append(m.ParticipantIdentities, identity)
+
+		items := finalItems(p.Items)
+		m.Participants = append(m.Participants, &riot.Participant{
+			ChampionId:                p.ChampionId,
+			HighestAchievedSeasonTier: tier,
+			ParticipantId:             participantId,
+			Summoner1:                 p.Spell1,
+			Summoner2:                 p.Spell2,
+			TeamId:                    p.TeamId,
+			Stats: &riot.ParticipantStats{
+				Assists:              int64(p.Assists),
+				ChampLevel:           int64(p.ChampLevel),
+				Deaths:               int64(p.Deaths),
+				GoldEarned:           int64(p.GoldEarned),
+				Item0:                int64(items[0]),
+				Item1:                int64(items[1]),
+				Item2:                int64(items[2]),
+				Item3:                int64(items[3]),
+				Item4:                int64(items[4]),
+				Item5:                int64(items[5]),
+				Item6:                int64(items[6]),
+				Kills:                int64(p.Kills),
+				MinionsKilled:        int64(p.MinionsKilled),
+				NeutralMinionsKilled: int64(p.NeutralMinionsKilled),
+				WardsPlaced:          int64(p.WardsPlaced),
+				Winner:               p.TeamId == g.WinningTeam,
+			},
+			Timeline: &riot.ParticipantTimeline{Lane: p.Lane, Role: p.Role},
+		})
+	}
+
+	events := g.events(participantIds)
+	m.Teams = g.teams(events)
+	m.Timeline = g.timeline(events)
+	return m
+}
+
+// Returns the kill, ward, item and objective events of the game in time order.
+func (g *Game) events(participantIds map[int64]int) []*riot.Event {
+	durationMillis := int64(g.DurationSeconds) * 1000
+	var events []*riot.Event
+
+	// Spreads n events evenly over the game.
+	spread := func(i, n int) int64 {
+		return durationMillis * int64(i+1) / int64(n+1)
+	}
+
+	for _, p := range g.Players {
+		participantId := participantIds[p.SummonerId]
+		var victims []int
+		for _, other := range g.Players {
+			if other.TeamId != p.TeamId {
+				victims = append(victims, participantIds[other.SummonerId])
+			}
+		}
+		for i := 0; i < p.Kills && len(victims) > 0; i++ {
+			events = append(events, &riot.Event{
+				EventType: "CHAMPION_KILL",
+				KillerId:  participantId,
+				VictimId:  victims[i%len(victims)],
+				Timestamp: spread(i, p.Kills),
+			})
+		}
+		for i := 0; i < p.WardsPlaced; i++ {
+			events = append(events, &riot.Event{
+				EventType: "WARD_PLACED",
+				CreatorId: participantId,
+				WardType:  "YELLOW_TRINKET",
+				Timestamp: spread(i, p.WardsPlaced),
+			})
+		}
+		for i, item := range p.Items {
+			events = append(events, &riot.Event{
+				EventType:     "ITEM_PURCHASED",
+				ParticipantId: participantId,
+				ItemId:        item,
+				Timestamp:     spread(i, len(p.Items)),
+			})
+		}
+	}
+	for _, o := range g.Objectives {
+		e := &riot.Event{
+			TeamId:    o.TeamId,
+			Timestamp: int64(o.Minute) * 60 * 1000,
+		}
+		switch o.Type {
+		case "TOWER_BUILDING", "INHIBITOR_BUILDING":
+			e.EventType = "BUILDING_KILL"
+			e.BuildingType = o.Type
+			// BUILDING_KILL events report the team that lost the building.
+			e.TeamId = riot.BlueTeamId + riot.PurpleTeamId - o.TeamId
+		default:
+			e.EventType = "ELITE_MONSTER_KILL"
+			e.MonsterType = o.Type
+		}
+		// Attribute the objective to the first player on the taking team.
+		for _, p := range g.Players {
+			if p.TeamId == o.TeamId {
+				e.KillerId = participantIds[p.SummonerId]
+				break
+			}
+		}
+		events = append(events, e)
+	}
+	sort.Stable(eventsByTime(events))
+	return events
+}
+
+type eventsByTime []*riot.Event
+
+func (a eventsByTime) Len() int           { return len(a) }
+func (a eventsByTime) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a eventsByTime) Less(i, j int) bool { return a[i].Timestamp < a[j].Timestamp }
+
+func (g *Game) teams(events []*riot.Event) []*riot.Team {
+	blue := &riot.Team{TeamId: riot.BlueTeamId, Winner: g.WinningTeam == riot.BlueTeamId}
+	purple := &riot.Team{TeamId: riot.PurpleTeamId, Winner: g.WinningTeam == riot.PurpleTeamId}
+	for i, championId := range g.BlueBans {
+		blue.Bans = append(blue.Bans, &riot.BannedChampion{ChampionId: championId, PickTurn: 2*i + 1})
+	}
+	for i, championId := range g.PurpleBans {
+		purple.Bans = append(purple.Bans, &riot.BannedChampion{ChampionId: championId, PickTurn: 2*i + 2})
+	}
+
+	teamOf := func(participantId int) *riot.Team {
+		if participantId < 1 || participantId > len(g.Players) {
+			return nil
+		}
+		if g.Players[participantId-1].TeamId == riot.BlueTeamId {
+			return blue
+		}
+		return purple
+	}
+	var firstBlood, firstTower, firstInhibitor, firstDragon, firstBaron, firstHerald bool
+	for _, e := range events {
+		t := teamOf(e.KillerId)
+		if t == nil {
+			continue
+		}
+		switch e.EventType {
+		case "CHAMPION_KILL":
+			if !firstBlood {
+				t.FirstBlood, firstBlood = true, true
+			}
+		case "BUILDING_KILL":
+			if e.BuildingType == "TOWER_BUILDING" {
+				t.TowerKills++
+				if !firstTower {
+					t.FirstTower, firstTower = true, true
+				}
+			} else {
+				t.InhibitorKills++
+				if !firstInhibitor {
+					t.FirstInhibitor, firstInhibitor = true, true
+				}
+			}
+		case "ELITE_MONSTER_KILL":
+			switch e.MonsterType {
+			case "DRAGON":
+				t.DragonKills++
+				if !firstDragon {
+					t.FirstDragon, firstDragon = true, true
+				}
+			case "BARON_NASHOR":
+				t.BaronKills++
+				if !firstBaron {
+					t.FirstBaron, firstBaron = true, true
+				}
+			case "RIFT_HERALD":
+				t.RiftHeraldKills++
+				if !firstHerald {
+					t.FirstRiftHerald, firstHerald = true, true
+				}
+			}
+		}
+	}
+	return []*riot.Team{blue, purple}
+}
+
+func (g *Game) timeline(events []*riot.Event) *riot.Timeline {
+	const frameInterval = 60 * 1000
+	durationMillis := int64(g.DurationSeconds) * 1000
+	numFrames := int(durationMillis/frameInterval) + 1
+	if durationMillis%frameInterval != 0 {
+		numFrames++
+	}
+
+	t := &riot.Timeline{FrameInterval: frameInterval}
+	for f := 0; f < numFrames; f++ {
+		timestamp := int64(f) * frameInterval
+		if timestamp > durationMillis {
+			timestamp = durationMillis
+		}
+		// Fraction of the game completed at this frame.
+		progress := float64(timestamp) / float64(durationMillis)
+		interpolate := func(start, end int) int {
+			return start + int(progress*float64(end-start))
+		}
+
+		frame := &riot.Frame{
+			ParticipantFrames: make(map[string]riot.ParticipantFrame),
+			Timestamp:         riot.RiotTime(time.Unix(0, timestamp*int64(time.Millisecond))),
+		}
+		for i, p := range g.Players {
+			participantId := i + 1
+			totalGold := interpolate(500, p.GoldEarned)
+			frame.ParticipantFrames[strconv.Itoa(participantId)] = riot.ParticipantFrame{
+				CurrentGold:         totalGold / 10,
+				JungleMinionsKilled: interpolate(0, p.NeutralMinionsKilled),
+				Level:               interpolate(1, p.ChampLevel),
+				MinionsKilled:       interpolate(0, p.MinionsKilled),
+				ParticipantId:       participantId,
+				TotalGold:           totalGold,
+				Xp:                  interpolate(0, 1020*p.ChampLevel),
+			}
+		}
+		for _, e := range events {
+			// Each frame holds the events since the previous frame.
+			if e.Timestamp > timestamp-frameInterval && e.Timestamp <= timestamp {
+				frame.Events = append(frame.Events, e)
+			}
+		}
+		t.Frames = append(t.Frames, frame)
+	}
+	return t
+}
@@ -1,9 +1,9 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"github.com/OwenDurni/loltools/riot"
-	"github.com/OwenDurni/loltools/util/web"
 	"golang.org/x/net/context"
 	"golang.org/x/time/rate"
 	"io/ioutil"
@@ -22,12 +22,26 @@ func check(err error) {
 	}
 }
 
+var (
+	riotBaseUrl = flag.String("riot_base_url", "",
+		"send requests here instead of the live API (ex: a local tools/fakeriot)")
+	cacheDir = flag.String("cache_dir", "riot-cache",
+		"directory Riot responses are cached in; empty disables caching")
+)
+
 // Usage:
-//   go run tools/ranked_data.go [summoner...] 2> /dev/null
+//
+//	go run tools/ranked_data.go [-riot_base_url url] [summoner...] 2> /dev/null
 func main() {
+	flag.Parse()
+
 	var riotApiKey string
 	{
 		contents, err := ioutil.ReadFile("riot-api-key")
+		if os.IsNotExist(err) && *riotBaseUrl != "" {
+			// The fake server accepts any key.
+			contents, err = []byte("fake"), nil
+		}
 		check(err)
 		riotApiKey = string(contents)
 	}
@@ -40,49 +54,69 @@ func main() {
 		gamesSinceStartDate = riot.RiotTime(t)
 	}
 
-	var rateLimiter func()
+	ctx := context.Background()
+	var rateLimiter func(context.Context) error
 	{
 		// Create a rate limiter that allows 1 call every 2 seconds with a burst of
 		// 10. Wait 10 at the beginning so that consecutive invocations of this tool
 		// do not exceed the rate limit (e.g. the bucket should start empty).
-		ctx := context.Background()
 		lim := rate.NewLimiter(0.5, 10)
 		lim.WaitN(ctx, 10)
-		rateLimiter = func() {
-			lim.Wait(ctx)
+		rateLimiter = func(ctx context.Context) error {
+			return lim.Wait(ctx)
 		}
 	}
 
-	for _, arg := range os.Args[1:] {
+	regions := riot.Regions
+	if *riotBaseUrl != "" {
+		regions = regions.WithBaseUrl(*riotBaseUrl)
+	}
+	var cache riot.Cache
+	if *cacheDir != "" {
+		cache = &riot.FileCache{Dir: *cacheDir}
+	}
+	client := riot.NewClient(riot.ClientOptions{
+		ApiKey:    riot.StaticApiKey(riotApiKey),
+		RateLimit: rateLimiter,
+		Region:    Region,
+		Regions:   regions,
+		Cache:     cache,
+	})
+
+	for _, arg := range flag.Args() {
 		summoner := arg
-		summonerData, err := riot.SummonerByName(
-			web.FetchUrl, rateLimiter, riotApiKey, Region, summoner)
+		summonerData, err := client.SummonerByName(ctx, summoner)
 		check(err)
 
 		summonerId := summonerData.Id
 
-		rankedStats, err := riot.RankedStatsBySummonerId(
-			web.FetchUrl, rateLimiter, riotApiKey, Region, summonerId)
+		rankedStats, err := client.RankedStatsBySummonerId(ctx, summonerId)
 		check(err)
 
-		rankedGames, err := riot.RankedGameHistoryBySummonerIdSince(
-			web.FetchUrl, rateLimiter, riotApiKey, Region, summonerId,
-			gamesSinceStartDate)
-		check(err)
+		// Walk every page of the summoner's history since the start date.
+		var rankedGames []*riot.MatchReference
+		it := client.MatchListIterator(
+			summonerId, &riot.MatchListQuery{BeginTime: gamesSinceStartDate})
+		for {
+			ref, err := it.Next(ctx)
+			if err == riot.Done {
+				break
+			}
+			check(err)
+			rankedGames = append(rankedGames, ref)
+		}
 
 		var sampleMatchId *int64 = nil
-		if len(rankedGames.Matches) > 0 {
-			sampleMatchId = &rankedGames.Matches[0].MatchId
+		if len(rankedGames) > 0 {
+			sampleMatchId = &rankedGames[0].MatchId
 		}
 
-		soloRank, err := riot.SoloQueueRankBySummonerId(
-			web.FetchUrl, rateLimiter, riotApiKey, Region, summonerId)
+		soloRank, err := client.SoloQueueRankBySummonerId(ctx, summonerId)
 		check(err)
 
 		previousSeasonRank := "Unranked"
 		if sampleMatchId != nil {
-			match, err := riot.LookupMatch(
-				web.FetchUrl, rateLimiter, riotApiKey, Region, *sampleMatchId)
+			match, err := client.LookupMatch(ctx, *sampleMatchId)
 			check(err)
 			for _, pid := range match.ParticipantIdentities {
 				if pid.Player.SummonerId == summonerId {
@@ -96,14 +130,14 @@ func main() {
 		}
 
 		totalRankedGames := 0
-		totalGamesSinceDate := rankedGames.TotalGames
+		totalGamesSinceDate := len(rankedGames)
 		for _, championStats := range rankedStats.Champions {
 			if championStats.ChampionId == riot.ChampionStatsDto_AllChampions {
 				totalRankedGames = championStats.Stats.TotalSessionsPlayed
 			}
 		}
 
-		for _, rankedGame := range rankedGames.Matches {
+		for _, rankedGame := range rankedGames {
 			fmt.Fprintln(
 				os.Stderr, time.Time(rankedGame.Timestamp).Format(time.RFC3339))
 		}
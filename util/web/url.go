@@ -2,16 +2,19 @@ package web
 
 import (
 	"fmt"
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
 	"io/ioutil"
 	"net/http"
 	"os"
 )
 
 // Returns the contents of the page at `loc` and the HTTP status code of the
-// response.
-func FetchUrl(loc string) ([]byte, int, error) {
+// response. The fetch is abandoned with ctx.Err() once ctx is done, so callers
+// wanting a timeout should pass a context from context.WithTimeout.
+func FetchUrl(ctx context.Context, loc string) ([]byte, int, error) {
 	fmt.Fprintf(os.Stderr, "Fetch: %s\n", loc)
-	res, err := http.Get(loc)
+	res, err := ctxhttp.Get(ctx, http.DefaultClient, loc)
 	if err != nil {
 		return nil, 0, err
 	}
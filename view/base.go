@@ -127,10 +127,27 @@ func tmpl_gold(gold int) string {
 	return fmt.Sprintf("%0.1fk", float64(gold)/1000.)
 }
 
+func tmpl_minus(a, b int) int {
+	return a - b
+}
+
+// Formats d as minutes and seconds (ex: "12:05").
+func tmpl_duration(d time.Duration) string {
+	seconds := int(d.Seconds())
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// Formats a fraction from 0.0 to 1.0 as a whole percentage.
+func tmpl_percent(f float64) string {
+	return fmt.Sprintf("%.0f%%", f*100)
+}
+
 func tmpl_riot_history_link(region string, gameId int64) string {
-	return fmt.Sprintf(
-		"http://matchhistory.%s.leagueoflegends.com/%s/#match-details/%s/%d",
-		region, "en", "NA1", gameId)
+	r, err := riot.Regions.Lookup(region)
+	if err != nil {
+		return ""
+	}
+	return r.MatchHistoryUrl("en", gameId)
 }
 
 func tmpl_time_deltanow(t time.Time) string {
@@ -214,10 +231,13 @@ var tmplFuncRegistry = template.FuncMap{
 	"dds_sx":   tmpl_dds_sx,
 	"dds_sy":   tmpl_dds_sy,
 
+	"duration":          tmpl_duration,
 	"even":              tmpl_even,
 	"form":              tmpl_form,
 	"gold":              tmpl_gold,
+	"minus":             tmpl_minus,
 	"odd":               tmpl_odd,
+	"percent":           tmpl_percent,
 	"riot_history_link": tmpl_riot_history_link,
 	"time_deltanow":     tmpl_time_deltanow,
 }
@@ -0,0 +1,328 @@
+package view
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"errors"
+	"fmt"
+	"github.com/OwenDurni/loltools/model"
+	"net/http"
+	"strconv"
+)
+
+type Bracket struct {
+	Name  string
+	Id    string
+	Uri   string
+	Type  string
+	Teams int
+}
+
+func (b *Bracket) Fill(
+	bracket *model.Bracket, bracketKey *datastore.Key, leagueKey *datastore.Key) *Bracket {
+	b.Name = bracket.Name
+	b.Id = model.BracketId(bracketKey)
+	b.Uri = model.LeagueBracketUri(leagueKey, bracketKey)
+	b.Type = bracket.Type.String()
+	b.Teams = len(bracket.Seeds)
+	return b
+}
+
+type BracketTeam struct {
+	Team
+	Seed int
+	Won  bool
+}
+
+type BracketSlot struct {
+	Id       string
+	MatchUri string
+	// Nil if not yet known, or for a bye if the slot is resolved.
+	Home *BracketTeam
+	Away *BracketTeam
+	Bye  bool
+
+	// Set while both teams are known and neither has won. A league editor may choose the
+	// winner, such as for a tied match.
+	Undecided    bool
+	WinnerChosen bool
+}
+
+type BracketRound struct {
+	Name  string
+	Slots []BracketSlot
+}
+
+type BracketSection struct {
+	Name   string
+	Rounds []BracketRound
+}
+
+func bracketSectionName(section model.BracketSection, typ model.BracketType) string {
+	switch section {
+	case model.LosersSection:
+		return "Losers"
+	case model.GrandFinalSection:
+		return "Grand Final"
+	}
+	if typ == model.DoubleElimination {
+		return "Winners"
+	}
+	return ""
+}
+
+func LeagueBracketsHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+	leagueId := args["leagueId"]
+
+	user, userKey, err := model.GetUser(c)
+	if HandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, leagueId)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	brackets, bracketKeys, err := model.LeagueBrackets(c, userAcls, league, leagueKey)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	divisions, err := currentDivisions(c, userAcls, league, leagueKey)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	// Populate view context.
+	ctx := struct {
+		ctxBase
+		League
+		Brackets  []Bracket
+		Divisions []Division
+	}{}
+	ctx.ctxBase.init(c, user)
+	ctx.ctxBase.Title = fmt.Sprintf("loltools > %s > Brackets", league.Name)
+	ctx.League.Fill(league, leagueKey)
+	ctx.Divisions = divisions
+
+	ctx.Brackets = make([]Bracket, len(brackets))
+	for i, b := range brackets {
+		ctx.Brackets[i].Fill(b, bracketKeys[i], leagueKey)
+	}
+
+	// Render
+	err = RenderTemplate(w, "leagues/brackets/index.html", "base", ctx)
+	if HandleError(c, w, err) {
+		return
+	}
+}
+
+func BracketViewHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+	leagueId := args["leagueId"]
+	bracketId := args["bracketId"]
+
+	user, userKey, err := model.GetUser(c)
+	if HandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, leagueId)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	bracket, bracketKey, slots, err := model.BracketById(
+		c, userAcls, league, leagueKey, bracketId)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	teams, teamKeys, err := model.LeagueAllTeams(c, userAcls, league, leagueKey)
+	if HandleError(c, w, err) {
+		return
+	}
+	teamsById := make(map[string]*Team)
+	for i, t := range teams {
+		teamsById[teamKeys[i].Encode()] = new(Team).Fill(t, teamKeys[i], leagueKey)
+	}
+	seeds := make(map[string]int)
+	for i, k := range bracket.Seeds {
+		seeds[k.Encode()] = i + 1
+	}
+	bracketTeam := func(teamKey *datastore.Key, winner *datastore.Key) *BracketTeam {
+		if teamKey == nil {
+			return nil
+		}
+		t := &BracketTeam{Seed: seeds[teamKey.Encode()]}
+		if team := teamsById[teamKey.Encode()]; team != nil {
+			t.Team = *team
+		}
+		t.Won = winner != nil && winner.Equal(teamKey)
+		return t
+	}
+
+	// Populate view context.
+	ctx := struct {
+		ctxBase
+		League
+		Bracket
+		Sections []*BracketSection
+	}{}
+	ctx.ctxBase.init(c, user)
+	ctx.ctxBase.Title = fmt.Sprintf("loltools > %s > %s", league.Name, bracket.Name)
+	ctx.League.Fill(league, leagueKey)
+	ctx.Bracket.Fill(bracket, bracketKey, leagueKey)
+
+	// Slots are ordered by section, round and position.
+	var section *BracketSection
+	for i, slot := range slots {
+		if i == 0 || slot.Section != slots[i-1].Section {
+			section = &BracketSection{Name: bracketSectionName(slot.Section, bracket.Type)}
+			ctx.Sections = append(ctx.Sections, section)
+		}
+		if len(section.Rounds) == 0 || slot.Round != slots[i-1].Round {
+			section.Rounds = append(section.Rounds, BracketRound{
+				Name: fmt.Sprintf("Round %d", slot.Round),
+			})
+		}
+		round := &section.Rounds[len(section.Rounds)-1]
+
+		s := BracketSlot{
+			Id:   slot.Id,
+			Home: bracketTeam(slot.HomeTeam, slot.Winner),
+			Away: bracketTeam(slot.AwayTeam, slot.Winner),
+			Bye:  slot.Resolved && (slot.HomeTeam == nil || slot.AwayTeam == nil),
+
+			Undecided: !slot.Resolved && slot.HomeReady && slot.AwayReady &&
+				slot.HomeTeam != nil && slot.AwayTeam != nil,
+			WinnerChosen: slot.WinnerChosen,
+		}
+		if slot.ScheduledMatch != nil {
+			s.MatchUri = model.LeagueMatchUri(leagueKey, slot.ScheduledMatch)
+		}
+		round.Slots = append(round.Slots, s)
+	}
+	for _, section := range ctx.Sections {
+		if len(section.Rounds) == 1 {
+			section.Rounds[0].Name = ""
+		}
+	}
+
+	// Render
+	err = RenderTemplate(w, "leagues/brackets/view.html", "base", ctx)
+	if HandleError(c, w, err) {
+		return
+	}
+}
+
+func ApiBracketCreateHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+
+	bracketType, err := model.ParseBracketType(r.FormValue("type"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	// Parse the numeric fields. Empty fields are zero.
+	var numTeams, numGames, mapId, windowDays int64
+	fields := []struct {
+		name  string
+		value *int64
+	}{
+		{"teams", &numTeams},
+		{"num-games", &numGames},
+		{"map", &mapId},
+		{"window-days", &windowDays},
+	}
+	for _, f := range fields {
+		if r.FormValue(f.name) == "" {
+			continue
+		}
+		*f.value, err = strconv.ParseInt(r.FormValue(f.name), 10, 32)
+		if err != nil || *f.value < 0 {
+			ApiHandleError(c, w, errors.New(fmt.Sprintf(
+				"'%s' must be a non-negative number: '%s'", f.name, r.FormValue(f.name))))
+			return
+		}
+	}
+
+	_, userKey, err := model.GetUser(c)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, r.FormValue("league"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	bracket := &model.Bracket{
+		Name:       r.FormValue("name"),
+		Type:       bracketType,
+		Summary:    r.FormValue("summary"),
+		NumGames:   int(numGames),
+		MapId:      int(mapId),
+		GameMode:   r.FormValue("mode"),
+		WindowDays: int(windowDays),
+	}
+	scope, err := model.LeagueScopeById(
+		c, userAcls, league, leagueKey, "", r.FormValue("division"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	bracketKey, err := model.CreateBracket(
+		c, userAcls, league, leagueKey, scope, bracket, int(numTeams))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	HttpReplyResourceCreated(w, model.LeagueBracketUri(leagueKey, bracketKey))
+}
+
+// Chooses the winner of a bracket slot on behalf of a league editor.
+func ApiBracketChooseWinnerHandler(
+	w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+
+	var homeWon bool
+	switch winner := r.FormValue("winner"); winner {
+	case "home":
+		homeWon = true
+	case "away":
+		homeWon = false
+	default:
+		ApiHandleError(c, w, errors.New(fmt.Sprintf("Unrecognized winner '%s'", winner)))
+		return
+	}
+
+	_, userKey, err := model.GetUser(c)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, r.FormValue("league"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	_, bracketKey, _, err := model.BracketById(
+		c, userAcls, league, leagueKey, r.FormValue("bracket"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	err = model.BracketChooseWinner(
+		c, userAcls, league, leagueKey, bracketKey, r.FormValue("slot"), homeWon)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	HttpReplyOkEmpty(w)
+}
@@ -0,0 +1,131 @@
+package view
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"fmt"
+	"github.com/OwenDurni/loltools/model"
+	"github.com/OwenDurni/loltools/model/tags"
+	"net/http"
+	"sort"
+)
+
+type DraftFilter struct {
+	Action string
+	Tag    string
+	Tags   []string
+}
+
+// Returns the keys of the games detected as results of the league's scheduled matches,
+// limited to matches with primaryTag (if not empty) that teamKey (if not nil) plays
+// in. Also returns every distinct primary tag of the league's matches.
+func leagueMatchGameKeys(
+	c appengine.Context,
+	userAcls *model.RequestorAclCache,
+	league *model.League,
+	leagueKey *datastore.Key,
+	teamKey *datastore.Key,
+	primaryTag string) ([]*datastore.Key, []string, error) {
+	matches, matchKeys, err := model.LeagueScheduledMatches(
+		c, userAcls, league, leagueKey, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var gameKeys []*datastore.Key
+	seenGames := make(map[string]bool)
+	seenTags := make(map[string]bool)
+	var primaryTags []string
+	for i, match := range matches {
+		if match.PrimaryTag != "" && !seenTags[match.PrimaryTag] {
+			seenTags[match.PrimaryTag] = true
+			primaryTags = append(primaryTags, match.PrimaryTag)
+		}
+		if primaryTag != "" && match.PrimaryTag != primaryTag {
+			continue
+		}
+		if teamKey != nil && !match.HasTeam(teamKey) {
+			continue
+		}
+		matchGameKeys, err := model.GameKeysWithTag(
+			c, leagueKey, tags.AutomaticallyDetectedMatchResultFor(matchKeys[i]))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, gameKey := range matchGameKeys {
+			if !seenGames[gameKey.StringID()] {
+				seenGames[gameKey.StringID()] = true
+				gameKeys = append(gameKeys, gameKey)
+			}
+		}
+	}
+	sort.Strings(primaryTags)
+	return gameKeys, primaryTags, nil
+}
+
+func LeagueDraftHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+	leagueId := args["leagueId"]
+	primaryTag := r.FormValue("tag")
+
+	user, userKey, err := model.GetUser(c)
+	if HandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, leagueId)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	teams, teamKeys, err := model.LeagueAllTeams(c, userAcls, league, leagueKey)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	gameKeys, primaryTags, err := leagueMatchGameKeys(
+		c, userAcls, league, leagueKey, nil, primaryTag)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	report, err := model.GetDraftReport(c, userAcls, league, leagueKey, teamKeys, gameKeys)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	// Populate view context.
+	type teamDraft struct {
+		Team
+		Draft *model.TeamDraftReport
+	}
+	ctx := struct {
+		ctxBase
+		League
+		Filter DraftFilter
+		Draft  *model.DraftReport
+		Teams  []teamDraft
+	}{}
+	ctx.ctxBase.init(c, user)
+	ctx.ctxBase.Title = fmt.Sprintf("loltools > %s > Draft", league.Name)
+	ctx.League.Fill(league, leagueKey)
+	ctx.Filter = DraftFilter{
+		Action: fmt.Sprintf("%s/draft", model.LeagueUri(leagueKey)),
+		Tag:    primaryTag,
+		Tags:   primaryTags,
+	}
+	ctx.Draft = report
+
+	ctx.Teams = make([]teamDraft, len(teams))
+	for i, t := range teams {
+		ctx.Teams[i].Team.Fill(t, teamKeys[i], leagueKey)
+		ctx.Teams[i].Draft = report.Teams[i]
+	}
+
+	// Render
+	err = RenderTemplate(w, "leagues/draft.html", "base", ctx)
+	if HandleError(c, w, err) {
+		return
+	}
+}
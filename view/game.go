@@ -3,12 +3,15 @@ package view
 import (
 	"appengine"
 	"fmt"
+	"github.com/OwenDurni/loltools/analysis"
 	"github.com/OwenDurni/loltools/model"
 	"net/http"
 )
 
 func GameViewHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
 	c := appengine.NewContext(r)
+	reqCtx, cancel := model.NewRequestContext(r)
+	defer cancel()
 	gameId := args["gameId"]
 
 	user, _, err := model.GetUser(c)
@@ -16,7 +19,7 @@ func GameViewHandler(w http.ResponseWriter, r *http.Request, args map[string]str
 		return
 	}
 
-	game, _, err := model.GameById(c, gameId)
+	game, gameKey, err := model.GameById(c, gameId)
 	playerCache := model.NewPlayerCache(c, game.Region)
 	gameInfo, errs := model.GetGameInfo(c, playerCache, game)
 	if HandleError(c, w, errs...) {
@@ -26,11 +29,17 @@ func GameViewHandler(w http.ResponseWriter, r *http.Request, args map[string]str
 	ctx := struct {
 		ctxBase
 		GameInfo *model.GameInfo
+		Analysis *analysis.MatchAnalysis
 	}{}
 	ctx.ctxBase.init(c, user)
 	ctx.ctxBase.Title = fmt.Sprintf("loltools > %s", gameId)
 	ctx.GameInfo = gameInfo
 
+	ctx.Analysis, err = model.GetGameAnalysis(c, reqCtx, game, gameKey)
+	if err != nil {
+		ctx.ctxBase.Errors = append(ctx.ctxBase.Errors, err)
+	}
+
 	err = RenderTemplate(w, "games/index.html", "base", ctx)
 	if HandleError(c, w, err) {
 		return
@@ -39,6 +48,8 @@ func GameViewHandler(w http.ResponseWriter, r *http.Request, args map[string]str
 
 func LeagueGameViewHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
 	c := appengine.NewContext(r)
+	reqCtx, cancel := model.NewRequestContext(r)
+	defer cancel()
 	leagueId := args["leagueId"]
 	gameId := args["gameId"]
 
@@ -64,6 +75,7 @@ func LeagueGameViewHandler(w http.ResponseWriter, r *http.Request, args map[stri
 		ctxBase
 		League
 		GameInfo *model.GameInfo
+		Analysis *analysis.MatchAnalysis
 		UserTags []*model.UserGameTag
 		Tags     []*model.GameTag
 	}{}
@@ -72,6 +84,11 @@ func LeagueGameViewHandler(w http.ResponseWriter, r *http.Request, args map[stri
 	ctx.League.Fill(league, leagueKey)
 	ctx.GameInfo = gameInfo
 
+	ctx.Analysis, err = model.GetGameAnalysis(c, reqCtx, game, gameKey)
+	if err != nil {
+		ctx.ctxBase.Errors = append(ctx.ctxBase.Errors, err)
+	}
+
 	ctx.UserTags, _, err = model.GetUserGameTags(c, userKey, leagueKey, gameKey)
 	if HandleError(c, w, err) {
 		return
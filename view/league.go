@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"github.com/OwenDurni/loltools/model"
 	"net/http"
+	"sort"
+	"strconv"
+	"time"
 )
 
 type League struct {
@@ -15,6 +18,30 @@ type League struct {
 	Id     string
 	Uri    string
 	Region string
+
+	// The name of the league's model.TeamScoringType.
+	TeamScoring string
+
+	// The league's model.RosterRules, with zero limits left blank.
+	RosterMinSize        string
+	RosterMaxSize        string
+	RosterMaxSubstitutes string
+	RosterLockDate       string
+	RosterLockTime       string
+
+	// The league's model.EligibilityRules, with a zero MinLevel left blank.
+	MinTier          string
+	MaxTier          string
+	MaxAverageTier   string
+	MinLevel         string
+	OneTeamPerLeague bool
+}
+
+// A team that failed its last eligibility check.
+type TeamEligibility struct {
+	Team       Team
+	Checked    string
+	Violations []string
 }
 
 func (l *League) Fill(m *model.League, k *datastore.Key) *League {
@@ -25,6 +52,30 @@ func (l *League) Fill(m *model.League, k *datastore.Key) *League {
 	if m.Region != "" {
 		l.Region = m.Region
 	}
+	l.TeamScoring = m.TeamScoring.String()
+
+	limit := func(n int) string {
+		if n == 0 {
+			return ""
+		}
+		return strconv.Itoa(n)
+	}
+	l.RosterMinSize = limit(m.RosterRules.MinSize)
+	l.RosterMaxSize = limit(m.RosterRules.MaxSize)
+	l.RosterMaxSubstitutes = limit(m.RosterRules.MaxSubstitutes)
+	if !m.RosterRules.LockDate.IsZero() {
+		if location, err := time.LoadLocation("America/Los_Angeles"); err == nil {
+			lock := m.RosterRules.LockDate.In(location)
+			l.RosterLockDate = lock.Format("2006-01-02")
+			l.RosterLockTime = lock.Format("15:04")
+		}
+	}
+
+	l.MinTier = m.EligibilityRules.MinTier
+	l.MaxTier = m.EligibilityRules.MaxTier
+	l.MaxAverageTier = m.EligibilityRules.MaxAverageTier
+	l.MinLevel = limit(m.EligibilityRules.MinLevel)
+	l.OneTeamPerLeague = m.EligibilityRules.OneTeamPerLeague
 	return l
 }
 
@@ -33,6 +84,7 @@ type Team struct {
 	Id   string
 	Uri  string
 
+	Points int
 	Wins   int
 	Losses int
 }
@@ -100,26 +152,94 @@ func LeagueViewHandler(w http.ResponseWriter, r *http.Request, args map[string]s
 		return
 	}
 
-	teams, teamKeys, err := model.LeagueAllTeams(c, userAcls, league, leagueKey)
+	scope, filter, err := seasonFilter(
+		c, userAcls, league, leagueKey, r, model.LeagueUri(leagueKey), true)
 	if HandleError(c, w, err) {
 		return
 	}
 
+	standings, teams, err := model.LeagueStandings(c, userAcls, league, leagueKey, scope)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	matches, matchKeys, err := model.LeagueScheduledMatches(
+		c, userAcls, league, leagueKey, scope)
+	if HandleError(c, w, err) {
+		return
+	}
+	sort.Sort(model.ScheduledMatchesByTime{Matches: matches, Keys: matchKeys})
+
+	results, err := model.LeagueMatchResults(c, userAcls, league, leagueKey, scope)
+	if HandleError(c, w, err) {
+		return
+	}
+	finalResults := make(map[string]int)
+	for _, result := range results {
+		if result.IsFinal {
+			finalResults[result.ScheduledMatch.Encode()]++
+		}
+	}
+
 	// Populate view context.
 	ctx := struct {
 		ctxBase
 		League
-		Teams     []Team
-		GroupAcls []GroupAcl
+		SeasonFilter    *SeasonFilter
+		Teams           []Team
+		GroupAcls       []GroupAcl
+		IneligibleTeams []TeamEligibility
+
+		UnfinishedMatches []Match
+		// Most recent first.
+		FinishedMatches []Match
 	}{}
 	ctx.ctxBase.init(c, user)
 	ctx.ctxBase.Title = fmt.Sprintf("loltools > %s", league.Name)
 
 	ctx.League.Fill(league, leagueKey)
+	ctx.SeasonFilter = filter
 
 	ctx.Teams = make([]Team, len(teams))
 	for i, t := range teams {
-		ctx.Teams[i].Fill(t, teamKeys[i], leagueKey)
+		ctx.Teams[i].Fill(t, standings[i].TeamKey, leagueKey)
+		ctx.Teams[i].Points = standings[i].Points
+		ctx.Teams[i].Wins = standings[i].Wins
+		ctx.Teams[i].Losses = standings[i].Losses
+	}
+
+	for i, match := range matches {
+		var m Match
+		m.Fill(match, matchKeys[i], leagueKey)
+		if finalResults[matchKeys[i].Encode()] < len(match.TeamKeys) {
+			ctx.UnfinishedMatches = append(ctx.UnfinishedMatches, m)
+		} else {
+			ctx.FinishedMatches = append([]Match{m}, ctx.FinishedMatches...)
+		}
+	}
+
+	ineligible, err := model.LeagueIneligibleTeams(c, userAcls, league, leagueKey)
+	if HandleError(c, w, err) {
+		return
+	}
+	if len(ineligible) > 0 {
+		allTeams, allTeamKeys, err := model.LeagueAllTeams(c, userAcls, league, leagueKey)
+		if HandleError(c, w, err) {
+			return
+		}
+		teamsById := make(map[string]*Team)
+		for i, t := range allTeams {
+			teamsById[allTeamKeys[i].Encode()] = new(Team).Fill(t, allTeamKeys[i], leagueKey)
+		}
+		for _, e := range ineligible {
+			if t := teamsById[e.TeamKey.Encode()]; t != nil {
+				ctx.IneligibleTeams = append(ctx.IneligibleTeams, TeamEligibility{
+					Team:       *t,
+					Checked:    fmtTime(e.Checked, "America/Los_Angeles"),
+					Violations: e.Violations,
+				})
+			}
+		}
 	}
 
 	//if *league.Owner == *userKey {
@@ -142,6 +262,61 @@ func LeagueViewHandler(w http.ResponseWriter, r *http.Request, args map[string]s
 	}
 }
 
+func LeagueStandingsHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+	leagueId := args["leagueId"]
+
+	user, userKey, err := model.GetUser(c)
+	if HandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, leagueId)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	scope, filter, err := seasonFilter(
+		c, userAcls, league, leagueKey, r, model.LeagueUri(leagueKey)+"/standings", true)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	standings, teams, err := model.LeagueStandings(c, userAcls, league, leagueKey, scope)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	// Populate view context.
+	type standing struct {
+		Team     Team
+		Standing *model.Standing
+	}
+	ctx := struct {
+		ctxBase
+		League
+		SeasonFilter *SeasonFilter
+		Standings    []standing
+	}{}
+	ctx.ctxBase.init(c, user)
+	ctx.ctxBase.Title = fmt.Sprintf("loltools > %s > Standings", league.Name)
+	ctx.League.Fill(league, leagueKey)
+	ctx.SeasonFilter = filter
+
+	ctx.Standings = make([]standing, len(standings))
+	for i, s := range standings {
+		ctx.Standings[i].Team.Fill(teams[i], s.TeamKey, leagueKey)
+		ctx.Standings[i].Standing = s
+	}
+
+	// Render
+	err = RenderTemplate(w, "leagues/standings.html", "base", ctx)
+	if HandleError(c, w, err) {
+		return
+	}
+}
+
 func ApiLeagueCreateHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
 	c := appengine.NewContext(r)
 	_, leagueKey, err := model.CreateLeague(c, r.FormValue("name"))
@@ -177,6 +352,136 @@ func ApiLeagueAddTeamHandler(w http.ResponseWriter, r *http.Request, args map[st
 	HttpReplyResourceCreated(w, model.LeagueTeamUri(leagueKey, teamKey))
 }
 
+func ApiLeagueSetScoringHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+	leagueId := r.FormValue("league")
+
+	scoringType, err := model.ParseTeamScoringType(r.FormValue("scoring"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	_, userKey, err := model.GetUser(c)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	_, leagueKey, err := model.LeagueById(c, leagueId)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	err = model.LeagueSetTeamScoring(c, userAcls, leagueKey, scoringType)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	HttpReplyOkEmpty(w)
+}
+
+func ApiLeagueSetRosterRulesHandler(
+	w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+	leagueId := r.FormValue("league")
+
+	// Parse the limits. Empty fields are unlimited.
+	rules := new(model.RosterRules)
+	fields := []struct {
+		name  string
+		value *int
+	}{
+		{"min-size", &rules.MinSize},
+		{"max-size", &rules.MaxSize},
+		{"max-substitutes", &rules.MaxSubstitutes},
+	}
+	for _, f := range fields {
+		if r.FormValue(f.name) == "" {
+			continue
+		}
+		var err error
+		*f.value, err = strconv.Atoi(r.FormValue(f.name))
+		if err != nil {
+			ApiHandleError(c, w, errors.New(fmt.Sprintf(
+				"'%s' must be a number: '%s'", f.name, r.FormValue(f.name))))
+			return
+		}
+	}
+	if r.FormValue("lock-date") != "" {
+		tz := r.FormValue("tz")
+		if tz == "" {
+			tz = "America/Los_Angeles"
+		}
+		lockTime := r.FormValue("lock-time")
+		if lockTime == "" {
+			lockTime = "00:00"
+		}
+		var err error
+		rules.LockDate, err = parseDatetime(r.FormValue("lock-date"), lockTime, tz)
+		if ApiHandleError(c, w, err) {
+			return
+		}
+	}
+
+	_, userKey, err := model.GetUser(c)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, leagueId)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	err = model.LeagueSetRosterRules(c, userAcls, league, leagueKey, rules)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	HttpReplyOkEmpty(w)
+}
+
+func ApiLeagueSetEligibilityHandler(
+	w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+	leagueId := r.FormValue("league")
+
+	rules := &model.EligibilityRules{
+		MinTier:          r.FormValue("min-tier"),
+		MaxTier:          r.FormValue("max-tier"),
+		MaxAverageTier:   r.FormValue("max-average-tier"),
+		OneTeamPerLeague: r.FormValue("one-team") == "1",
+	}
+	if r.FormValue("min-level") != "" {
+		var err error
+		rules.MinLevel, err = strconv.Atoi(r.FormValue("min-level"))
+		if err != nil {
+			ApiHandleError(c, w, errors.New(fmt.Sprintf(
+				"'min-level' must be a number: '%s'", r.FormValue("min-level"))))
+			return
+		}
+	}
+
+	_, userKey, err := model.GetUser(c)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, leagueId)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	err = model.LeagueSetEligibilityRules(c, userAcls, league, leagueKey, rules)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	HttpReplyOkEmpty(w)
+}
+
 func ApiLeagueGroupAclGrantHandler(
 	w http.ResponseWriter, r *http.Request, args map[string]string) {
 	c := appengine.NewContext(r)
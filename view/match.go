@@ -6,10 +6,69 @@ import (
 	"errors"
 	"fmt"
 	"github.com/OwenDurni/loltools/model"
+	"github.com/OwenDurni/loltools/model/tags"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
+type Match struct {
+	Id  string
+	Uri string
+
+	Summary     string
+	Description string
+	PrimaryTag  string
+	NumGames    int
+	Map         string
+	Official    string
+	Earliest    string
+	Latest      string
+}
+
+func (m *Match) Fill(
+	match *model.ScheduledMatch, matchKey *datastore.Key, leagueKey *datastore.Key) *Match {
+	m.Id = model.MatchId(matchKey)
+	m.Uri = model.LeagueMatchUri(leagueKey, matchKey)
+	m.Summary = match.Summary
+	m.Description = match.Description
+	m.PrimaryTag = match.PrimaryTag
+	m.NumGames = match.NumGames
+	var mapMode []string
+	if match.MapId > 0 {
+		mapMode = append(mapMode, fmt.Sprintf("map %d", match.MapId))
+	}
+	if match.GameMode != "" {
+		mapMode = append(mapMode, fmt.Sprintf("mode %s", match.GameMode))
+	}
+	m.Map = "any"
+	if len(mapMode) > 0 {
+		m.Map = strings.Join(mapMode, ", ")
+	}
+	m.Official = fmtTime(match.OfficialDatetime, "America/Los_Angeles")
+	m.Earliest = fmtTime(match.DateEarliest, "America/Los_Angeles")
+	m.Latest = fmtTime(match.DateLatest, "America/Los_Angeles")
+	return m
+}
+
+// A game that is, or may be, part of a match.
+type MatchGame struct {
+	Id         string
+	Uri        string
+	Status     string
+	Confidence int
+	Reason     string
+	Reviewed   bool
+}
+
+type MatchReportEvent struct {
+	User     string
+	State    string
+	GamesWon []int
+	Comment  string
+	Time     string
+}
+
 func MatchCreateHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
 	c := appengine.NewContext(r)
 	leagueId := args["leagueId"]
@@ -53,6 +112,140 @@ func MatchCreateHandler(w http.ResponseWriter, r *http.Request, args map[string]
 	}
 }
 
+func MatchViewHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+	leagueId := args["leagueId"]
+	matchId := args["matchId"]
+
+	user, userKey, err := model.GetUser(c)
+	if HandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, leagueId)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	match, matchKey, err := model.ScheduledMatchById(c, userAcls, league, leagueKey, matchId)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	results, err := model.GetMatchResults(c, userAcls, league, leagueKey, match, matchKey)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	report, err := model.GetMatchReport(c, userAcls, league, leagueKey, matchKey)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	events, err := model.GetMatchReportEvents(c, userAcls, league, leagueKey, matchKey)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	resultTags, err := model.GameTagsWithTag(
+		c, leagueKey, tags.AutomaticallyDetectedMatchResultFor(matchKey))
+	if HandleError(c, w, err) {
+		return
+	}
+	reviewTags, err := model.GameTagsWithTag(c, leagueKey, tags.NeedsReviewFor(matchKey))
+	if HandleError(c, w, err) {
+		return
+	}
+
+	// Populate view context.
+	type matchTeam struct {
+		Team
+		Result *model.MatchResult
+	}
+	ctx := struct {
+		ctxBase
+		League
+		Match
+		Teams []matchTeam
+
+		Report *model.MatchReport
+		// Index into Teams of the team that reported the result.
+		ReportingTeam int
+		ReportState   string
+		ReportGames   []string
+		Events        []MatchReportEvent
+
+		Games []MatchGame
+	}{}
+	ctx.ctxBase.init(c, user)
+	ctx.ctxBase.Title = fmt.Sprintf("loltools > %s > %s", league.Name, match.Summary)
+	ctx.League.Fill(league, leagueKey)
+	ctx.Match.Fill(match, matchKey, leagueKey)
+
+	ctx.Teams = make([]matchTeam, len(match.TeamKeys))
+	for i, teamKey := range match.TeamKeys {
+		team, _, err := model.TeamById(c, userAcls, league, leagueKey, model.EncodeKeyShort(teamKey))
+		if HandleError(c, w, err) {
+			return
+		}
+		ctx.Teams[i].Team.Fill(team, teamKey, leagueKey)
+		ctx.Teams[i].Result = results[i]
+	}
+
+	if report != nil {
+		ctx.Report = report
+		ctx.ReportState = report.State.String()
+		for i, teamKey := range match.TeamKeys {
+			if teamKey.Equal(report.ReportingTeam) {
+				ctx.ReportingTeam = i
+			}
+		}
+		for _, gameKey := range report.GameKeys {
+			ctx.ReportGames = append(ctx.ReportGames, gameKey.StringID())
+		}
+	}
+
+	for _, t := range resultTags {
+		ctx.Games = append(ctx.Games, MatchGame{
+			Status: "counted", Confidence: t.Confidence, Reason: t.Reason, Reviewed: t.Reviewed,
+			Id: t.Game.StringID(), Uri: model.LeagueGameUri(leagueKey, t.Game),
+		})
+	}
+	for _, t := range reviewTags {
+		status := "needs review"
+		if t.Reviewed {
+			status = "not counted"
+		}
+		ctx.Games = append(ctx.Games, MatchGame{
+			Status: status, Confidence: t.Confidence, Reason: t.Reason, Reviewed: t.Reviewed,
+			Id: t.Game.StringID(), Uri: model.LeagueGameUri(leagueKey, t.Game),
+		})
+	}
+
+	ctx.Events = make([]MatchReportEvent, len(events))
+	for i, e := range events {
+		ctx.Events[i].State = e.State.String()
+		ctx.Events[i].GamesWon = e.GamesWon
+		ctx.Events[i].Comment = e.Comment
+		ctx.Events[i].Time = fmtTime(e.Time, "America/Los_Angeles")
+		if e.User == nil {
+			continue
+		}
+		if u, err := model.GetUserByKey(c, e.User); err == nil {
+			ctx.Events[i].User = u.Email
+		} else {
+			ctx.Events[i].User = err.Error()
+		}
+	}
+
+	// Render
+	err = RenderTemplate(w, "leagues/matches/view.html", "base", ctx)
+	if HandleError(c, w, err) {
+		return
+	}
+}
+
 func ApiMatchCreateHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
 	c := appengine.NewContext(r)
 
@@ -81,6 +274,26 @@ func ApiMatchCreateHandler(w http.ResponseWriter, r *http.Request, args map[stri
 		return
 	}
 
+	// Zero and empty match any map and mode.
+	var mapId int64
+	if r.FormValue("map") != "" {
+		mapId, err = strconv.ParseInt(r.FormValue("map"), 10, 32)
+		if ApiHandleError(c, w, err) {
+			return
+		}
+	}
+	gameMode := r.FormValue("mode")
+
+	// An empty scoring uses the league's.
+	overrideScoring := r.FormValue("scoring") != ""
+	var scoringType model.TeamScoringType
+	if overrideScoring {
+		scoringType, err = model.ParseTeamScoringType(r.FormValue("scoring"))
+		if ApiHandleError(c, w, err) {
+			return
+		}
+	}
+
 	officialDatetime, err := parseDatetime(r.FormValue("official-date"), r.FormValue("official-time"), tz)
 	if ApiHandleError(c, w, err) {
 		return
@@ -151,9 +364,14 @@ func ApiMatchCreateHandler(w http.ResponseWriter, r *http.Request, args map[stri
 			PrimaryTag:       primaryTag,
 			TeamKeys:         []*datastore.Key{homeTeamKeys[i], awayTeamKeys[i]},
 			NumGames:         int(numGames),
+			MapId:            int(mapId),
+			GameMode:         gameMode,
 			OfficialDatetime: officialDatetime,
 			DateEarliest:     startDatetime,
 			DateLatest:       endDatetime,
+
+			OverrideTeamScoring: overrideScoring,
+			TeamScoring:         scoringType,
 		}
 
 		err = model.CreateScheduledMatch(c, userAcls, league, leagueKey, match)
@@ -164,3 +382,267 @@ func ApiMatchCreateHandler(w http.ResponseWriter, r *http.Request, args map[stri
 
 	HttpReplyOkEmpty(w)
 }
+
+// Parses the games won by the home and away teams from the form values with the given
+// prefix. Returns nil if both are empty.
+func parseGamesWon(r *http.Request, prefix string) ([]int, error) {
+	home := r.FormValue(prefix + "home-wins")
+	away := r.FormValue(prefix + "away-wins")
+	if home == "" && away == "" {
+		return nil, nil
+	}
+	gamesWon := make([]int, 2)
+	for i, value := range []string{home, away} {
+		won, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid number of games won: '%s'", value))
+		}
+		gamesWon[i] = int(won)
+	}
+	return gamesWon, nil
+}
+
+// Looks up the league and match named by the "league" and "match" form values.
+func matchFromForm(c appengine.Context, userAcls *model.RequestorAclCache, r *http.Request) (
+	*model.League, *datastore.Key, *model.ScheduledMatch, *datastore.Key, error) {
+	league, leagueKey, err := model.LeagueById(c, r.FormValue("league"))
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	match, matchKey, err := model.ScheduledMatchById(
+		c, userAcls, league, leagueKey, r.FormValue("match"))
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return league, leagueKey, match, matchKey, nil
+}
+
+func ApiMatchReportHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+
+	gamesWon, err := parseGamesWon(r, "")
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	if gamesWon == nil {
+		ApiHandleError(c, w, errors.New("The games won by each team are required"))
+		return
+	}
+
+	_, userKey, err := model.GetUser(c)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, match, matchKey, err := matchFromForm(c, userAcls, r)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	_, teamKey, err := model.TeamById(c, userAcls, league, leagueKey, r.FormValue("team"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	// Game ids may be separated by commas or whitespace.
+	var gameKeys []*datastore.Key
+	for _, gameId := range strings.Fields(strings.Replace(r.FormValue("games"), ",", " ", -1)) {
+		gameKeys = append(gameKeys, model.KeyForGameId(c, gameId))
+	}
+
+	err = model.ReportMatchResult(
+		c, userAcls, league, leagueKey, match, matchKey, teamKey, gamesWon, gameKeys,
+		r.FormValue("comment"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	HttpReplyOkEmpty(w)
+}
+
+func ApiMatchRespondHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+
+	var confirm bool
+	switch response := r.FormValue("response"); response {
+	case "confirm":
+		confirm = true
+	case "dispute":
+		confirm = false
+	default:
+		ApiHandleError(c, w, errors.New(fmt.Sprintf("Unrecognized response '%s'", response)))
+		return
+	}
+
+	_, userKey, err := model.GetUser(c)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, match, matchKey, err := matchFromForm(c, userAcls, r)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	_, teamKey, err := model.TeamById(c, userAcls, league, leagueKey, r.FormValue("team"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	err = model.RespondToMatchReport(
+		c, userAcls, league, leagueKey, match, matchKey, teamKey, confirm,
+		r.FormValue("comment"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	if confirm {
+		err = tagReportedGames(
+			c, league, leagueKey, matchKey, "listed in the report confirmed by the opposing team")
+		if ApiHandleError(c, w, err) {
+			return
+		}
+	}
+
+	HttpReplyOkEmpty(w)
+}
+
+func ApiMatchResolveHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+
+	var accept bool
+	switch resolution := r.FormValue("resolution"); resolution {
+	case "accept":
+		accept = true
+	case "reject":
+		accept = false
+	default:
+		ApiHandleError(c, w, errors.New(fmt.Sprintf("Unrecognized resolution '%s'", resolution)))
+		return
+	}
+
+	// Optionally corrects the reported score.
+	gamesWon, err := parseGamesWon(r, "")
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	_, userKey, err := model.GetUser(c)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, match, matchKey, err := matchFromForm(c, userAcls, r)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	err = model.ResolveMatchReport(
+		c, userAcls, league, leagueKey, match, matchKey, accept, gamesWon,
+		r.FormValue("comment"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	if accept {
+		err = tagReportedGames(
+			c, league, leagueKey, matchKey, "listed in the report accepted by a league editor")
+		if ApiHandleError(c, w, err) {
+			return
+		}
+	}
+
+	HttpReplyOkEmpty(w)
+}
+
+// Tags the games listed in the final report of a match as the games of the match. Both
+// teams or a league editor agreed on them, so they count as reviewed and automatic
+// detection leaves them alone.
+func tagReportedGames(
+	c appengine.Context,
+	league *model.League,
+	leagueKey *datastore.Key,
+	matchKey *datastore.Key,
+	reason string) error {
+	report, err := model.GetMatchReport(c, nil, league, leagueKey, matchKey)
+	if err != nil || report == nil {
+		return err
+	}
+	resultTag := tags.AutomaticallyDetectedMatchResultFor(matchKey)
+	reviewTag := tags.NeedsReviewFor(matchKey)
+	for _, gameKey := range report.GameKeys {
+		err := model.SetGameTag(c, nil, league, leagueKey, &model.GameTag{
+			Game:       gameKey,
+			Tag:        resultTag,
+			Reason:     reason,
+			Confidence: 100,
+			Reviewed:   true,
+		})
+		if err != nil {
+			return err
+		}
+		if err := model.DelGameTag(c, nil, leagueKey, gameKey, reviewTag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Accepts or rejects a game as part of a match on behalf of a league editor. Reviewed
+// games are no longer changed by automatic detection.
+func ApiMatchReviewGameHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+
+	var accept bool
+	switch decision := r.FormValue("decision"); decision {
+	case "accept":
+		accept = true
+	case "reject":
+		accept = false
+	default:
+		ApiHandleError(c, w, errors.New(fmt.Sprintf("Unrecognized decision '%s'", decision)))
+		return
+	}
+
+	_, userKey, err := model.GetUser(c)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, _, matchKey, err := matchFromForm(c, userAcls, r)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	gameKey := model.KeyForGameId(c, r.FormValue("game"))
+
+	resultTag := tags.AutomaticallyDetectedMatchResultFor(matchKey)
+	reviewTag := tags.NeedsReviewFor(matchKey)
+	gameTag := &model.GameTag{
+		Game:     gameKey,
+		Reviewed: true,
+	}
+	var delTag string
+	if accept {
+		gameTag.Tag, delTag = resultTag, reviewTag
+		gameTag.Reason = "accepted by a league editor"
+		gameTag.Confidence = 100
+	} else {
+		gameTag.Tag, delTag = reviewTag, resultTag
+		gameTag.Reason = "rejected by a league editor"
+		gameTag.Confidence = 0
+	}
+
+	err = model.SetGameTag(c, userAcls, league, leagueKey, gameTag)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	// SetGameTag checked that the user may edit the league.
+	err = model.DelGameTag(c, nil, leagueKey, gameKey, delTag)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	HttpReplyOkEmpty(w)
+}
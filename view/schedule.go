@@ -0,0 +1,221 @@
+package view
+
+import (
+	"appengine"
+	"errors"
+	"fmt"
+	"github.com/OwenDurni/loltools/model"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type ScheduleRound struct {
+	Tag     string
+	Start   string
+	End     string
+	Matches []ScheduleMatch
+	Bye     *Team
+}
+
+type ScheduleMatch struct {
+	Home Team
+	Away Team
+}
+
+// Parses a whole number of days from a form value.
+func parseDays(r *http.Request, name string) (time.Duration, error) {
+	days, err := strconv.ParseInt(r.FormValue(name), 10, 32)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("'%s' must be a number of days", name))
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// Parses the options of the schedule generator form.
+func parseRoundRobinOptions(r *http.Request) (*model.RoundRobinOptions, error) {
+	var err error
+	opts := &model.RoundRobinOptions{
+		Summary:     r.FormValue("summary"),
+		Description: r.FormValue("description"),
+		GameMode:    r.FormValue("mode"),
+	}
+
+	tz := r.FormValue("tz")
+	if tz == "" {
+		tz = "America/Los_Angeles"
+	}
+	opts.Start, err = parseDatetime(r.FormValue("start-date"), r.FormValue("start-time"), tz)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Cadence, err = parseDays(r, "cadence-days"); err != nil {
+		return nil, err
+	}
+	if opts.Window, err = parseDays(r, "window-days"); err != nil {
+		return nil, err
+	}
+
+	switch rounds := r.FormValue("rounds"); rounds {
+	case "single":
+		opts.Double = false
+	case "double":
+		opts.Double = true
+	default:
+		return nil, errors.New(fmt.Sprintf("Unrecognized rounds '%s'", rounds))
+	}
+
+	numGames, err := strconv.ParseInt(r.FormValue("num-games"), 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	opts.NumGames = int(numGames)
+
+	if r.FormValue("map") != "" {
+		mapId, err := strconv.ParseInt(r.FormValue("map"), 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		opts.MapId = int(mapId)
+	}
+
+	// An empty scoring uses the league's.
+	if r.FormValue("scoring") != "" {
+		opts.OverrideTeamScoring = true
+		opts.TeamScoring, err = model.ParseTeamScoringType(r.FormValue("scoring"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return opts, nil
+}
+
+// Shows the schedule generator form and, once submitted, a preview of the schedule to
+// confirm.
+func MatchGenerateHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+	leagueId := args["leagueId"]
+
+	user, userKey, err := model.GetUser(c)
+	if HandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, leagueId)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	ctx := struct {
+		ctxBase
+		League
+
+		// The submitted form, or the defaults.
+		Form url.Values
+
+		// The divisions of the current season a schedule may be limited to.
+		Divisions []Division
+
+		Preview bool
+		Rounds  []ScheduleRound
+	}{}
+	ctx.ctxBase.init(c, user)
+	ctx.ctxBase.Title = fmt.Sprintf("loltools > %s > Generate a Schedule", league.Name)
+	ctx.League.Fill(league, leagueKey)
+
+	ctx.Divisions, err = currentDivisions(c, userAcls, league, leagueKey)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	r.ParseForm()
+	ctx.Form = r.Form
+	if r.FormValue("preview") == "" {
+		ctx.Form = url.Values{
+			"start-time":   {"19:00"},
+			"cadence-days": {"7"},
+			"window-days":  {"7"},
+			"rounds":       {"single"},
+			"num-games":    {"1"},
+		}
+	} else if opts, err := parseRoundRobinOptions(r); err != nil {
+		ctx.ctxBase.Errors = append(ctx.ctxBase.Errors, err)
+	} else if scope, err := model.LeagueScopeById(
+		c, userAcls, league, leagueKey, "", r.FormValue("division")); err != nil {
+		ctx.ctxBase.Errors = append(ctx.ctxBase.Errors, err)
+	} else {
+		rounds, teams, teamKeys, err := model.LeagueRoundRobin(
+			c, userAcls, league, leagueKey, scope, opts)
+		if err != nil {
+			ctx.ctxBase.Errors = append(ctx.ctxBase.Errors, err)
+		} else {
+			ctx.Preview = true
+			teamsById := make(map[string]*Team)
+			for i, t := range teams {
+				teamsById[teamKeys[i].Encode()] = new(Team).Fill(t, teamKeys[i], leagueKey)
+			}
+			ctx.Rounds = make([]ScheduleRound, len(rounds))
+			for i, round := range rounds {
+				ctx.Rounds[i].Tag = round.Tag
+				ctx.Rounds[i].Matches = make([]ScheduleMatch, len(round.Matches))
+				for j, m := range round.Matches {
+					ctx.Rounds[i].Start = fmtTime(m.DateEarliest, "America/Los_Angeles")
+					ctx.Rounds[i].End = fmtTime(m.DateLatest, "America/Los_Angeles")
+					ctx.Rounds[i].Matches[j].Home = *teamsById[m.HomeTeam().Encode()]
+					ctx.Rounds[i].Matches[j].Away = *teamsById[m.AwayTeam().Encode()]
+				}
+				if round.Bye != nil {
+					ctx.Rounds[i].Bye = teamsById[round.Bye.Encode()]
+				}
+			}
+		}
+	}
+
+	// Render
+	err = RenderTemplate(w, "leagues/matches/generate.html", "base", ctx)
+	if HandleError(c, w, err) {
+		return
+	}
+}
+
+// Creates every match of a round-robin schedule previewed with MatchGenerateHandler, in
+// the league's current season.
+func ApiMatchGenerateHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+
+	opts, err := parseRoundRobinOptions(r)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	_, userKey, err := model.GetUser(c)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, r.FormValue("league"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	scope, err := model.LeagueScopeById(
+		c, userAcls, league, leagueKey, "", r.FormValue("division"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	rounds, _, _, err := model.LeagueRoundRobin(c, userAcls, league, leagueKey, scope, opts)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	err = model.CreateSchedule(c, userAcls, league, leagueKey, rounds)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	HttpReplyResourceCreated(w, model.LeagueUri(leagueKey))
+}
@@ -0,0 +1,328 @@
+package view
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"fmt"
+	"github.com/OwenDurni/loltools/model"
+	"net/http"
+	"sort"
+)
+
+type Season struct {
+	Name    string
+	Id      string
+	Start   string
+	End     string
+	Current bool
+
+	// Set while teams, matches and rosters are still being carried into the season.
+	Migrating bool
+}
+
+func (s *Season) Fill(
+	season *model.Season, seasonKey *datastore.Key, league *model.League) *Season {
+	s.Name = season.Name
+	s.Id = model.SeasonId(seasonKey)
+	s.Start = fmtTime(season.Start, "America/Los_Angeles")
+	if !season.End.IsZero() {
+		s.End = fmtTime(season.End, "America/Los_Angeles")
+	}
+	s.Current = league.CurrentSeason != nil && league.CurrentSeason.Equal(seasonKey)
+	s.Migrating = season.Migrating
+	return s
+}
+
+type Division struct {
+	Name string
+	Id   string
+}
+
+func (d *Division) Fill(division *model.Division, divisionKey *datastore.Key) *Division {
+	d.Name = division.Name
+	d.Id = model.DivisionId(divisionKey)
+	return d
+}
+
+// Selects the season, and optionally the division, a page shows.
+type SeasonFilter struct {
+	Action string
+
+	// The selected season's id, or "all" for the whole league.
+	Season string
+	// The selected division's id, or "" for the whole season.
+	Division string
+
+	Seasons []Season
+	// The divisions of the selected season. Nil if the filter has no division select.
+	Divisions []Division
+}
+
+// Returns the scope selected by the "season" and "division" parameters of r, defaulting
+// to the league's current season, and a filter to change it that submits to action.
+// Divisions are only selectable if withDivisions is set.
+func seasonFilter(
+	c appengine.Context,
+	userAcls *model.RequestorAclCache,
+	league *model.League,
+	leagueKey *datastore.Key,
+	r *http.Request,
+	action string,
+	withDivisions bool) (*model.LeagueScope, *SeasonFilter, error) {
+	divisionId := ""
+	if withDivisions {
+		divisionId = r.FormValue("division")
+	}
+	scope, err := model.LeagueScopeById(
+		c, userAcls, league, leagueKey, r.FormValue("season"), divisionId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filter := &SeasonFilter{Action: action, Season: "all", Division: divisionId}
+	seasons, seasonKeys, err := model.LeagueSeasons(c, userAcls, league, leagueKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	filter.Seasons = make([]Season, len(seasons))
+	for i, s := range seasons {
+		filter.Seasons[i].Fill(s, seasonKeys[i], league)
+	}
+	if scope == nil {
+		return scope, filter, nil
+	}
+
+	filter.Season = model.SeasonId(scope.SeasonKey)
+	if withDivisions {
+		divisions, divisionKeys, _, err := model.SeasonDivisionsAndTeams(
+			c, userAcls, league, leagueKey, scope.SeasonKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		filter.Divisions = make([]Division, len(divisions))
+		for i, d := range divisions {
+			filter.Divisions[i].Fill(d, divisionKeys[i])
+		}
+	}
+	return scope, filter, nil
+}
+
+// Returns the divisions of a league's current season, for forms that create matches
+// within a division.
+func currentDivisions(
+	c appengine.Context,
+	userAcls *model.RequestorAclCache,
+	league *model.League,
+	leagueKey *datastore.Key) ([]Division, error) {
+	if league.CurrentSeason == nil {
+		return nil, nil
+	}
+	divisions, divisionKeys, _, err := model.SeasonDivisionsAndTeams(
+		c, userAcls, league, leagueKey, league.CurrentSeason)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]Division, len(divisions))
+	for i, d := range divisions {
+		views[i].Fill(d, divisionKeys[i])
+	}
+	return views, nil
+}
+
+// Shows a league's seasons, and the divisions and teams of the selected one.
+func LeagueSeasonsHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+	leagueId := args["leagueId"]
+
+	user, userKey, err := model.GetUser(c)
+	if HandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, leagueId)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	scope, filter, err := seasonFilter(
+		c, userAcls, league, leagueKey, r, model.LeagueUri(leagueKey)+"/seasons", false)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	teams, teamKeys, err := model.LeagueAllTeams(c, userAcls, league, leagueKey)
+	if HandleError(c, w, err) {
+		return
+	}
+	sort.Sort(model.TeamsByName{Teams: teams, Keys: teamKeys})
+
+	// Populate view context.
+	type seasonTeam struct {
+		Team
+		InSeason bool
+		Division string
+	}
+	ctx := struct {
+		ctxBase
+		League
+		Filter    *SeasonFilter
+		Season    *Season
+		Divisions []Division
+		Teams     []seasonTeam
+	}{}
+	ctx.ctxBase.init(c, user)
+	ctx.ctxBase.Title = fmt.Sprintf("loltools > %s > Seasons", league.Name)
+	ctx.League.Fill(league, leagueKey)
+	ctx.Filter = filter
+
+	if scope != nil {
+		ctx.Season = new(Season).Fill(scope.Season, scope.SeasonKey, league)
+		divisions, divisionKeys, seasonTeams, err := model.SeasonDivisionsAndTeams(
+			c, userAcls, league, leagueKey, scope.SeasonKey)
+		if HandleError(c, w, err) {
+			return
+		}
+		ctx.Divisions = make([]Division, len(divisions))
+		for i, d := range divisions {
+			ctx.Divisions[i].Fill(d, divisionKeys[i])
+		}
+		divisionOf := make(map[string]string)
+		for _, t := range seasonTeams {
+			divisionOf[t.TeamKey.Encode()] = ""
+			if t.Division != nil {
+				divisionOf[t.TeamKey.Encode()] = model.DivisionId(t.Division)
+			}
+		}
+		ctx.Teams = make([]seasonTeam, len(teams))
+		for i, t := range teams {
+			ctx.Teams[i].Fill(t, teamKeys[i], leagueKey)
+			ctx.Teams[i].Division, ctx.Teams[i].InSeason = divisionOf[teamKeys[i].Encode()]
+		}
+	}
+
+	// Render
+	err = RenderTemplate(w, "leagues/seasons.html", "base", ctx)
+	if HandleError(c, w, err) {
+		return
+	}
+}
+
+// Starts a league's next season, rolling the current one over.
+func ApiSeasonStartHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+
+	tz := r.FormValue("tz")
+	if tz == "" {
+		tz = "America/Los_Angeles"
+	}
+	season := &model.Season{Name: r.FormValue("name")}
+	var err error
+	season.Start, err = parseDatetime(r.FormValue("start-date"), "00:00", tz)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	if r.FormValue("end-date") != "" {
+		season.End, err = parseDatetime(r.FormValue("end-date"), "00:00", tz)
+		if ApiHandleError(c, w, err) {
+			return
+		}
+		// The end date is the season's last day.
+		season.End = season.End.AddDate(0, 0, 1)
+	}
+
+	_, userKey, err := model.GetUser(c)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, r.FormValue("league"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	_, err = model.StartSeason(c, userAcls, league, leagueKey, season)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	HttpReplyResourceCreated(w, model.LeagueUri(leagueKey)+"/seasons")
+}
+
+func ApiSeasonCreateDivisionHandler(
+	w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+
+	_, userKey, err := model.GetUser(c)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, r.FormValue("league"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	seasonKey, err := model.DecodeKeyShort(c, "Season", r.FormValue("season"), leagueKey)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	_, err = model.CreateDivision(
+		c, userAcls, league, leagueKey, seasonKey, r.FormValue("name"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	HttpReplyOkEmpty(w)
+}
+
+// Adds a team to a season, moves it between the season's divisions or, if "division" is
+// "none", removes it from the season.
+func ApiSeasonSetTeamHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+
+	_, userKey, err := model.GetUser(c)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, r.FormValue("league"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	seasonKey, err := model.DecodeKeyShort(c, "Season", r.FormValue("season"), leagueKey)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	_, teamKey, err := model.TeamById(c, userAcls, league, leagueKey, r.FormValue("team"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	var divisionKey *datastore.Key
+	remove := false
+	switch divisionId := r.FormValue("division"); divisionId {
+	case "none":
+		remove = true
+	case "":
+	default:
+		divisionKey, err = model.DecodeKeyShort(c, "Division", divisionId, leagueKey)
+		if ApiHandleError(c, w, err) {
+			return
+		}
+	}
+
+	err = model.SetSeasonTeam(
+		c, userAcls, league, leagueKey, seasonKey, teamKey, divisionKey, remove)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	HttpReplyOkEmpty(w)
+}
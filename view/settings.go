@@ -37,6 +37,8 @@ func SettingsIndexHandler(
 func ApiUserAddSummoner(
 	w http.ResponseWriter, r *http.Request, args map[string]string) {
 	c := appengine.NewContext(r)
+	reqCtx, cancel := model.NewRequestContext(r)
+	defer cancel()
 	region := r.FormValue("region")
 	summoner := r.FormValue("summoner")
 
@@ -45,7 +47,7 @@ func ApiUserAddSummoner(
 		return
 	}
 
-	err = model.AddUnverifiedSummoner(c, userKey, region, summoner)
+	err = model.AddUnverifiedSummoner(c, reqCtx, userKey, region, summoner)
 	if ApiHandleError(c, w, err) {
 		return
 	}
@@ -56,6 +58,8 @@ func ApiUserAddSummoner(
 func ApiUserVerifySummoner(
 	w http.ResponseWriter, r *http.Request, args map[string]string) {
 	c := appengine.NewContext(r)
+	reqCtx, cancel := model.NewRequestContext(r)
+	defer cancel()
 	region := r.FormValue("region")
 	summonerId, err := strconv.ParseInt(r.FormValue("summonerid"), 10, 64)
 	if ApiHandleError(c, w, err) {
@@ -67,12 +71,12 @@ func ApiUserVerifySummoner(
 		return
 	}
 
-	player, playerKey, err := model.GetOrCreatePlayerByRiotId(c, region, summonerId)
+	player, playerKey, err := model.GetOrCreatePlayerByRiotId(c, reqCtx, region, summonerId)
 	if ApiHandleError(c, w, err) {
 		return
 	}
 
-	err = model.VerifySummoner(c, userKey, playerKey, player)
+	err = model.VerifySummoner(c, reqCtx, userKey, playerKey, player)
 	if ApiHandleError(c, w, err) {
 		return
 	}
@@ -83,6 +87,8 @@ func ApiUserVerifySummoner(
 func ApiUserSetPrimarySummoner(
 	w http.ResponseWriter, r *http.Request, args map[string]string) {
 	c := appengine.NewContext(r)
+	reqCtx, cancel := model.NewRequestContext(r)
+	defer cancel()
 	region := r.FormValue("region")
 	summonerId, err := strconv.ParseInt(r.FormValue("summonerid"), 10, 64)
 	if ApiHandleError(c, w, err) {
@@ -94,7 +100,7 @@ func ApiUserSetPrimarySummoner(
 		return
 	}
 
-	player, playerKey, err := model.GetOrCreatePlayerByRiotId(c, region, summonerId)
+	player, playerKey, err := model.GetOrCreatePlayerByRiotId(c, reqCtx, region, summonerId)
 	if ApiHandleError(c, w, err) {
 		return
 	}
@@ -0,0 +1,282 @@
+package view
+
+import (
+	"appengine"
+	"appengine/datastore"
+	"errors"
+	"fmt"
+	"github.com/OwenDurni/loltools/model"
+	"net/http"
+	"strconv"
+)
+
+type Swiss struct {
+	Name      string
+	Id        string
+	Uri       string
+	Teams     int
+	Round     int
+	NumRounds int
+}
+
+func (s *Swiss) Fill(
+	swiss *model.SwissTournament, swissKey *datastore.Key, leagueKey *datastore.Key) *Swiss {
+	s.Name = swiss.Name
+	s.Id = model.SwissId(swissKey)
+	s.Uri = model.LeagueSwissUri(leagueKey, swissKey)
+	s.Teams = len(swiss.Teams)
+	s.Round = swiss.Round
+	s.NumRounds = swiss.NumRounds
+	return s
+}
+
+type SwissRecord struct {
+	Team
+	Rank                  int
+	Points                int
+	Wins                  int
+	Losses                int
+	Ties                  int
+	Byes                  int
+	Buchholz              int
+	OpponentWinPercentage float64
+}
+
+type SwissPairing struct {
+	Home     Team
+	Away     *Team
+	MatchUri string
+}
+
+type SwissRound struct {
+	Round    int
+	Pairings []SwissPairing
+}
+
+func LeagueSwissHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+	leagueId := args["leagueId"]
+
+	user, userKey, err := model.GetUser(c)
+	if HandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, leagueId)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	tournaments, swissKeys, err := model.LeagueSwissTournaments(c, userAcls, league, leagueKey)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	divisions, err := currentDivisions(c, userAcls, league, leagueKey)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	// Populate view context.
+	ctx := struct {
+		ctxBase
+		League
+		Tournaments []Swiss
+		Divisions   []Division
+	}{}
+	ctx.ctxBase.init(c, user)
+	ctx.ctxBase.Title = fmt.Sprintf("loltools > %s > Swiss", league.Name)
+	ctx.League.Fill(league, leagueKey)
+	ctx.Divisions = divisions
+
+	ctx.Tournaments = make([]Swiss, len(tournaments))
+	for i, s := range tournaments {
+		ctx.Tournaments[i].Fill(s, swissKeys[i], leagueKey)
+	}
+
+	// Render
+	err = RenderTemplate(w, "leagues/swiss/index.html", "base", ctx)
+	if HandleError(c, w, err) {
+		return
+	}
+}
+
+func SwissViewHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+	leagueId := args["leagueId"]
+	swissId := args["swissId"]
+
+	user, userKey, err := model.GetUser(c)
+	if HandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, leagueId)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	swiss, swissKey, records, pairings, err := model.SwissById(
+		c, userAcls, league, leagueKey, swissId)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	teams, teamKeys, err := model.LeagueAllTeams(c, userAcls, league, leagueKey)
+	if HandleError(c, w, err) {
+		return
+	}
+	teamsById := make(map[string]*Team)
+	for i, t := range teams {
+		teamsById[teamKeys[i].Encode()] = new(Team).Fill(t, teamKeys[i], leagueKey)
+	}
+	team := func(teamKey *datastore.Key) *Team {
+		if t := teamsById[teamKey.Encode()]; t != nil {
+			return t
+		}
+		return new(Team)
+	}
+
+	// Populate view context.
+	ctx := struct {
+		ctxBase
+		League
+		Swiss
+		Records []SwissRecord
+		Rounds  []SwissRound
+	}{}
+	ctx.ctxBase.init(c, user)
+	ctx.ctxBase.Title = fmt.Sprintf("loltools > %s > %s", league.Name, swiss.Name)
+	ctx.League.Fill(league, leagueKey)
+	ctx.Swiss.Fill(swiss, swissKey, leagueKey)
+
+	ctx.Records = make([]SwissRecord, len(records))
+	for i, rec := range records {
+		ctx.Records[i] = SwissRecord{
+			Team:                  *team(rec.TeamKey),
+			Rank:                  rec.Rank,
+			Points:                rec.Points,
+			Wins:                  rec.Wins,
+			Losses:                rec.Losses,
+			Ties:                  rec.Ties,
+			Byes:                  rec.Byes,
+			Buchholz:              rec.Buchholz,
+			OpponentWinPercentage: rec.OpponentWinPercentage,
+		}
+	}
+
+	// Pairings are ordered by round.
+	for _, p := range pairings {
+		if len(ctx.Rounds) == 0 || ctx.Rounds[len(ctx.Rounds)-1].Round != p.Round {
+			ctx.Rounds = append(ctx.Rounds, SwissRound{Round: p.Round})
+		}
+		round := &ctx.Rounds[len(ctx.Rounds)-1]
+		pairing := SwissPairing{Home: *team(p.HomeTeam)}
+		if p.AwayTeam != nil {
+			pairing.Away = team(p.AwayTeam)
+			pairing.MatchUri = model.LeagueMatchUri(leagueKey, p.ScheduledMatch)
+		}
+		round.Pairings = append(round.Pairings, pairing)
+	}
+
+	// Render
+	err = RenderTemplate(w, "leagues/swiss/view.html", "base", ctx)
+	if HandleError(c, w, err) {
+		return
+	}
+}
+
+func ApiSwissCreateHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+
+	// Parse the numeric fields. Empty fields are zero.
+	var numTeams, numRounds, numGames, mapId, windowDays int64
+	fields := []struct {
+		name  string
+		value *int64
+	}{
+		{"teams", &numTeams},
+		{"rounds", &numRounds},
+		{"num-games", &numGames},
+		{"map", &mapId},
+		{"window-days", &windowDays},
+	}
+	for _, f := range fields {
+		if r.FormValue(f.name) == "" {
+			continue
+		}
+		var err error
+		*f.value, err = strconv.ParseInt(r.FormValue(f.name), 10, 32)
+		if err != nil || *f.value < 0 {
+			ApiHandleError(c, w, errors.New(fmt.Sprintf(
+				"'%s' must be a non-negative number: '%s'", f.name, r.FormValue(f.name))))
+			return
+		}
+	}
+
+	_, userKey, err := model.GetUser(c)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, r.FormValue("league"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	swiss := &model.SwissTournament{
+		Name:       r.FormValue("name"),
+		NumRounds:  int(numRounds),
+		Summary:    r.FormValue("summary"),
+		NumGames:   int(numGames),
+		MapId:      int(mapId),
+		GameMode:   r.FormValue("mode"),
+		WindowDays: int(windowDays),
+	}
+	scope, err := model.LeagueScopeById(
+		c, userAcls, league, leagueKey, "", r.FormValue("division"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	swissKey, err := model.CreateSwissTournament(
+		c, userAcls, league, leagueKey, scope, swiss, int(numTeams))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	HttpReplyResourceCreated(w, model.LeagueSwissUri(leagueKey, swissKey))
+}
+
+// Creates the next round of a tournament. Rounds are normally created as soon as the
+// previous round's results are final; this retries if that failed.
+func ApiSwissNextRoundHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+
+	_, userKey, err := model.GetUser(c)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, r.FormValue("league"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	swissKey, err := model.DecodeKeyShort(
+		c, "SwissTournament", r.FormValue("swiss"), leagueKey)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	err = model.StartNextSwissRound(c, userAcls, league, leagueKey, swissKey)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	HttpReplyOkEmpty(w)
+}
@@ -2,6 +2,7 @@ package view
 
 import (
 	"appengine"
+	"appengine/datastore"
 	"fmt"
 	"github.com/OwenDurni/loltools/model"
 	"net/http"
@@ -15,6 +16,28 @@ type PlayerInfo struct {
 	Summoner string
 	Wins     int
 	Losses   int
+
+	// The player's place on the roster. Only populated on team pages.
+	Role       string
+	Substitute bool
+	Captain    bool
+	Joined     string
+
+	// Highest champion mastery first. Only populated on team pages.
+	TopChampions []model.ChampionMastery
+}
+
+// A change to a team's roster.
+type RosterTransaction struct {
+	Time       string
+	Type       string
+	Summoner   string
+	PlayerUri  string
+	Role       string
+	Substitute bool
+	Captain    bool
+	User       string
+	Reason     string
 }
 
 func (p *PlayerInfo) Fill(m *model.Player) {
@@ -25,8 +48,11 @@ func (p *PlayerInfo) Fill(m *model.Player) {
 
 func TeamViewHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
 	c := appengine.NewContext(r)
+	reqCtx, cancel := model.NewRequestContext(r)
+	defer cancel()
 	leagueId := args["leagueId"]
 	teamId := args["teamId"]
+	primaryTag := r.FormValue("tag")
 
 	user, userKey, err := model.GetUser(c)
 	if HandleError(c, w, err) {
@@ -44,12 +70,31 @@ func TeamViewHandler(w http.ResponseWriter, r *http.Request, args map[string]str
 		return
 	}
 
-	players, _, err := model.TeamAllPlayers(
-		c, userAcls, league, leagueKey, teamKey, model.KeysAndEntities)
+	scope, filter, err := seasonFilter(
+		c, userAcls, league, leagueKey, r, model.LeagueTeamUri(leagueKey, teamKey), false)
+	if HandleError(c, w, err) {
+		return
+	}
+
+	players, playerKeys, err := model.TeamSeasonPlayers(
+		c, userAcls, league, leagueKey, teamKey, scope, model.KeysAndEntities)
 	if HandleError(c, w, err) {
 		return
 	}
 
+	roster, err := model.TeamRoster(c, userAcls, league, leagueKey, teamKey, scope)
+	if HandleError(c, w, err) {
+		return
+	}
+	memberships := make(map[string]*model.TeamMembership)
+	for _, m := range roster {
+		memberships[m.PlayerKey.Encode()] = m
+	}
+	membershipOf := make(map[*model.Player]*model.TeamMembership)
+	for i, p := range players {
+		membershipOf[p] = memberships[playerKeys[i].Encode()]
+	}
+
 	playerCache := model.NewPlayerCache(c, league.Region)
 	for _, p := range players {
 		playerCache.Add(p)
@@ -59,27 +104,126 @@ func TeamViewHandler(w http.ResponseWriter, r *http.Request, args map[string]str
 	sort.Sort(model.PlayersBySummoner(players))
 
 	// Get recent match history.
-	gameInfos, errors := model.TeamRecentGameInfo(c, userAcls, 5, playerCache, league, leagueKey, teamKey)
+	gameInfos, errors := model.TeamRecentGameInfo(
+		c, userAcls, 5, playerCache, league, leagueKey, teamKey, scope)
+
+	liveGame, err := model.TeamCurrentGame(c, reqCtx, userAcls, league, leagueKey, teamKey)
+	if err != nil {
+		errors = append(errors, err)
+	}
+
+	eligibility, err := model.GetTeamEligibility(c, userAcls, league, leagueKey, teamKey)
+	if err != nil {
+		errors = append(errors, err)
+	}
+
+	transactions, err := model.TeamRosterTransactions(
+		c, userAcls, league, leagueKey, teamKey, scope)
+	if err != nil {
+		errors = append(errors, err)
+	}
+
+	// Get draft statistics from the team's league games.
+	var draft *model.DraftReport
+	draftGameKeys, primaryTags, err := leagueMatchGameKeys(
+		c, userAcls, league, leagueKey, teamKey, primaryTag)
+	if err == nil {
+		draft, err = model.GetDraftReport(
+			c, userAcls, league, leagueKey, []*datastore.Key{teamKey}, draftGameKeys)
+	}
+	if err != nil {
+		errors = append(errors, err)
+	}
 
 	// Populate view context.
 	ctx := struct {
 		ctxBase
 		League
 		Team
-		RecentGames []*model.GameInfo
-		Players     []*PlayerInfo
+		SeasonFilter      *SeasonFilter
+		RecentGames       []*model.GameInfo
+		Players           []*PlayerInfo
+		LiveGame          *model.TeamLiveGame
+		DraftFilter       DraftFilter
+		Draft             *model.TeamDraftReport
+		DraftMissingGames int
+		Eligibility       *TeamEligibility
+		RosterHistory     []RosterTransaction
 	}{}
 	ctx.ctxBase.init(c, user)
 	ctx.ctxBase.Title = fmt.Sprintf("loltools > %s > %s", league.Name, team.Name)
 	ctx.ctxBase.Errors = errors
 	ctx.League.Fill(league, leagueKey)
 	ctx.Team.Fill(team, teamKey, leagueKey)
+	ctx.SeasonFilter = filter
 	ctx.RecentGames = gameInfos
+	ctx.LiveGame = liveGame
+	ctx.DraftFilter = DraftFilter{
+		Action: model.LeagueTeamUri(leagueKey, teamKey),
+		Tag:    primaryTag,
+		Tags:   primaryTags,
+	}
+	if eligibility != nil && len(eligibility.Violations) > 0 {
+		ctx.Eligibility = &TeamEligibility{
+			Team:       ctx.Team,
+			Checked:    fmtTime(eligibility.Checked, "America/Los_Angeles"),
+			Violations: eligibility.Violations,
+		}
+	}
+	ctx.RosterHistory = make([]RosterTransaction, len(transactions))
+	for i, t := range transactions {
+		rt := &ctx.RosterHistory[i]
+		rt.Time = fmtTime(t.Time, "America/Los_Angeles")
+		rt.Type = t.Type.String()
+		rt.Role = t.Role.String()
+		rt.Substitute = t.Substitute
+		rt.Captain = t.Captain
+		rt.Reason = t.Reason
+		_, riotId, err := model.SplitPlayerKey(t.PlayerKey)
+		if err == nil {
+			var p *model.Player
+			if p, err = playerCache.ById(riotId); err == nil {
+				rt.Summoner = p.Summoner
+				rt.PlayerUri = p.Uri()
+			}
+		}
+		if err != nil {
+			rt.Summoner = err.Error()
+		}
+		if t.User == nil {
+			continue
+		}
+		if u, err := model.GetUserByKey(c, t.User); err == nil {
+			rt.User = u.Email
+		} else {
+			rt.User = err.Error()
+		}
+	}
+	if draft != nil {
+		ctx.Draft = draft.Teams[0]
+		ctx.DraftMissingGames = draft.MissingGames
+	}
 
 	ctx.Players = make([]*PlayerInfo, len(players))
 	for i, p := range players {
 		ctx.Players[i] = new(PlayerInfo)
 		ctx.Players[i].Fill(p)
+		if m := membershipOf[p]; m != nil {
+			ctx.Players[i].Role = m.Role.String()
+			ctx.Players[i].Substitute = m.Substitute
+			ctx.Players[i].Captain = m.Captain
+			if !m.Joined.IsZero() {
+				ctx.Players[i].Joined = fmtTime(m.Joined, "America/Los_Angeles")
+			}
+		}
+
+		topChampions, err := model.GetPlayerTopChampions(c, reqCtx, p)
+		if err != nil {
+			ctx.ctxBase.Errors = append(ctx.ctxBase.Errors, err)
+		}
+		if topChampions != nil {
+			ctx.Players[i].TopChampions = topChampions.Champions
+		}
 	}
 
 	// Render
@@ -110,25 +254,34 @@ func TeamGameHistory(w http.ResponseWriter, r *http.Request, args map[string]str
 		return
 	}
 
+	scope, filter, err := seasonFilter(
+		c, userAcls, league, leagueKey, r,
+		model.LeagueTeamUri(leagueKey, teamKey)+"/history", false)
+	if HandleError(c, w, err) {
+		return
+	}
+
 	// Get "all" match history.
 	// TODO(durni): This should likely be paginated or use infinite scroll. Currently
 	// just shows 100 games.
 	playerCache := model.NewPlayerCache(c, league.Region)
 	gameInfos, errors := model.TeamRecentGameInfo(
-		c, userAcls, 100, playerCache, league, leagueKey, teamKey)
+		c, userAcls, 100, playerCache, league, leagueKey, teamKey, scope)
 
 	// Populate view context.
 	ctx := struct {
 		ctxBase
 		League
 		Team
-		RecentGames []*model.GameInfo
+		SeasonFilter *SeasonFilter
+		RecentGames  []*model.GameInfo
 	}{}
 	ctx.ctxBase.init(c, user)
 	ctx.ctxBase.Title = fmt.Sprintf("loltools > %s > %s", league.Name, team.Name)
 	ctx.ctxBase.Errors = errors
 	ctx.League.Fill(league, leagueKey)
 	ctx.Team.Fill(team, teamKey, leagueKey)
+	ctx.SeasonFilter = filter
 	ctx.RecentGames = gameInfos
 
 	// Render
@@ -140,11 +293,18 @@ func TeamGameHistory(w http.ResponseWriter, r *http.Request, args map[string]str
 
 func ApiTeamAddPlayerHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
 	c := appengine.NewContext(r)
+	reqCtx, cancel := model.NewRequestContext(r)
+	defer cancel()
 	leagueId := r.FormValue("league")
 	teamId := r.FormValue("team")
 	region := r.FormValue("region")
 	summoner := r.FormValue("summoner")
 
+	role, err := model.ParseRosterRole(r.FormValue("role"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
 	_, userKey, err := model.GetUser(c)
 	if ApiHandleError(c, w, err) {
 		return
@@ -161,12 +321,15 @@ func ApiTeamAddPlayerHandler(w http.ResponseWriter, r *http.Request, args map[st
 		return
 	}
 
-	_, playerKey, err := model.GetOrCreatePlayerBySummoner(c, region, summoner)
+	_, playerKey, err := model.GetOrCreatePlayerBySummoner(c, reqCtx, region, summoner)
 	if ApiHandleError(c, w, err) {
 		return
 	}
 
-	err = model.TeamAddPlayer(c, userAcls, league, leagueKey, teamKey, playerKey)
+	err = model.TeamAddPlayer(
+		c, reqCtx, userAcls, league, leagueKey, teamKey, playerKey, role,
+		r.FormValue("substitute") == "1", r.FormValue("captain") == "1",
+		r.FormValue("reason"))
 	if ApiHandleError(c, w, err) {
 		return
 	}
@@ -176,6 +339,8 @@ func ApiTeamAddPlayerHandler(w http.ResponseWriter, r *http.Request, args map[st
 
 func ApiTeamDelPlayerHandler(w http.ResponseWriter, r *http.Request, args map[string]string) {
 	c := appengine.NewContext(r)
+	reqCtx, cancel := model.NewRequestContext(r)
+	defer cancel()
 	leagueId := r.FormValue("league")
 	teamId := r.FormValue("team")
 	region := r.FormValue("region")
@@ -197,12 +362,61 @@ func ApiTeamDelPlayerHandler(w http.ResponseWriter, r *http.Request, args map[st
 		return
 	}
 
-	_, playerKey, err := model.GetOrCreatePlayerBySummoner(c, region, summoner)
+	_, playerKey, err := model.GetOrCreatePlayerBySummoner(c, reqCtx, region, summoner)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	err = model.TeamDelPlayer(
+		c, userAcls, league, leagueKey, teamKey, playerKey, r.FormValue("reason"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	HttpReplyOkEmpty(w)
+}
+
+// Changes the role and the substitute and captain flags of a player on a team's roster.
+func ApiTeamUpdatePlayerHandler(
+	w http.ResponseWriter, r *http.Request, args map[string]string) {
+	c := appengine.NewContext(r)
+	reqCtx, cancel := model.NewRequestContext(r)
+	defer cancel()
+	leagueId := r.FormValue("league")
+	teamId := r.FormValue("team")
+	region := r.FormValue("region")
+	summoner := r.FormValue("summoner")
+
+	role, err := model.ParseRosterRole(r.FormValue("role"))
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	_, userKey, err := model.GetUser(c)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+	userAcls := model.NewRequestorAclCache(userKey)
+
+	league, leagueKey, err := model.LeagueById(c, leagueId)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	_, teamKey, err := model.TeamById(c, userAcls, league, leagueKey, teamId)
+	if ApiHandleError(c, w, err) {
+		return
+	}
+
+	_, playerKey, err := model.GetOrCreatePlayerBySummoner(c, reqCtx, region, summoner)
 	if ApiHandleError(c, w, err) {
 		return
 	}
 
-	err = model.TeamDelPlayer(c, userAcls, league, leagueKey, teamKey, playerKey)
+	err = model.TeamUpdatePlayer(
+		c, userAcls, league, leagueKey, teamKey, playerKey, role,
+		r.FormValue("substitute") == "1", r.FormValue("captain") == "1",
+		r.FormValue("reason"))
 	if ApiHandleError(c, w, err) {
 		return
 	}